@@ -0,0 +1,760 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package iris
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// LetsEncryptDirectoryURL is the directory url of the Let's Encrypt ACME server
+	LetsEncryptDirectoryURL = "https://acme-v02.api.letsencrypt.org/directory"
+	// DefaultRenewBefore is the default duration before the expiration of a certificate which the ACMEManager renews it
+	DefaultRenewBefore = 30 * 24 * time.Hour
+
+	// ACMEChallengeTLSALPN01 is the tls-alpn-01 challenge, it's validated on the https server itself (port 443)
+	ACMEChallengeTLSALPN01 = "tls-alpn-01"
+	// ACMEChallengeHTTP01 is the http-01 challenge, it's validated on the http server (port 80), see ACMEManager.HTTPHandler
+	ACMEChallengeHTTP01 = "http-01"
+
+	acmeALPNProto         = "acme-tls/1"
+	acmeHTTPChallengePath = "/.well-known/acme-challenge/"
+	acmeAccountKeyFile    = "acme_account.key"
+)
+
+var (
+	// oidACMEIdentifier is the id-pe-acmeIdentifier extension of the tls-alpn-01 challenge certificate, RFC 8737
+	oidACMEIdentifier = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}
+	// acmePollInterval is the interval between the status checks of the authorizations and the orders
+	acmePollInterval = time.Second
+	// acmePollAttempts is the maximum status checks of an authorization or order
+	acmePollAttempts = 60
+	// acmeRetryInterval is the wait after the first failed order of a domain, it's doubled on each next failure up to the acmeMaxRetryInterval
+	acmeRetryInterval    = time.Minute
+	acmeMaxRetryInterval = 24 * time.Hour
+)
+
+type (
+	// ACMEManager is an ICertificateProvider which obtains and renews the certificates automatically
+	// from an ACME (RFC 8555) server, ex: Let's Encrypt or a local pebble for testing
+	//
+	// A certificate is obtained on the first tls handshake of a domain,
+	// and it's renewed in the background when it's going to expire (RenewBefore)
+	// a failed order is retried after a backoff, from a minute up to a day, the still valid certificate is served until then
+	//
+	// Use it with the ListenTLSProvider
+	ACMEManager struct {
+		// DirectoryURL is the directory url of the ACME server
+		// Default is the LetsEncryptDirectoryURL
+		DirectoryURL string
+		// Email is the contact email of the ACME account, optional
+		Email string
+		// Domains are the only domains which a certificate can be obtained for
+		// if empty then the domains of the registed routes are used (see Route.GetDomain())
+		Domains []string
+		// Challenge is the challenge type, ACMEChallengeTLSALPN01 or ACMEChallengeHTTP01
+		// Default is the ACMEChallengeTLSALPN01
+		Challenge string
+		// CacheDir is the directory which the account key and the certificates are saved,
+		// if empty they are kept only in memory and they are obtained again on each restart
+		CacheDir string
+		// RenewBefore is the duration before the expiration of a certificate which it's renewed
+		// Default is the DefaultRenewBefore (30 days)
+		RenewBefore time.Duration
+		// Client is the http client which is used to communicate with the ACME server
+		// Default is the http.DefaultClient
+		Client *http.Client
+		// Logger writes the errors of the orders, ex: a renewal which failed in the background
+		// if nil then the logger of the station is used, it's set by the ListenTLSProvider
+		Logger *Logger
+
+		mu        sync.Mutex
+		certs     map[string]*tls.Certificate
+		orders    map[string]*acmeCall
+		failures  map[string]*acmeFailure
+		tokens    map[string]string
+		alpnCerts map[string]*tls.Certificate
+
+		// accountMu serializes the registration of the account, the directory, the accountKey and the kid are set once by it
+		accountMu  sync.Mutex
+		directory  *acmeDirectory
+		accountKey *ecdsa.PrivateKey
+		kid        string
+		// nonceMu guards the nonce, the orders of different domains are sent concurrently
+		nonceMu sync.Mutex
+		nonce   string
+	}
+
+	// acmeCall is the running order of a domain, the handshakes of the domain wait for it to be done
+	acmeCall struct {
+		done chan struct{}
+		cert *tls.Certificate
+		err  error
+	}
+
+	// acmeFailure is the last failed order of a domain, the next order waits for the backoff of the failures
+	acmeFailure struct {
+		count int
+		last  time.Time
+		err   error
+	}
+
+	acmeDirectory struct {
+		NewNonce   string `json:"newNonce"`
+		NewAccount string `json:"newAccount"`
+		NewOrder   string `json:"newOrder"`
+	}
+
+	acmeProblem struct {
+		Type   string `json:"type"`
+		Detail string `json:"detail"`
+	}
+
+	acmeIdentifier struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+
+	acmeOrder struct {
+		Status         string           `json:"status"`
+		Identifiers    []acmeIdentifier `json:"identifiers"`
+		Authorizations []string         `json:"authorizations"`
+		Finalize       string           `json:"finalize"`
+		Certificate    string           `json:"certificate,omitempty"`
+		Error          *acmeProblem     `json:"error,omitempty"`
+	}
+
+	acmeChallenge struct {
+		Type   string       `json:"type"`
+		URL    string       `json:"url"`
+		Token  string       `json:"token"`
+		Status string       `json:"status"`
+		Error  *acmeProblem `json:"error,omitempty"`
+	}
+
+	acmeAuthorization struct {
+		Status     string          `json:"status"`
+		Identifier acmeIdentifier  `json:"identifier"`
+		Challenges []acmeChallenge `json:"challenges"`
+	}
+)
+
+var _ ICertificateProvider = &ACMEManager{}
+var _ IDomainsReceiver = &ACMEManager{}
+
+func (p *acmeProblem) Error() string {
+	return "[Iris] ACME: " + p.Type + ": " + p.Detail
+}
+
+// SetDomains sets the Domains, if they are not already set, it's called by the ListenTLSProvider
+func (m *ACMEManager) SetDomains(domains []string) error {
+	if len(m.Domains) == 0 {
+		m.Domains = domains
+	}
+	return nil
+}
+
+// GetCertificate returns the certificate for the client's server name, see tls.Config.GetCertificate
+// it obtains the certificate if it doesn't exists and it serves the tls-alpn-01 challenges
+func (m *ACMEManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if name == "" {
+		return nil, errors.New("[Iris] ACME: missing server name")
+	}
+
+	if len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == acmeALPNProto {
+		m.mu.Lock()
+		cert := m.alpnCerts[name]
+		m.mu.Unlock()
+		if cert == nil {
+			return nil, fmt.Errorf("[Iris] ACME: no tls-alpn-01 challenge for %s", name)
+		}
+		return cert, nil
+	}
+
+	if !m.allowed(name) {
+		return nil, fmt.Errorf("[Iris] ACME: domain %s is not allowed", name)
+	}
+
+	if cert := m.cached(name); cert != nil {
+		if time.Until(cert.Leaf.NotAfter) < m.renewBefore() {
+			m.renew(name)
+		}
+		return cert, nil
+	}
+
+	m.mu.Lock()
+	call := m.orders[name]
+	if call == nil {
+		// it could be obtained after the cached check
+		if cert := m.certs[name]; cert != nil && time.Now().Before(cert.Leaf.NotAfter) {
+			m.mu.Unlock()
+			return cert, nil
+		}
+		// don't start a new order on each handshake while the ACME server fails
+		if err := m.backoff(name); err != nil {
+			m.mu.Unlock()
+			return nil, err
+		}
+		call = m.order(name)
+	}
+	m.mu.Unlock()
+
+	// only the handshakes of this domain wait for its order
+	<-call.done
+	return call.cert, call.err
+}
+
+// HTTPHandler serves the http-01 challenges, the rest requests are passed to the fallback handler
+// if the fallback is nil then the rest requests are redirected to https
+//
+// It's needed only if the Challenge is the ACMEChallengeHTTP01, use it on port 80, ex:
+// go http.ListenAndServe(":80", manager.HTTPHandler(nil))
+func (m *ACMEManager) HTTPHandler(fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if !strings.HasPrefix(req.URL.Path, acmeHTTPChallengePath) {
+			if fallback != nil {
+				fallback.ServeHTTP(res, req)
+				return
+			}
+			http.Redirect(res, req, "https://"+req.Host+req.URL.RequestURI(), http.StatusFound)
+			return
+		}
+
+		m.mu.Lock()
+		keyAuth, found := m.tokens[strings.TrimPrefix(req.URL.Path, acmeHTTPChallengePath)]
+		m.mu.Unlock()
+		if !found {
+			http.NotFound(res, req)
+			return
+		}
+		res.Header().Set("Content-Type", "text/plain")
+		res.Write([]byte(keyAuth))
+	})
+}
+
+func (m *ACMEManager) allowed(name string) bool {
+	for _, domain := range m.Domains {
+		if strings.EqualFold(domain, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *ACMEManager) renewBefore() time.Duration {
+	if m.RenewBefore <= 0 {
+		return DefaultRenewBefore
+	}
+	return m.RenewBefore
+}
+
+func (m *ACMEManager) client() *http.Client {
+	if m.Client == nil {
+		return http.DefaultClient
+	}
+	return m.Client
+}
+
+// cached returns the certificate of the domain from the memory or from the CacheDir, if it's not expired
+func (m *ACMEManager) cached(name string) *tls.Certificate {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cert := m.certs[name]
+	if cert == nil && m.CacheDir != "" {
+		if c, err := tls.LoadX509KeyPair(filepath.Join(m.CacheDir, name+".crt"), filepath.Join(m.CacheDir, name+".key")); err == nil {
+			if c.Leaf, err = x509.ParseCertificate(c.Certificate[0]); err == nil {
+				cert = &c
+				m.setCert(name, cert)
+			}
+		}
+	}
+	if cert == nil || time.Now().After(cert.Leaf.NotAfter) {
+		return nil
+	}
+	return cert
+}
+
+// setCert keeps the certificate of the domain in memory, m.mu should be locked
+func (m *ACMEManager) setCert(name string, cert *tls.Certificate) {
+	if m.certs == nil {
+		m.certs = make(map[string]*tls.Certificate)
+	}
+	m.certs[name] = cert
+}
+
+// renew obtains a new certificate for the domain in the background, the old one is served until then
+func (m *ACMEManager) renew(name string) {
+	m.mu.Lock()
+	if m.orders[name] == nil && m.backoff(name) == nil {
+		m.order(name)
+	}
+	m.mu.Unlock()
+}
+
+// backoff returns the error of the last failed order of the domain if it's too early for the next one, m.mu should be locked
+func (m *ACMEManager) backoff(name string) error {
+	f := m.failures[name]
+	if f == nil {
+		return nil
+	}
+	wait := acmeRetryInterval
+	for i := 1; i < f.count && wait < acmeMaxRetryInterval; i++ {
+		wait *= 2
+	}
+	if wait > acmeMaxRetryInterval {
+		wait = acmeMaxRetryInterval
+	}
+	if retry := f.last.Add(wait); time.Now().Before(retry) {
+		return fmt.Errorf("[Iris] ACME: the next order of %s is after %s: %w", name, retry.Format(time.RFC3339), f.err)
+	}
+	return nil
+}
+
+// order starts the order of a new certificate for the domain in the background, m.mu should be locked
+// the certificate is kept and the order is removed together, so a handshake finds the one or the other
+func (m *ACMEManager) order(name string) *acmeCall {
+	if m.orders == nil {
+		m.orders = make(map[string]*acmeCall)
+	}
+	call := &acmeCall{done: make(chan struct{})}
+	m.orders[name] = call
+
+	go func() {
+		call.cert, call.err = m.obtain(name)
+		if call.err != nil && m.Logger != nil {
+			m.Logger.Error("ACME order failed", "domain", name, "err", call.err)
+		}
+
+		m.mu.Lock()
+		if call.err == nil {
+			m.setCert(name, call.cert)
+			delete(m.failures, name)
+		} else {
+			if m.failures == nil {
+				m.failures = make(map[string]*acmeFailure)
+			}
+			f := m.failures[name]
+			if f == nil {
+				f = &acmeFailure{}
+				m.failures[name] = f
+			}
+			f.count++
+			f.last = time.Now()
+			f.err = call.err
+		}
+		delete(m.orders, name)
+		m.mu.Unlock()
+		close(call.done)
+	}()
+	return call
+}
+
+// obtain orders a new certificate for the domain from the ACME server
+func (m *ACMEManager) obtain(name string) (*tls.Certificate, error) {
+	if err := m.register(); err != nil {
+		return nil, err
+	}
+
+	var order acmeOrder
+	header, err := m.post(m.directory.NewOrder, map[string]interface{}{"identifiers": []acmeIdentifier{{Type: "dns", Value: name}}}, &order)
+	if err != nil {
+		return nil, err
+	}
+	orderURL := header.Get("Location")
+
+	for _, authzURL := range order.Authorizations {
+		if err = m.authorize(authzURL); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: name}, DNSNames: []string{name}}, key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = m.post(order.Finalize, map[string]string{"csr": base64.RawURLEncoding.EncodeToString(csr)}, &order); err != nil {
+		return nil, err
+	}
+	for i := 0; order.Status != "valid"; i++ {
+		if order.Status == "invalid" || i == acmePollAttempts {
+			if order.Error != nil {
+				return nil, order.Error
+			}
+			return nil, fmt.Errorf("[Iris] ACME: order of %s is %s", name, order.Status)
+		}
+		time.Sleep(acmePollInterval)
+		if _, err = m.post(orderURL, nil, &order); err != nil {
+			return nil, err
+		}
+	}
+
+	var chain []byte
+	if _, err = m.post(order.Certificate, nil, &chain); err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	cert, err := tls.X509KeyPair(chain, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return nil, err
+	}
+
+	if m.CacheDir != "" {
+		os.MkdirAll(m.CacheDir, 0700)
+		os.WriteFile(filepath.Join(m.CacheDir, name+".crt"), chain, 0600)
+		os.WriteFile(filepath.Join(m.CacheDir, name+".key"), keyPEM, 0600)
+	}
+	return &cert, nil
+}
+
+// authorize completes the challenge of an authorization
+func (m *ACMEManager) authorize(authzURL string) error {
+	var authz acmeAuthorization
+	if _, err := m.post(authzURL, nil, &authz); err != nil {
+		return err
+	}
+	if authz.Status == "valid" {
+		return nil
+	}
+
+	challengeType := m.Challenge
+	if challengeType == "" {
+		challengeType = ACMEChallengeTLSALPN01
+	}
+	var challenge *acmeChallenge
+	for i := range authz.Challenges {
+		if authz.Challenges[i].Type == challengeType {
+			challenge = &authz.Challenges[i]
+			break
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("[Iris] ACME: the server doesn't offer the %s challenge for %s", challengeType, authz.Identifier.Value)
+	}
+
+	keyAuth := challenge.Token + "." + acmeThumbprint(&m.accountKey.PublicKey)
+	name := authz.Identifier.Value
+	m.mu.Lock()
+	if challengeType == ACMEChallengeHTTP01 {
+		if m.tokens == nil {
+			m.tokens = make(map[string]string)
+		}
+		m.tokens[challenge.Token] = keyAuth
+	} else {
+		cert, err := acmeALPNCertificate(name, keyAuth)
+		if err != nil {
+			m.mu.Unlock()
+			return err
+		}
+		if m.alpnCerts == nil {
+			m.alpnCerts = make(map[string]*tls.Certificate)
+		}
+		m.alpnCerts[name] = cert
+	}
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		delete(m.tokens, challenge.Token)
+		delete(m.alpnCerts, name)
+		m.mu.Unlock()
+	}()
+
+	// the server validates the challenge after this
+	if _, err := m.post(challenge.URL, struct{}{}, nil); err != nil {
+		return err
+	}
+
+	for i := 0; authz.Status != "valid"; i++ {
+		if authz.Status == "invalid" || i == acmePollAttempts {
+			for _, c := range authz.Challenges {
+				if c.Error != nil {
+					return c.Error
+				}
+			}
+			return fmt.Errorf("[Iris] ACME: authorization of %s is %s", name, authz.Status)
+		}
+		time.Sleep(acmePollInterval)
+		if _, err := m.post(authzURL, nil, &authz); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// register loads or creates the account key and registers the account to the ACME server, only once
+func (m *ACMEManager) register() error {
+	m.accountMu.Lock()
+	defer m.accountMu.Unlock()
+	if m.kid != "" {
+		return nil
+	}
+
+	if m.directory == nil {
+		directoryURL := m.DirectoryURL
+		if directoryURL == "" {
+			directoryURL = LetsEncryptDirectoryURL
+		}
+		res, err := m.client().Get(directoryURL)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("[Iris] ACME: directory %s returned %s", directoryURL, res.Status)
+		}
+		directory := &acmeDirectory{}
+		if err = json.NewDecoder(res.Body).Decode(directory); err != nil {
+			return err
+		}
+		m.directory = directory
+	}
+
+	if m.accountKey == nil {
+		key, err := m.loadAccountKey()
+		if err != nil {
+			return err
+		}
+		m.accountKey = key
+	}
+
+	account := map[string]interface{}{"termsOfServiceAgreed": true}
+	if m.Email != "" {
+		account["contact"] = []string{"mailto:" + m.Email}
+	}
+	header, err := m.post(m.directory.NewAccount, account, nil)
+	if err != nil {
+		return err
+	}
+	m.kid = header.Get("Location")
+	if m.kid == "" {
+		return errors.New("[Iris] ACME: the account has no location")
+	}
+	return nil
+}
+
+// loadAccountKey loads the account key from the CacheDir, or it creates a new one
+func (m *ACMEManager) loadAccountKey() (*ecdsa.PrivateKey, error) {
+	filename := filepath.Join(m.CacheDir, acmeAccountKeyFile)
+	if m.CacheDir != "" {
+		if contents, err := os.ReadFile(filename); err == nil {
+			if block, _ := pem.Decode(contents); block != nil {
+				return x509.ParseECPrivateKey(block.Bytes)
+			}
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if m.CacheDir != "" {
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		os.MkdirAll(m.CacheDir, 0700)
+		if err = os.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// post sends a JWS signed request to the ACME server, a nil payload is a POST-as-GET request
+// the response is decoded to the result, if it's a *[]byte then the raw body is set to it
+func (m *ACMEManager) post(url string, payload interface{}, result interface{}) (http.Header, error) {
+	for retry := true; ; retry = false {
+		body, err := m.sign(url, payload)
+		if err != nil {
+			return nil, err
+		}
+		res, err := m.client().Post(url, "application/jose+json", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		m.nonceMu.Lock()
+		m.nonce = res.Header.Get("Replay-Nonce")
+		m.nonceMu.Unlock()
+		contents, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode >= 400 {
+			problem := &acmeProblem{}
+			if json.Unmarshal(contents, problem) != nil || problem.Type == "" {
+				problem.Type, problem.Detail = strconv.Itoa(res.StatusCode), string(contents)
+			}
+			// the nonce is expired or used, try once again with the new one
+			if retry && problem.Type == "urn:ietf:params:acme:error:badNonce" {
+				continue
+			}
+			return nil, problem
+		}
+
+		if raw, ok := result.(*[]byte); ok {
+			*raw = contents
+		} else if result != nil {
+			if err = json.Unmarshal(contents, result); err != nil {
+				return nil, err
+			}
+		}
+		return res.Header, nil
+	}
+}
+
+// sign returns the flattened JWS (RFC 7515) of the payload, signed with the account key (ES256)
+func (m *ACMEManager) sign(url string, payload interface{}) ([]byte, error) {
+	// each nonce is used once, the last one is taken or a new one is requested
+	m.nonceMu.Lock()
+	nonce := m.nonce
+	m.nonce = ""
+	m.nonceMu.Unlock()
+	if nonce == "" {
+		res, err := m.client().Head(m.directory.NewNonce)
+		if err != nil {
+			return nil, err
+		}
+		res.Body.Close()
+		nonce = res.Header.Get("Replay-Nonce")
+	}
+
+	protected := map[string]interface{}{"alg": "ES256", "nonce": nonce, "url": url}
+	if m.kid != "" {
+		protected["kid"] = m.kid
+	} else {
+		protected["jwk"] = json.RawMessage(acmeJWK(&m.accountKey.PublicKey))
+	}
+
+	header, err := json.Marshal(protected)
+	if err != nil {
+		return nil, err
+	}
+	var content []byte
+	if payload != nil {
+		if content, err = json.Marshal(payload); err != nil {
+			return nil, err
+		}
+	}
+	protected64 := base64.RawURLEncoding.EncodeToString(header)
+	payload64 := base64.RawURLEncoding.EncodeToString(content)
+
+	hash := sha256.Sum256([]byte(protected64 + "." + payload64))
+	r, s, err := ecdsa.Sign(rand.Reader, m.accountKey, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return json.Marshal(map[string]string{
+		"protected": protected64,
+		"payload":   payload64,
+		"signature": base64.RawURLEncoding.EncodeToString(signature),
+	})
+}
+
+// acmeJWK returns the JSON Web Key of a P-256 public key, the members are in the order of the RFC 7638 thumbprint
+func acmeJWK(key *ecdsa.PublicKey) string {
+	x, y := make([]byte, 32), make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return `{"crv":"P-256","kty":"EC","x":"` + base64.RawURLEncoding.EncodeToString(x) + `","y":"` + base64.RawURLEncoding.EncodeToString(y) + `"}`
+}
+
+// acmeThumbprint returns the JWK thumbprint (RFC 7638) of the account key, it's the second part of the key authorizations
+func acmeThumbprint(key *ecdsa.PublicKey) string {
+	sum := sha256.Sum256([]byte(acmeJWK(key)))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// acmeALPNCertificate creates the self-signed certificate of the tls-alpn-01 challenge, RFC 8737
+func acmeALPNCertificate(name, keyAuth string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(keyAuth))
+	extension, err := asn1.Marshal(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:    serial,
+		Subject:         pkix.Name{CommonName: name},
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().Add(24 * time.Hour),
+		DNSNames:        []string{name},
+		ExtraExtensions: []pkix.Extension{{Id: oidACMEIdentifier, Critical: true, Value: extension}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: crypto.Signer(key)}, nil
+}
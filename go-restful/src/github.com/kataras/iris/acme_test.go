@@ -0,0 +1,418 @@
+package iris
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testACMEServer is a minimal ACME server, like the pebble, it validates the tls-alpn-01 challenge
+// with a tls handshake to the validationAddr and it issues the certificates from its own CA
+type testACMEServer struct {
+	*httptest.Server
+	t              *testing.T
+	validationAddr string
+
+	mu         sync.Mutex
+	nonce      int
+	accountKey *ecdsa.PublicKey
+	domain     string
+	authzValid bool
+	issued     []byte
+
+	caKey  *ecdsa.PrivateKey
+	caCert *x509.Certificate
+}
+
+func newTestACMEServer(t *testing.T) *testACMEServer {
+	s := &testACMEServer{t: t}
+	s.caKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ACME CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, s.caKey.Public(), s.caKey)
+	s.caCert, _ = x509.ParseCertificate(der)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dir", func(res http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(res).Encode(acmeDirectory{NewNonce: s.URL + "/nonce", NewAccount: s.URL + "/account", NewOrder: s.URL + "/order"})
+	})
+	mux.HandleFunc("/nonce", func(res http.ResponseWriter, req *http.Request) { s.setNonce(res) })
+	mux.HandleFunc("/account", func(res http.ResponseWriter, req *http.Request) {
+		s.verify(res, req, nil)
+		res.Header().Set("Location", s.URL+"/account/1")
+		res.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/order", func(res http.ResponseWriter, req *http.Request) {
+		var order acmeOrder
+		s.verify(res, req, &order)
+		s.mu.Lock()
+		s.domain = order.Identifiers[0].Value
+		s.mu.Unlock()
+		res.Header().Set("Location", s.URL+"/order/1")
+		res.WriteHeader(http.StatusCreated)
+		json.NewEncoder(res).Encode(s.order())
+	})
+	mux.HandleFunc("/order/1", func(res http.ResponseWriter, req *http.Request) {
+		s.verify(res, req, nil)
+		json.NewEncoder(res).Encode(s.order())
+	})
+	mux.HandleFunc("/authz/1", func(res http.ResponseWriter, req *http.Request) {
+		s.verify(res, req, nil)
+		json.NewEncoder(res).Encode(s.authorization())
+	})
+	mux.HandleFunc("/challenge/1", func(res http.ResponseWriter, req *http.Request) {
+		s.verify(res, req, nil)
+		s.validate()
+		json.NewEncoder(res).Encode(s.authorization().Challenges[0])
+	})
+	mux.HandleFunc("/finalize/1", func(res http.ResponseWriter, req *http.Request) {
+		var finalize struct {
+			CSR string `json:"csr"`
+		}
+		s.verify(res, req, &finalize)
+		s.issue(finalize.CSR)
+		json.NewEncoder(res).Encode(s.order())
+	})
+	mux.HandleFunc("/certificate/1", func(res http.ResponseWriter, req *http.Request) {
+		s.verify(res, req, nil)
+		s.mu.Lock()
+		res.Write(s.issued)
+		s.mu.Unlock()
+	})
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func (s *testACMEServer) setNonce(res http.ResponseWriter) {
+	s.mu.Lock()
+	s.nonce++
+	res.Header().Set("Replay-Nonce", strconv.Itoa(s.nonce))
+	s.mu.Unlock()
+}
+
+// verify verifies the JWS signature of the request and decodes its payload to the v
+func (s *testACMEServer) verify(res http.ResponseWriter, req *http.Request, v interface{}) {
+	var jws struct{ Protected, Payload, Signature string }
+	if err := json.NewDecoder(req.Body).Decode(&jws); err != nil {
+		s.t.Errorf("ACME request %s is not a JWS: %v", req.URL.Path, err)
+	}
+	protectedJSON, _ := base64.RawURLEncoding.DecodeString(jws.Protected)
+	var protected struct {
+		Alg, Nonce, URL, Kid string
+		JWK                  *struct{ X, Y string }
+	}
+	json.Unmarshal(protectedJSON, &protected)
+
+	s.mu.Lock()
+	if protected.JWK != nil {
+		x, _ := base64.RawURLEncoding.DecodeString(protected.JWK.X)
+		y, _ := base64.RawURLEncoding.DecodeString(protected.JWK.Y)
+		s.accountKey = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	} else if protected.Kid != s.URL+"/account/1" {
+		s.t.Errorf("Expecting the kid of the account but got %q", protected.Kid)
+	}
+	key := s.accountKey
+	nonce := strconv.Itoa(s.nonce)
+	s.mu.Unlock()
+
+	if protected.Nonce != nonce || protected.URL != s.URL+req.URL.Path || protected.Alg != "ES256" {
+		s.t.Errorf("Invalid protected header of %s: %s", req.URL.Path, protectedJSON)
+	}
+	signature, _ := base64.RawURLEncoding.DecodeString(jws.Signature)
+	hash := sha256.Sum256([]byte(jws.Protected + "." + jws.Payload))
+	if len(signature) != 64 || !ecdsa.Verify(key, hash[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+		s.t.Errorf("Invalid signature of %s", req.URL.Path)
+	}
+	if v != nil {
+		payload, _ := base64.RawURLEncoding.DecodeString(jws.Payload)
+		json.Unmarshal(payload, v)
+	}
+	s.setNonce(res)
+}
+
+func (s *testACMEServer) authorization() acmeAuthorization {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := "pending"
+	if s.authzValid {
+		status = "valid"
+	}
+	return acmeAuthorization{
+		Status:     status,
+		Identifier: acmeIdentifier{Type: "dns", Value: s.domain},
+		Challenges: []acmeChallenge{
+			{Type: ACMEChallengeHTTP01, URL: s.URL + "/challenge/2", Token: "http-token", Status: status},
+			{Type: ACMEChallengeTLSALPN01, URL: s.URL + "/challenge/1", Token: "alpn-token", Status: status},
+		},
+	}
+}
+
+func (s *testACMEServer) order() acmeOrder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order := acmeOrder{Status: "pending", Authorizations: []string{s.URL + "/authz/1"}, Finalize: s.URL + "/finalize/1"}
+	if s.authzValid {
+		order.Status = "ready"
+	}
+	if s.issued != nil {
+		order.Status = "valid"
+		order.Certificate = s.URL + "/certificate/1"
+	}
+	return order
+}
+
+// validate makes the tls-alpn-01 validation, RFC 8737
+func (s *testACMEServer) validate() {
+	s.mu.Lock()
+	domain, key := s.domain, s.accountKey
+	s.mu.Unlock()
+
+	conn, err := tls.Dial("tcp", s.validationAddr, &tls.Config{ServerName: domain, NextProtos: []string{acmeALPNProto}, InsecureSkipVerify: true})
+	if err != nil {
+		s.t.Errorf("tls-alpn-01 validation handshake failed: %v", err)
+		return
+	}
+	defer conn.Close()
+	state := conn.ConnectionState()
+	if state.NegotiatedProtocol != acmeALPNProto {
+		s.t.Errorf("Expecting the %s protocol to be negotiated but got %q", acmeALPNProto, state.NegotiatedProtocol)
+		return
+	}
+
+	sum := sha256.Sum256([]byte("alpn-token." + acmeThumbprint(key)))
+	expected, _ := asn1.Marshal(sum[:])
+	for _, ext := range state.PeerCertificates[0].Extensions {
+		if ext.Id.Equal(oidACMEIdentifier) && ext.Critical && bytes.Equal(ext.Value, expected) {
+			s.mu.Lock()
+			s.authzValid = true
+			s.mu.Unlock()
+			return
+		}
+	}
+	s.t.Error("The tls-alpn-01 certificate has no valid acmeIdentifier extension")
+}
+
+func (s *testACMEServer) issue(csr64 string) {
+	der, _ := base64.RawURLEncoding.DecodeString(csr64)
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		s.t.Errorf("Invalid CSR: %v", err)
+		return
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      csr.Subject,
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	leaf, _ := x509.CreateCertificate(rand.Reader, template, s.caCert, csr.PublicKey, s.caKey)
+	s.mu.Lock()
+	s.issued = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf}), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.caCert.Raw})...)
+	s.mu.Unlock()
+}
+
+func TestACMEManager(t *testing.T) {
+	acmeServer := newTestACMEServer(t)
+	defer acmeServer.Close()
+	defer func(interval time.Duration) { acmePollInterval = interval }(acmePollInterval)
+	acmePollInterval = 10 * time.Millisecond
+
+	m := &ACMEManager{DirectoryURL: acmeServer.URL + "/dir", Email: "admin@ideopod.com", Domains: []string{"ideopod.com"}, CacheDir: t.TempDir()}
+
+	s := New()
+	s.Get("/", HandlerFunc(func(c *Context) { c.Write("secure") }))
+	result := listenTestWith(t, s, func() error { return s.ListenTLSProvider("127.0.0.1:0", m) })
+	defer func() {
+		s.Close()
+		<-result
+	}()
+	acmeServer.validationAddr = s.Server.listener.Addr().String()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(acmeServer.caCert)
+	conn, err := tls.Dial("tcp", acmeServer.validationAddr, &tls.Config{ServerName: "ideopod.com", RootCAs: roots})
+	if err != nil {
+		t.Fatalf("Expecting a certificate from the ACME server: %v", err)
+	}
+	conn.Close()
+
+	if _, err = m.GetCertificate(&tls.ClientHelloInfo{ServerName: "kataras.com"}); err == nil {
+		t.Fatal("Expecting an error for a domain which is not allowed")
+	}
+
+	// the certificate is loaded from the cache dir, without the ACME server
+	cached := &ACMEManager{DirectoryURL: "http://127.0.0.1:0/dir", Domains: m.Domains, CacheDir: m.CacheDir}
+	cert, err := cached.GetCertificate(&tls.ClientHelloInfo{ServerName: "ideopod.com"})
+	if err != nil {
+		t.Fatalf("Expecting the cached certificate: %v", err)
+	}
+	if cert.Leaf.SerialNumber.Int64() != 2 {
+		t.Fatalf("Expecting the issued certificate but got %d", cert.Leaf.SerialNumber.Int64())
+	}
+}
+
+func TestACMEManagerHTTPHandler(t *testing.T) {
+	m := &ACMEManager{tokens: map[string]string{"token": "token.thumbprint"}}
+	handler := m.HTTPHandler(nil)
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "http://ideopod.com"+acmeHTTPChallengePath+"token", nil))
+	if res.Body.String() != "token.thumbprint" {
+		t.Fatalf("Expecting the key authorization but got %q", res.Body.String())
+	}
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "http://ideopod.com"+acmeHTTPChallengePath+"other", nil))
+	if res.Code != http.StatusNotFound {
+		t.Fatalf("Expecting 404 for an unknown token but got %d", res.Code)
+	}
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "http://ideopod.com/about?a=1", nil))
+	if location := res.Header().Get("Location"); location != "https://ideopod.com/about?a=1" {
+		t.Fatalf("Expecting a redirect to https but got %q", location)
+	}
+}
+
+func TestACMEManagerOrders(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	directory := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		http.Error(res, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer directory.Close()
+
+	ring := NewRingSink(10)
+	logger := NewLogger(nil, "", 0)
+	logger.SetSinks(ring)
+	m := &ACMEManager{DirectoryURL: directory.URL, Domains: []string{"ideopod.com"}, Logger: logger}
+
+	// the handshakes of the same domain wait for the same order
+	errs := make(chan error, 3)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "ideopod.com"})
+			errs <- err
+		}()
+	}
+	for atomic.LoadInt32(&requests) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// let the rest handshakes join the order
+	time.Sleep(50 * time.Millisecond)
+	// the rest domains are not blocked by the running order
+	if _, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "kataras.com"}); err == nil {
+		t.Fatal("Expecting an error for a domain which is not allowed")
+	}
+	close(release)
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err == nil {
+			t.Fatal("Expecting the error of the order")
+		}
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("Expecting one order but got %d", n)
+	}
+	records := ring.Records()
+	if len(records) != 1 || records[0].Level != LevelError || records[0].Fields[0].Value != "ideopod.com" {
+		t.Fatalf("Expecting the error of the order to be logged but got %v", records)
+	}
+	if len(m.orders) != 0 {
+		t.Fatalf("Expecting the order to be removed but got %d", len(m.orders))
+	}
+}
+
+func TestACMEManagerBackoff(t *testing.T) {
+	var requests int32
+	directory := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(res, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer directory.Close()
+
+	// the cached certificate expires in an hour, so it's renewed on the first handshake
+	dir := t.TempDir()
+	writeTestCertificate(t, dir, 7, "ideopod.com")
+	m := &ACMEManager{DirectoryURL: directory.URL, Domains: []string{"ideopod.com", "kataras.com"}, CacheDir: dir}
+	waitOrders := func() {
+		for {
+			m.mu.Lock()
+			n := len(m.orders)
+			m.mu.Unlock()
+			if n == 0 {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "ideopod.com"})
+		if err != nil || cert.Leaf.SerialNumber.Int64() != 7 {
+			t.Fatalf("[%d] Expecting the cached certificate while the renewal fails but got %v", i, err)
+		}
+		waitOrders()
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("Expecting one failed renewal but got %d orders", n)
+	}
+
+	// a domain without a certificate gets the error of the last order until the backoff is passed
+	for i := 0; i < 3; i++ {
+		if _, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "kataras.com"}); err == nil {
+			t.Fatalf("[%d] Expecting the error of the order", i)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("Expecting one failed order of the new domain but got %d orders", n-1)
+	}
+
+	m.mu.Lock()
+	m.failures["kataras.com"].last = time.Now().Add(-acmeRetryInterval)
+	m.mu.Unlock()
+	if _, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "kataras.com"}); err == nil {
+		t.Fatal("Expecting the error of the order")
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("Expecting a new order after the backoff but got %d orders", n)
+	}
+	// the backoff is doubled after the second failure
+	m.mu.Lock()
+	m.failures["kataras.com"].last = time.Now().Add(-acmeRetryInterval)
+	m.mu.Unlock()
+	if _, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "kataras.com"}); err == nil {
+		t.Fatal("Expecting the error of the last order")
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("Expecting no order before the doubled backoff but got %d orders", n)
+	}
+}
@@ -369,11 +369,12 @@ func irisHandleTestContexted(c *Context) {
 }
 
 func loadIris(routes []routeTest) http.Handler {
-	h := irisHandleTestContexted
-	//disable cache with:
-	//api := Custom(StationOptions{Cache: false})
 	//enable cache (default) with:
-	api := New()
+	return loadIrisStation(New(), routes)
+}
+
+func loadIrisStation(api *Station, routes []routeTest) http.Handler {
+	h := irisHandleTestContexted
 
 	for _, route := range routes {
 		api.HandleFunc(route.method, route.path, h)
@@ -406,6 +407,13 @@ func BenchmarkIris_GithubAll(b *testing.B) {
 	benchRoutes(b, githubIris, githubAPI)
 }
 
+// BenchmarkIris_GithubAllNoCache benchmarks the tree of the routes, without the router's cache
+func BenchmarkIris_GithubAllNoCache(b *testing.B) {
+	options := defaultOptions()
+	options.Cache = false
+	benchRoutes(b, loadIrisStation(Custom(options), githubAPI), githubAPI)
+}
+
 //Results ( one core 2.5GHz )
 //
 //#GithubAPI Routes: 203
@@ -0,0 +1,645 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package iris
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMaxBodySize is the default maximum size of the request body which the Context.Bind reads, 32MB
+	DefaultMaxBodySize = 32 << 20
+	// defaultMultipartMemory is the maximum memory of the multipart forms, the rest is stored in temporary files
+	defaultMultipartMemory = 32 << 20
+)
+
+// BodyDecoder decodes a request body to the dst, it's used by the Context.Bind for a Content-Type
+type BodyDecoder func(body io.Reader, dst interface{}) error
+
+// bodyDecoders are the decoders of the Context.Bind, by Content-Type
+var bodyDecoders = map[string]BodyDecoder{
+	ContentJSON:    decodeJSON,
+	ContentXML:     decodeXML,
+	ContentXMLText: decodeXML,
+}
+
+// RegisterBodyDecoder registers a decoder for a Content-Type, ex: application/msgpack, which the Context.Bind uses
+// it replaces the previous decoder of this Content-Type, if any
+func RegisterBodyDecoder(contentType string, decoder BodyDecoder) {
+	bodyDecoders[strings.ToLower(contentType)] = decoder
+}
+
+func decodeJSON(body io.Reader, dst interface{}) error {
+	if err := json.NewDecoder(body).Decode(dst); err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+func decodeXML(body io.Reader, dst interface{}) error {
+	if err := xml.NewDecoder(body).Decode(dst); err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// FieldError is the error of a struct field, see BindError
+type FieldError struct {
+	// Field is the name of the field, the form or json tag's name if any
+	Field string `json:"field"`
+	// Tag is the failed validation rule, ex: required, min or "type" if the value cannot be converted to the field's type
+	Tag string `json:"tag"`
+	// Param is the parameter of the validation rule, ex: 1 for min=1
+	Param string `json:"param,omitempty"`
+	// Message is the human readable error
+	Message string `json:"message"`
+}
+
+// BindError is the error of the Context.Bind
+type BindError struct {
+	// Status is the http status code of the error
+	// 400 for the malformed bodies and values, 413 for too large bodies, 415 for unsupported Content-Types and 422 for the validation errors
+	Status int `json:"-"`
+	// Message is the human readable error
+	Message string `json:"message"`
+	// Fields are the errors of the struct fields, if any
+	Fields []FieldError `json:"errors,omitempty"`
+}
+
+func (e *BindError) Error() string {
+	if len(e.Fields) == 0 {
+		return "[Iris] Bind: " + e.Message
+	}
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Message
+	}
+	return "[Iris] Bind: " + e.Message + ": " + strings.Join(messages, ", ")
+}
+
+// defaultBindErrorHandler sends the BindError as JSON
+func defaultBindErrorHandler(ctx *Context, err *BindError) {
+	ctx.WriteJSON(err.Status, err)
+}
+
+// Bind decodes the request to the dst, which should be a pointer to a struct
+//
+// the body is decoded by the Content-Type, JSON and XML are supported by default, see RegisterBodyDecoder,
+// the form (url-encoded and multipart) and the url query values are set to the fields with the `form:"name"` tag,
+// the multipart files to the *multipart.FileHeader and []*multipart.FileHeader fields with the `form:"name"` tag
+// and the path parameters to the fields with the `param:"name"` tag
+//
+// after that the fields are validated by their `validate` tag, ex: `validate:"required,min=1"`
+// the rules are: required, min, max, len (the value of the numbers, the length of the strings, slices and maps), oneof (space separated values) and email
+// an empty field, its zero value or a nil pointer, is valid if it's not required and the rest rules are not checked,
+// ex: an int with min=18 accepts a request without age, use a pointer (*int) in order to check the rules of a sent zero value
+//
+// the tags are parsed once per type, on its first Bind, an invalid tag (ex: min=abc or an unknown rule) is returned as an error which is not a *BindError
+//
+// the body is limited by the StationOptions.MaxBodySize, the rest returned errors are *BindError
+func (ctx *Context) Bind(dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	isStruct := v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Struct
+	var validation *structValidation
+	if isStruct {
+		if validation = validationOf(v.Elem().Type()); validation.err != nil {
+			return validation.err
+		}
+	}
+
+	req := ctx.Request
+	maxBodySize := int64(DefaultMaxBodySize)
+	if ctx.station != nil && ctx.station.options.MaxBodySize > 0 {
+		maxBodySize = ctx.station.options.MaxBodySize
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = http.MaxBytesReader(ctx.ResponseWriter, req.Body, maxBodySize)
+	}
+
+	var mediaType string
+	if contentType := req.Header.Get(ContentType); contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return &BindError{Status: http.StatusUnsupportedMediaType, Message: "invalid Content-Type: " + contentType}
+		}
+	}
+
+	var err error
+	hasBody := req.ContentLength != 0 && req.Body != nil && req.Body != http.NoBody
+	switch mediaType {
+	case ContentForm:
+		err = req.ParseForm()
+	case ContentMultipart:
+		memory := maxBodySize
+		if memory > defaultMultipartMemory {
+			memory = defaultMultipartMemory
+		}
+		err = req.ParseMultipartForm(memory)
+	case "":
+		if hasBody && req.ContentLength > 0 {
+			return &BindError{Status: http.StatusUnsupportedMediaType, Message: "missing Content-Type"}
+		}
+	default:
+		decoder, found := bodyDecoders[mediaType]
+		if !found {
+			if hasBody {
+				return &BindError{Status: http.StatusUnsupportedMediaType, Message: "unsupported Content-Type: " + mediaType}
+			}
+			break
+		}
+		if hasBody {
+			err = decoder(req.Body, dst)
+		}
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return &BindError{Status: http.StatusRequestEntityTooLarge, Message: "request body too large, the limit is " + strconv.FormatInt(maxBodySize, 10) + " bytes"}
+		}
+		return &BindError{Status: http.StatusBadRequest, Message: "malformed body: " + err.Error()}
+	}
+
+	if !isStruct {
+		return nil
+	}
+
+	form := req.Form
+	if form == nil {
+		form = req.URL.Query()
+	}
+	var files map[string][]*multipart.FileHeader
+	if req.MultipartForm != nil {
+		files = req.MultipartForm.File
+	}
+
+	var fields []FieldError
+	ctx.bindValues(v.Elem(), form, files, &fields)
+	if len(fields) > 0 {
+		return &BindError{Status: http.StatusBadRequest, Message: "invalid values", Fields: fields}
+	}
+	// the errors have the names which the client sent
+	tags := []string{"form", "param", "json", "xml"}
+	if mediaType == ContentJSON {
+		tags = []string{"json", "form", "param"}
+	} else if mediaType == ContentXML || mediaType == ContentXMLText {
+		tags = []string{"xml", "form", "param"}
+	}
+	validateStruct(v.Elem(), validation, "", tags, &fields)
+	if len(fields) > 0 {
+		return &BindError{Status: http.StatusUnprocessableEntity, Message: "validation failed", Fields: fields}
+	}
+	return nil
+}
+
+// MustBind calls the Bind, on error it renders the error with the station's bind error handler (see Station.OnBindError),
+// it stops the execution of the next handlers and returns false
+//
+// an invalid validate tag is an error of the server, it's sent by the Context.Error, as 500
+func (ctx *Context) MustBind(dst interface{}) bool {
+	err := ctx.Bind(dst)
+	if err == nil {
+		return true
+	}
+	bindErr, ok := err.(*BindError)
+	if !ok {
+		ctx.Error(err)
+		return false
+	}
+	handler := defaultBindErrorHandler
+	if ctx.station != nil && ctx.station.bindErrorHandler != nil {
+		handler = ctx.station.bindErrorHandler
+	}
+	handler(ctx, bindErr)
+	ctx.StopExecution()
+	return false
+}
+
+var (
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType     = reflect.TypeOf([]*multipart.FileHeader(nil))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// bindValues sets the form values, the files and the path parameters to the fields of the struct v, by their tags
+func (ctx *Context) bindValues(v reflect.Value, form map[string][]string, files map[string][]*multipart.FileHeader, fields *[]FieldError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		fv := v.Field(i)
+
+		if name := field.Tag.Get("form"); name != "" && name != "-" {
+			switch {
+			case field.Type == fileHeaderType:
+				if len(files[name]) > 0 {
+					fv.Set(reflect.ValueOf(files[name][0]))
+				}
+			case field.Type == fileHeadersType:
+				if len(files[name]) > 0 {
+					fv.Set(reflect.ValueOf(files[name]))
+				}
+			default:
+				if values := form[name]; len(values) > 0 {
+					if err := setField(fv, values); err != nil {
+						*fields = append(*fields, FieldError{Field: name, Tag: "type", Message: name + " should be " + typeName(field.Type)})
+					}
+				}
+			}
+			continue
+		}
+
+		if name := field.Tag.Get("param"); name != "" && name != "-" {
+			for _, p := range ctx.Params {
+				if p.Key == name {
+					if err := setField(fv, []string{p.Value}); err != nil {
+						*fields = append(*fields, FieldError{Field: name, Tag: "type", Message: name + " should be " + typeName(field.Type)})
+					}
+					break
+				}
+			}
+			continue
+		}
+
+		if fv.Kind() == reflect.Struct && field.Type != timeType && !reflect.PtrTo(field.Type).Implements(textUnmarshalerType) && fv.CanSet() {
+			ctx.bindValues(fv, form, files, fields)
+		}
+	}
+}
+
+// setField sets the string values to the field, a slice receives all the values, the rest only the first
+func setField(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice && !v.Type().Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return setValue(v, values[0])
+}
+
+// setValue sets a string value to a basic type or to an encoding.TextUnmarshaler
+func setValue(v reflect.Value, value string) error {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(value))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// typeName returns the name of the type for the error messages, ex: "a number"
+func typeName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if t == reflect.TypeOf(time.Duration(0)) {
+			return "a duration"
+		}
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "a boolean"
+	}
+	return "a valid " + t.String()
+}
+
+// fieldName returns the name of the field for the errors, the name of the first tag (ex: json, form) which the field has
+func fieldName(field reflect.StructField, tags []string) string {
+	for _, tag := range tags {
+		if name := strings.Split(field.Tag.Get(tag), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// validationRule is a parsed rule of a `validate` tag, ex: min=1
+type validationRule struct {
+	name  string
+	param string
+	// limit is the number of the min, max and len rules
+	limit float64
+}
+
+// fieldValidation are the rules of a struct field and the validation of its struct, if it's a nested struct
+type fieldValidation struct {
+	rules  []validationRule
+	nested *structValidation
+}
+
+// structValidation are the parsed `validate` tags of a struct type, by the index of the fields, see validationOf
+type structValidation struct {
+	fields []fieldValidation
+	// err is the first invalid tag of the struct or of its nested structs
+	err error
+}
+
+// structValidations are the parsed struct types, reflect.Type: *structValidation
+var structValidations sync.Map
+
+// validationOf returns the parsed `validate` tags of the struct type t and of its nested structs, they are parsed once per type
+func validationOf(t reflect.Type) *structValidation {
+	if validation, found := structValidations.Load(t); found {
+		return validation.(*structValidation)
+	}
+
+	parsed := make(map[reflect.Type]*structValidation)
+	parseValidation(t, parsed)
+	// the error of a nested struct is the error of the structs which contain it, a recursive struct is parsed once
+	for changed := true; changed; {
+		changed = false
+		for _, validation := range parsed {
+			for _, f := range validation.fields {
+				if validation.err == nil && f.nested != nil && f.nested.err != nil {
+					validation.err = f.nested.err
+					changed = true
+				}
+			}
+		}
+	}
+	for typ, validation := range parsed {
+		structValidations.LoadOrStore(typ, validation)
+	}
+	validation, _ := structValidations.Load(t)
+	return validation.(*structValidation)
+}
+
+// parseValidation parses the `validate` tags of the struct type t, the parsed are the types which are parsed by this validationOf
+func parseValidation(t reflect.Type, parsed map[reflect.Type]*structValidation) *structValidation {
+	if validation, found := parsed[t]; found {
+		return validation
+	}
+	if validation, found := structValidations.Load(t); found {
+		return validation.(*structValidation)
+	}
+
+	validation := &structValidation{fields: make([]fieldValidation, t.NumField())}
+	parsed[t] = validation
+	for i := range validation.fields {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		if tag := field.Tag.Get("validate"); tag != "" && tag != "-" {
+			rules, err := parseRules(t.String()+"."+field.Name, field.Type, tag)
+			if err != nil && validation.err == nil {
+				validation.err = err
+			}
+			validation.fields[i].rules = rules
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct && fieldType != timeType {
+			validation.fields[i].nested = parseValidation(fieldType, parsed)
+		}
+	}
+	return validation
+}
+
+// parseRules parses the rules of a `validate` tag, ex: required,min=1
+// it returns an error if a rule is unknown, its parameter is invalid or it's not supported for the type of the field
+func parseRules(name string, fieldType reflect.Type, tag string) ([]validationRule, error) {
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	var rules []validationRule
+	for _, rule := range strings.Split(tag, ",") {
+		r := validationRule{name: strings.TrimSpace(rule)}
+		if idx := strings.IndexByte(r.name, '='); idx != -1 {
+			r.name, r.param = r.name[:idx], r.name[idx+1:]
+		}
+
+		switch r.name {
+		case "required", "oneof":
+		case "min", "max", "len":
+			limit, err := strconv.ParseFloat(r.param, 64)
+			if err != nil {
+				return nil, errors.New("[Iris] Bind: the " + r.name + " rule of " + name + " needs a number")
+			}
+			r.limit = limit
+			switch fieldType.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+				reflect.Float32, reflect.Float64, reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+			default:
+				return nil, errors.New("[Iris] Bind: the " + r.name + " rule of " + name + " is not supported for " + fieldType.String())
+			}
+		case "email":
+			if fieldType.Kind() != reflect.String {
+				return nil, errors.New("[Iris] Bind: the email rule of " + name + " needs a string")
+			}
+		default:
+			return nil, errors.New("[Iris] Bind: unknown validation rule " + r.name + " of " + name)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// validateStruct validates the fields of the struct v by their parsed `validate` tags, the nested structs are validated too
+// the names of the fields are taken by the tags, in order
+func validateStruct(v reflect.Value, validation *structValidation, prefix string, tags []string, fields *[]FieldError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		fv := v.Field(i)
+		name := prefix + fieldName(field, tags)
+
+		if rules := validation.fields[i].rules; len(rules) > 0 {
+			validateField(fv, name, rules, fields)
+		}
+
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if nested := validation.fields[i].nested; nested != nil && fv.Kind() == reflect.Struct {
+			if field.Anonymous {
+				validateStruct(fv, nested, prefix, tags, fields)
+			} else {
+				validateStruct(fv, nested, name+".", tags, fields)
+			}
+		}
+	}
+}
+
+// validateField validates a field by its rules, ex: required,min=1
+// the rest rules of an empty field are skipped, if it's not required,
+// a field is empty if it's a nil pointer or its zero value, a pointer to a zero value is not empty
+func validateField(v reflect.Value, name string, rules []validationRule, fields *[]FieldError) {
+	isPtr := v.Kind() == reflect.Ptr
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v = reflect.Value{}
+			break
+		}
+		v = v.Elem()
+	}
+	empty := !v.IsValid() || (!isPtr && v.IsZero())
+
+	for _, rule := range rules {
+		if rule.name == "required" {
+			if empty {
+				*fields = append(*fields, FieldError{Field: name, Tag: rule.name, Message: name + " is required"})
+				return
+			}
+			continue
+		}
+		if empty {
+			return
+		}
+
+		var message string
+		switch rule.name {
+		case "min", "max", "len":
+			size, isLength := validationSize(v)
+			what := ""
+			if isLength {
+				what = " length"
+			}
+			if rule.name == "min" && size < rule.limit {
+				message = name + what + " should be at least " + rule.param
+			} else if rule.name == "max" && size > rule.limit {
+				message = name + what + " should be at most " + rule.param
+			} else if rule.name == "len" && size != rule.limit {
+				message = name + what + " should be " + rule.param
+			}
+		case "oneof":
+			value := fmt.Sprint(v.Interface())
+			found := false
+			for _, allowed := range strings.Fields(rule.param) {
+				if value == allowed {
+					found = true
+					break
+				}
+			}
+			if !found {
+				message = name + " should be one of " + strings.Join(strings.Fields(rule.param), ", ")
+			}
+		case "email":
+			if address, err := mail.ParseAddress(v.String()); err != nil || address.Address != v.String() {
+				message = name + " should be a valid email"
+			}
+		}
+
+		if message != "" {
+			*fields = append(*fields, FieldError{Field: name, Tag: rule.name, Param: rule.param, Message: message})
+		}
+	}
+}
+
+// validationSize returns the value of a number or the length of a string, slice or map, for the min, max and len rules
+// the kind of the field is checked by the parseRules
+func validationSize(v reflect.Value) (size float64, isLength bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	}
+	return float64(v.Len()), true
+}
@@ -0,0 +1,281 @@
+package iris
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testBindAddress struct {
+	City string `json:"city" form:"city" validate:"required"`
+}
+
+type testBindUser struct {
+	ID       int                   `param:"id"`
+	Name     string                `json:"name" form:"name" validate:"required,min=2,max=10"`
+	Email    string                `json:"email" form:"email" validate:"email"`
+	Age      int                   `json:"age" form:"age" validate:"min=18"`
+	Role     string                `json:"role" form:"role" validate:"oneof=admin user"`
+	Tags     []string              `json:"tags" form:"tag" validate:"max=2"`
+	Timeout  time.Duration         `form:"timeout"`
+	Page     *int                  `form:"page"`
+	Avatar   *multipart.FileHeader `form:"avatar"`
+	Address  testBindAddress       `json:"address"`
+	internal string
+}
+
+// testBind binds the request to a testBindUser on the /users/:id route and returns the user and the error
+func testBind(t *testing.T, req *http.Request) (testBindUser, error) {
+	var (
+		user testBindUser
+		err  error
+	)
+	s := New()
+	s.Handle("POST", "/users/:id", HandlerFunc(func(c *Context) { err = c.Bind(&user) }))
+	s.Handle("GET", "/users/:id", HandlerFunc(func(c *Context) { err = c.Bind(&user) }))
+	s.Serve().ServeHTTP(httptest.NewRecorder(), req)
+	return user, err
+}
+
+func TestBindJSON(t *testing.T) {
+	req := httptest.NewRequest("POST", "/users/42?page=3", strings.NewReader(`{"name":"kataras","email":"k@ideopod.com","age":25,"role":"admin","address":{"city":"Athens"}}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	user, err := testBind(t, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 42 || user.Name != "kataras" || user.Age != 25 || user.Address.City != "Athens" || user.Page == nil || *user.Page != 3 {
+		t.Fatalf("Unexpected user: %+v", user)
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	req := httptest.NewRequest("POST", "/users/7", strings.NewReader("name=makis&age=30&role=user&tag=a&tag=b&timeout=2s&address.city=x"))
+	req.Header.Set("Content-Type", ContentForm)
+	user, err := testBind(t, req)
+	if err == nil {
+		t.Fatal("Expecting the validation error of the address.city")
+	}
+	fields := err.(*BindError).Fields
+	if err.(*BindError).Status != http.StatusUnprocessableEntity || len(fields) != 1 || fields[0].Field != "address.city" || fields[0].Tag != "required" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if user.ID != 7 || user.Name != "makis" || user.Age != 30 || len(user.Tags) != 2 || user.Timeout != 2*time.Second {
+		t.Fatalf("Unexpected user: %+v", user)
+	}
+}
+
+func TestBindMultipart(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("name", "gerasimos")
+	writer.WriteField("age", "20")
+	writer.WriteField("role", "user")
+	writer.WriteField("city", "Athens")
+	file, _ := writer.CreateFormFile("avatar", "avatar.png")
+	file.Write([]byte("png"))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/users/1", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	user, err := testBind(t, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "gerasimos" || user.Avatar == nil || user.Avatar.Filename != "avatar.png" || user.Address.City != "Athens" {
+		t.Fatalf("Unexpected user: %+v", user)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		status      int
+		fields      []string
+		maxBodySize int64
+	}{
+		{"application/json", `{"name":`, http.StatusBadRequest, nil, 0},
+		{"application/yaml", `name: kataras`, http.StatusUnsupportedMediaType, nil, 0},
+		{"", `{"name":"kataras"}`, http.StatusUnsupportedMediaType, nil, 0},
+		{ContentForm, "name=kataras&age=old&role=user&city=Athens", http.StatusBadRequest, []string{"age"}, 0},
+		{"application/json", `{"name":"k","email":"not an email","age":12,"role":"root","tags":["a","b","c"],"address":{"city":"Athens"}}`,
+			http.StatusUnprocessableEntity, []string{"name", "email", "age", "role", "tags"}, 0},
+		{"application/json", `{"name":"` + strings.Repeat("k", 64) + `"}`, http.StatusRequestEntityTooLarge, nil, 60},
+	}
+
+	for _, tt := range tests {
+		options := defaultOptions()
+		options.MaxBodySize = tt.maxBodySize
+		s := Custom(options)
+		var err error
+		s.Handle("POST", "/users/:id", HandlerFunc(func(c *Context) { err = c.Bind(&testBindUser{}) }))
+		req := httptest.NewRequest("POST", "/users/1", strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		s.Serve().ServeHTTP(httptest.NewRecorder(), req)
+
+		bindErr, ok := err.(*BindError)
+		if !ok {
+			t.Fatalf("Expecting a *BindError for %q but got %v", tt.body, err)
+		}
+		if bindErr.Status != tt.status || len(bindErr.Fields) != len(tt.fields) {
+			t.Fatalf("Expecting status %d with the fields %v for %q but got %d: %v", tt.status, tt.fields, tt.body, bindErr.Status, bindErr)
+		}
+		for i, field := range tt.fields {
+			if bindErr.Fields[i].Field != field {
+				t.Fatalf("Expecting the error of the %s but got %s", field, bindErr.Fields[i].Field)
+			}
+		}
+	}
+}
+
+func TestMustBind(t *testing.T) {
+	s := New()
+	executed := false
+	s.Post("/users/:id", HandlerFunc(func(c *Context) {
+		if c.MustBind(&testBindUser{}) {
+			c.Next()
+		}
+	}), HandlerFunc(func(c *Context) { executed = true }))
+
+	res := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/users/1", strings.NewReader(`{"name":"k"}`))
+	req.Header.Set("Content-Type", ContentJSON)
+	s.Serve().ServeHTTP(res, req)
+	if executed {
+		t.Fatal("Expecting the next handler to not be executed")
+	}
+	var bindErr BindError
+	if res.Code != http.StatusUnprocessableEntity || json.Unmarshal(res.Body.Bytes(), &bindErr) != nil || len(bindErr.Fields) != 2 {
+		t.Fatalf("Expecting the JSON of the error with 422 but got %d %s", res.Code, res.Body.String())
+	}
+
+	// custom handler
+	s.OnBindError(func(c *Context, err *BindError) { c.WriteText(http.StatusBadRequest, err.Error()) })
+	res = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/users/1", strings.NewReader(`{"name":"k"}`))
+	req.Header.Set("Content-Type", ContentJSON)
+	s.Serve().ServeHTTP(res, req)
+	if res.Code != http.StatusBadRequest || !strings.Contains(res.Body.String(), "name length should be at least 2") {
+		t.Fatalf("Expecting the custom handler's response but got %d %s", res.Code, res.Body.String())
+	}
+}
+
+type testBindNode struct {
+	Name string        `json:"name" validate:"required"`
+	Next *testBindNode `json:"next"`
+}
+
+type testBindInvalidNode struct {
+	Next *testBindInvalidNode `json:"next"`
+	Age  int                  `json:"age" validate:"min=abc"`
+}
+
+type testBindInvalidNested struct {
+	Address struct {
+		City int `json:"city" validate:"email"`
+	} `json:"address"`
+}
+
+func TestBindInvalidTags(t *testing.T) {
+	tests := []struct {
+		dst     interface{}
+		message string
+	}{
+		{&struct {
+			Age int `validate:"min=abc"`
+		}{}, "Age needs a number"},
+		{&struct {
+			Name string `validate:"required,unique"`
+		}{}, "unknown validation rule unique of"},
+		{&struct {
+			Admin bool `validate:"max=1"`
+		}{}, "the max rule of"},
+		{&testBindInvalidNested{}, "the email rule of"},
+		{&testBindInvalidNode{}, "the min rule of iris.testBindInvalidNode.Age needs a number"},
+	}
+	for i, tt := range tests {
+		for j := 0; j < 2; j++ {
+			var err error
+			s := New()
+			s.Post("/", HandlerFunc(func(c *Context) { err = c.Bind(tt.dst) }))
+			req := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+			req.Header.Set("Content-Type", ContentJSON)
+			s.Serve().ServeHTTP(httptest.NewRecorder(), req)
+			if _, isBindErr := err.(*BindError); err == nil || isBindErr || !strings.Contains(err.Error(), tt.message) {
+				t.Fatalf("[%d] Expecting the configuration error %q but got %v", i, tt.message, err)
+			}
+		}
+	}
+
+	// a valid recursive struct
+	var node testBindNode
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"a","next":{"next":{"name":"c"}}}`))
+	req.Header.Set("Content-Type", ContentJSON)
+	var err error
+	s := New()
+	s.Post("/", HandlerFunc(func(c *Context) { err = c.Bind(&node) }))
+	s.Serve().ServeHTTP(httptest.NewRecorder(), req)
+	if bindErr, ok := err.(*BindError); !ok || len(bindErr.Fields) != 1 || bindErr.Fields[0].Field != "next.name" {
+		t.Fatalf("Expecting the required error of the next.name but got %v", err)
+	}
+
+	// the MustBind sends it as 500, without the message
+	s = New()
+	s.Post("/", HandlerFunc(func(c *Context) { c.MustBind(&testBindInvalidNode{}) }))
+	res := httptest.NewRecorder()
+	s.Serve().ServeHTTP(res, httptest.NewRequest("POST", "/", nil))
+	if res.Code != http.StatusInternalServerError || strings.Contains(res.Body.String(), "min") {
+		t.Fatalf("Expecting 500 without the error but got %d %q", res.Code, res.Body.String())
+	}
+}
+
+func TestBindEmptyValues(t *testing.T) {
+	type profile struct {
+		Age   int   `json:"age" validate:"min=18"`
+		Page  *int  `json:"page" validate:"min=1"`
+		Admin *bool `json:"admin" validate:"required"`
+	}
+	tests := []struct {
+		body   string
+		fields []string
+	}{
+		// the empty values are valid if they are not required
+		{`{"admin":true}`, nil},
+		{`{}`, []string{"admin"}},
+		// a zero int is empty, a pointer to zero is a sent value
+		{`{"age":0,"page":0,"admin":false}`, []string{"page"}},
+		{`{"age":12,"page":2,"admin":false}`, []string{"age"}},
+	}
+	for i, tt := range tests {
+		var err error
+		s := New()
+		s.Post("/", HandlerFunc(func(c *Context) { err = c.Bind(&profile{}) }))
+		req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", ContentJSON)
+		s.Serve().ServeHTTP(httptest.NewRecorder(), req)
+
+		if len(tt.fields) == 0 {
+			if err != nil {
+				t.Fatalf("[%d] Expecting no error for %s but got %v", i, tt.body, err)
+			}
+			continue
+		}
+		bindErr, ok := err.(*BindError)
+		if !ok || len(bindErr.Fields) != len(tt.fields) {
+			t.Fatalf("[%d] Expecting the errors of %v for %s but got %v", i, tt.fields, tt.body, err)
+		}
+		for j, field := range tt.fields {
+			if bindErr.Fields[j].Field != field {
+				t.Fatalf("[%d] Expecting the error of the %s but got %s", i, field, bindErr.Fields[j].Field)
+			}
+		}
+	}
+}
@@ -42,9 +42,11 @@ const (
 )
 
 // PathParameter is a struct which contains Key and Value, used for named path parameters
+// Typed is the value which the parameter's constraint produced (if any), ex: int for the :id(int)
 type PathParameter struct {
 	Key   string
 	Value string
+	Typed interface{}
 }
 
 // PathParameters type for a slice of PathParameter
@@ -62,9 +64,20 @@ func (params PathParameters) Get(key string) string {
 	return ""
 }
 
+// GetTyped returns the typed value, which the parameter's constraint produced, from a key inside this Parameters
+// If no parameter with this key given or the parameter has no constraint then it returns nil
+func (params PathParameters) GetTyped(key string) interface{} {
+	for _, p := range params {
+		if p.Key == key {
+			return p.Typed
+		}
+	}
+	return nil
+}
+
 // Set sets a PathParameter to the PathParameters , it's not used anywhere.
 func (params PathParameters) Set(key string, value string) {
-	params = append(params, PathParameter{key, value})
+	params = append(params, PathParameter{Key: key, Value: value})
 }
 
 // String returns a string implementation of all parameters that this PathParameters object keeps
@@ -104,7 +117,7 @@ func ParseParams(str string) PathParameters {
 
 		key := _paramsstr[i][:idxOfEq]
 		val := _paramsstr[i][idxOfEq+1:]
-		params = append(params, PathParameter{key, val})
+		params = append(params, PathParameter{Key: key, Value: val})
 	}
 	return params
 }
@@ -140,8 +153,8 @@ type BranchCase uint8
 
 // IBranch is the interface which the type Branch must implement
 type IBranch interface {
-	AddBranch(string, Middleware)
-	AddNode(uint8, string, string, Middleware)
+	AddBranch(string, Middleware, ParamConstraints)
+	AddNode(uint8, string, string, Middleware, ParamConstraints)
 	GetBranch(string, PathParameters) (Middleware, PathParameters, bool)
 	GivePrecedenceTo(index int) int
 }
@@ -156,12 +169,38 @@ type Branch struct {
 	tokens      string
 	nodes       []*Branch
 	middleware  Middleware
+	// constrained is not nil only when at least one route of this Branch has constraints on it's parameters
+	constrained []*constrainedMiddleware
 	precedence  uint64
 	paramsLen   uint8
+	// segments is not nil only on the root Branch, when at least one of the routes can't be kept by the trie, see segmentTree
+	segments *segmentTree
+	// routes are the registed routes of the tree, kept by the root Branch until it switches to the segments
+	routes []branchRoute
+}
+
+// branchRoute is a registed route of a tree
+type branchRoute struct {
+	path        string
+	middleware  Middleware
+	constraints ParamConstraints
 }
 
 // AddBranch adds a branch to the existing branch or to the tree if no branch has the prefix of
-func (b *Branch) AddBranch(path string, middleware Middleware) {
+func (b *Branch) AddBranch(path string, middleware Middleware, constraints ParamConstraints) {
+	if b.segments == nil && (isExtendedPath(path) || b.collides(path)) {
+		b.segments = newSegmentTree()
+		for _, r := range b.routes {
+			b.segments.add(r.path, r.middleware, r.constraints)
+		}
+		b.routes = nil
+	}
+	if b.segments != nil {
+		b.segments.add(path, middleware, constraints)
+		return
+	}
+	b.routes = append(b.routes, branchRoute{path: path, middleware: middleware, constraints: constraints})
+
 	fullPath := path
 	b.precedence++
 	numParams := GetParamsLen(path)
@@ -186,6 +225,7 @@ func (b *Branch) AddBranch(path string, middleware Middleware) {
 					tokens:      b.tokens,
 					nodes:       b.nodes,
 					middleware:  b.middleware,
+					constrained: b.constrained,
 					precedence:  b.precedence - 1,
 				}
 
@@ -199,6 +239,7 @@ func (b *Branch) AddBranch(path string, middleware Middleware) {
 				b.tokens = string([]byte{b.part[i]})
 				b.part = path[:i]
 				b.middleware = nil
+				b.constrained = nil
 				b.hasWildNode = false
 			}
 
@@ -214,6 +255,19 @@ func (b *Branch) AddBranch(path string, middleware Middleware) {
 					}
 					numParams--
 
+					if b.BranchCase == hasParams && path[0] == ParameterStartByte {
+						// same parameter with other name, the route's names are kept by its constrainedMiddleware
+						end := 1
+						for end < len(path) && path[end] != SlashByte {
+							end++
+						}
+						path = b.part + path[end:]
+						if constraints == nil {
+							// not nil in order to keep the route's names
+							constraints = ParamConstraints{}
+						}
+					}
+
 					if len(path) >= len(b.part) && b.part == path[:len(b.part)] {
 
 						if len(b.part) >= len(path) || path[len(b.part)] == '/' {
@@ -250,25 +304,22 @@ func (b *Branch) AddBranch(path string, middleware Middleware) {
 					b.GivePrecedenceTo(len(b.tokens) - 1)
 					b = node
 				}
-				b.AddNode(numParams, path, fullPath, middleware)
+				b.AddNode(numParams, path, fullPath, middleware, constraints)
 				return
 
 			} else if i == len(path) {
-				if b.middleware != nil {
-					return
-				}
-				b.middleware = middleware
+				b.setMiddleware(fullPath, middleware, constraints)
 			}
 			return
 		}
 	} else {
-		b.AddNode(numParams, path, fullPath, middleware)
+		b.AddNode(numParams, path, fullPath, middleware, constraints)
 		b.BranchCase = isRoot
 	}
 }
 
 // AddNode adds a branch as children to other Branch
-func (b *Branch) AddNode(numParams uint8, path string, fullPath string, middleware Middleware) {
+func (b *Branch) AddNode(numParams uint8, path string, fullPath string, middleware Middleware, constraints ParamConstraints) {
 	var offset int
 
 	for i, max := 0, len(path); numParams > 0; i++ {
@@ -354,9 +405,9 @@ func (b *Branch) AddNode(numParams uint8, path string, fullPath string, middlewa
 				part:       path[i:],
 				BranchCase: matchEverything,
 				paramsLen:  1,
-				middleware: middleware,
 				precedence: 1,
 			}
+			child.setMiddleware(fullPath, middleware, constraints)
 			b.nodes = []*Branch{child}
 
 			return
@@ -364,11 +415,70 @@ func (b *Branch) AddNode(numParams uint8, path string, fullPath string, middlewa
 	}
 
 	b.part = path[offset:]
-	b.middleware = middleware
+	b.setMiddleware(fullPath, middleware, constraints)
+}
+
+// collides returns true if the trie can't keep the path with the already registed routes
+func (b *Branch) collides(path string) bool {
+	for _, r := range b.routes {
+		if trieCollides(r.path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// setMiddleware sets the middleware of this Branch,
+// if the Branch has already a middleware then the new one is kept only if one of them has constraints,
+// the routes with constraints are checked first, by the order they registed, and the one without constraints (if any) is the last
+func (b *Branch) setMiddleware(fullPath string, middleware Middleware, constraints ParamConstraints) {
+	if b.middleware == nil {
+		b.middleware = middleware
+		if constraints != nil {
+			b.constrained = []*constrainedMiddleware{newConstrainedMiddleware(fullPath, middleware, constraints)}
+		}
+		return
+	}
+
+	if len(constraints) == 0 && (b.constrained == nil || b.constrained[len(b.constrained)-1].constraints == nil) {
+		// we already have a route which accepts everything
+		return
+	}
+
+	if b.constrained == nil {
+		// the already registed route has no constraints, we don't have its full path here but the keys are the same as the Branch's
+		b.constrained = []*constrainedMiddleware{{keys: nil, middleware: b.middleware}}
+	}
+
+	c := newConstrainedMiddleware(fullPath, middleware, constraints)
+	last := len(b.constrained) - 1
+	if c.constraints != nil && b.constrained[last].constraints == nil {
+		// keep the route without constraints as the last one
+		b.constrained = append(b.constrained[:last], c, b.constrained[last])
+	} else {
+		b.constrained = append(b.constrained, c)
+	}
+	b.middleware = b.constrained[0].middleware
+}
+
+// resolve returns the middleware which its constraints are matching the request's parameters
+func (b *Branch) resolve(params PathParameters) Middleware {
+	if b.constrained == nil {
+		return b.middleware
+	}
+	for _, c := range b.constrained {
+		if c.match(params) {
+			return c.middleware
+		}
+	}
+	return nil
 }
 
 // GetBranch is used by the Router, it finds and returns the correct branch for a path
 func (b *Branch) GetBranch(path string, _params PathParameters) (middleware Middleware, params PathParameters, mustRedirect bool) {
+	if b.segments != nil {
+		return b.segments.lookup(path, _params)
+	}
 	params = _params
 loop:
 	for {
@@ -405,6 +515,7 @@ loop:
 					params = params[:i+1]
 					params[i].Key = b.part[1:]
 					params[i].Value = path[:end]
+					params[i].Typed = nil
 
 					if end < len(path) {
 						if len(b.nodes) > 0 {
@@ -417,7 +528,8 @@ loop:
 						return
 					}
 
-					if middleware = b.middleware; middleware != nil {
+					if b.middleware != nil {
+						middleware = b.resolve(params)
 						return
 					} else if len(b.nodes) == 1 {
 						b = b.nodes[0]
@@ -434,8 +546,9 @@ loop:
 					params = params[:i+1]
 					params[i].Key = b.part[2:]
 					params[i].Value = path
+					params[i].Typed = nil
 
-					middleware = b.middleware
+					middleware = b.resolve(params)
 					return
 
 				default:
@@ -443,7 +556,8 @@ loop:
 				}
 			}
 		} else if path == b.part {
-			if middleware = b.middleware; middleware != nil {
+			if b.middleware != nil {
+				middleware = b.resolve(params)
 				return
 			}
 
@@ -0,0 +1,464 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package iris
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	// OptionalParameterByte is the byte which makes a named parameter's segment optional, ex: /docs/:version?/intro
+	OptionalParameterByte = byte('?')
+)
+
+// isParamNameByte returns true if the byte can be part of the name of an in-segment or optional parameter, letters, digits and underscore
+// the name ends on any other byte, ex: /report-:year.:format has the parameters year and format
+func isParamNameByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// isWholeSegmentParam returns true if the parameter which starts at the i (the ':' or the '*') is the whole segment,
+// it's not optional and the segment has no other parameters, the constraints are skipped, ex: /users/:user-id(int)
+func isWholeSegmentParam(path string, i int) bool {
+	if i > 0 && path[i-1] != SlashByte {
+		return false
+	}
+	depth := 0
+	j := i + 1
+	for ; j < len(path); j++ {
+		c := path[j]
+		if depth > 0 {
+			switch c {
+			case '\\':
+				j++
+			case ConstraintStartByte:
+				depth++
+			case ConstraintEndByte:
+				depth--
+			}
+			continue
+		}
+		if c == SlashByte {
+			break
+		}
+		switch c {
+		case ConstraintStartByte:
+			depth++
+		case ParameterStartByte, MatchEverythingByte:
+			return false
+		}
+	}
+	return j > len(path) || path[j-1] != OptionalParameterByte
+}
+
+// paramNameEnd returns the index after the name of the parameter which starts at the i (the ':' or the '*')
+//
+// the name of a parameter which is the whole segment runs to the next slash or constraint, ex: /users/:user-id or /f/:name.json,
+// the name of an in-segment or optional parameter is letters, digits and underscore, ex: /report-:year.:format or /docs/:version?/intro
+func paramNameEnd(path string, i int) int {
+	end := i + 1
+	if isWholeSegmentParam(path, i) {
+		for end < len(path) && path[end] != SlashByte && path[end] != ConstraintStartByte {
+			end++
+		}
+		return end
+	}
+	for end < len(path) && isParamNameByte(path[end]) {
+		end++
+	}
+	return end
+}
+
+// validatePath checks the syntax of the parameters of a registed path, without the constraints
+func validatePath(path string) error {
+	segments := strings.Split(path, Slash)
+	wildcards := 0
+	for _, segment := range segments {
+		for j := 0; j < len(segment); j++ {
+			c := segment[j]
+			switch c {
+			case MatchEverythingByte:
+				if j != 0 {
+					return errors.New("the match everything parameter should be the whole segment, ex: /files/*path")
+				}
+				if wildcards++; wildcards > 1 {
+					return errors.New("only one match everything parameter is allowed")
+				}
+				fallthrough
+			case ParameterStartByte:
+				end := paramNameEnd(segment, j)
+				if end == j+1 {
+					return errors.New("missing the name of the parameter on the segment '" + segment + "'")
+				}
+				if c == MatchEverythingByte && end != len(segment) {
+					return errors.New("the match everything parameter should be the whole segment, ex: /files/*path")
+				}
+				if end < len(segment) && segment[end] == ParameterStartByte {
+					return errors.New("the parameters of the segment '" + segment + "' should be separated by static text, ex: :year.:format")
+				}
+				if end < len(segment) && segment[end] == OptionalParameterByte && (j != 0 || end != len(segment)-1) {
+					return errors.New("only a parameter which is the whole segment can be optional, ex: /docs/:version?/intro")
+				}
+				j = end - 1
+			case OptionalParameterByte:
+				if j != len(segment)-1 || segment[0] != ParameterStartByte || paramNameEnd(segment, 0) != j {
+					return errors.New("only a parameter which is the whole segment can be optional, ex: /docs/:version?/intro")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isExtendedPath returns true if the path has an in-segment parameter (/report-:year.:format), an optional segment (/docs/:version?/intro)
+// or a match everything parameter which is not the last segment (/files/*path/edit), the trie of the Branch doesn't support them
+func isExtendedPath(path string) bool {
+	segments := strings.Split(path, Slash)
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		switch segment[0] {
+		case MatchEverythingByte:
+			if i != len(segments)-1 {
+				return true
+			}
+		case ParameterStartByte:
+			if !isWholeSegmentParam(segment, 0) {
+				return true
+			}
+		default:
+			if strings.IndexByte(segment, ParameterStartByte) != -1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// trieCollides returns true if the trie of the Branch can't keep both paths,
+// a parameter or a match everything parameter is at the same position as a static segment
+func trieCollides(a, b string) bool {
+	segmentsA := strings.Split(a, Slash)
+	segmentsB := strings.Split(b, Slash)
+	for i := 0; i < len(segmentsA) && i < len(segmentsB); i++ {
+		sa, sb := segmentsA[i], segmentsB[i]
+		wildA, wildB := strings.HasPrefix(sa, "*"), strings.HasPrefix(sb, "*")
+		paramA, paramB := strings.HasPrefix(sa, ":"), strings.HasPrefix(sb, ":")
+		switch {
+		case wildA || wildB:
+			return wildA != wildB
+		case paramA && paramB:
+			continue
+		case paramA || paramB:
+			// /users/ and /users/:id
+			return sa != "" && sb != ""
+		case sa != sb:
+			return false
+		}
+	}
+	return false
+}
+
+// expandOptional returns the paths which a path with optional segments matches, ex: /docs/:version?/intro returns /docs/:version/intro and /docs/intro
+func expandOptional(path string) []string {
+	if strings.IndexByte(path, OptionalParameterByte) == -1 {
+		return []string{path}
+	}
+	paths := []string{""}
+	for _, segment := range strings.Split(path[1:], Slash) {
+		if strings.HasSuffix(segment, "?") {
+			segment = segment[:len(segment)-1]
+			without := make([]string, len(paths))
+			copy(without, paths)
+			for j := range paths {
+				paths[j] += Slash + segment
+			}
+			paths = append(paths, without...)
+			continue
+		}
+		for j := range paths {
+			paths[j] += Slash + segment
+		}
+	}
+	for j := range paths {
+		if paths[j] == "" {
+			paths[j] = Slash
+		}
+	}
+	return paths
+}
+
+// pathShape returns the path without the names of the parameters, two paths with the same shape match the same requests
+func pathShape(path string) string {
+	shape := make([]byte, 0, len(path))
+	for i := 0; i < len(path); i++ {
+		shape = append(shape, path[i])
+		if path[i] == ParameterStartByte || path[i] == MatchEverythingByte {
+			i = paramNameEnd(path, i) - 1
+		}
+	}
+	return string(shape)
+}
+
+// segmentTree is used by a Branch instead of its trie when at least one of its routes has an in-segment parameter (/report-:year.:format),
+// an optional segment (/docs/:version?/intro) or a match everything parameter which is not the last segment (/files/*path/edit),
+// or when a static segment and a parameter are at the same position (/users/new and /users/:id)
+type segmentTree struct {
+	root      *segmentNode
+	paramsLen uint8
+}
+
+// segmentNode is a node of the segmentTree, one per segment of the registed paths
+//
+// the children are checked by priority, static > in-segment parameters > parameter > match everything,
+// if the rest of the path is not matched by a child then the next child is checked
+type segmentNode struct {
+	static map[string]*segmentNode
+	// mixed are the segments with parameters and static text, the ones with the most static text are first
+	mixed []*mixedSegment
+	param *segmentNode
+	wild  *segmentNode
+	// leaves are the routes which end on this node, the ones with constraints are first
+	leaves []*constrainedMiddleware
+}
+
+// mixedSegment is a segment with parameters and static text, ex: report-:year.:format
+type mixedSegment struct {
+	shape string
+	// parts are the static texts and the parameters, a parameter is an empty string
+	parts     []string
+	staticLen int
+	node      *segmentNode
+}
+
+func newSegmentTree() *segmentTree {
+	return &segmentTree{root: &segmentNode{}}
+}
+
+// add adds a route
+func (t *segmentTree) add(path string, middleware Middleware, constraints ParamConstraints) {
+	for _, p := range expandOptional(path) {
+		n := t.root
+		for _, segment := range strings.Split(p[1:], Slash) {
+			n = n.child(segment)
+		}
+		n.addLeaf(newConstrainedMiddleware(p, middleware, constraints))
+	}
+	if numParams := GetParamsLen(path); numParams > t.paramsLen {
+		t.paramsLen = numParams
+	}
+}
+
+// child returns the child of the segment, it creates it if not exists
+func (n *segmentNode) child(segment string) *segmentNode {
+	switch {
+	case segment != "" && segment[0] == MatchEverythingByte:
+		if n.wild == nil {
+			n.wild = &segmentNode{}
+		}
+		return n.wild
+	case segment != "" && segment[0] == ParameterStartByte && pathShape(segment) == ":":
+		if n.param == nil {
+			n.param = &segmentNode{}
+		}
+		return n.param
+	case strings.IndexByte(segment, ParameterStartByte) != -1:
+		shape := pathShape(segment)
+		for _, m := range n.mixed {
+			if m.shape == shape {
+				return m.node
+			}
+		}
+		m := newMixedSegment(shape)
+		// keep the ones with the most static text first
+		i := len(n.mixed)
+		for i > 0 && n.mixed[i-1].staticLen < m.staticLen {
+			i--
+		}
+		n.mixed = append(n.mixed, nil)
+		copy(n.mixed[i+1:], n.mixed[i:])
+		n.mixed[i] = m
+		return m.node
+	default:
+		if n.static == nil {
+			n.static = make(map[string]*segmentNode)
+		}
+		child, ok := n.static[segment]
+		if !ok {
+			child = &segmentNode{}
+			n.static[segment] = child
+		}
+		return child
+	}
+}
+
+// newMixedSegment creates a mixedSegment from a shape, ex: report-:.:
+func newMixedSegment(shape string) *mixedSegment {
+	m := &mixedSegment{shape: shape, node: &segmentNode{}}
+	for _, static := range strings.SplitAfter(shape, ":") {
+		if static == "" {
+			continue
+		}
+		if strings.HasSuffix(static, ":") {
+			if static = static[:len(static)-1]; static != "" {
+				m.parts = append(m.parts, static)
+			}
+			m.parts = append(m.parts, "")
+		} else {
+			m.parts = append(m.parts, static)
+		}
+		m.staticLen += len(static)
+	}
+	return m
+}
+
+// addLeaf adds a route which ends on this node, the routes with constraints are checked first, by the order they registed
+func (n *segmentNode) addLeaf(c *constrainedMiddleware) {
+	last := len(n.leaves) - 1
+	if last == -1 || n.leaves[last].constraints != nil {
+		n.leaves = append(n.leaves, c)
+		return
+	}
+	if c.constraints == nil {
+		// we already have a route which accepts everything
+		return
+	}
+	n.leaves = append(n.leaves[:last], c, n.leaves[last])
+}
+
+// match appends the values of the segment's parameters to the params, returns false if the segment doesn't match
+func (m *mixedSegment) match(segment string, params PathParameters) (PathParameters, bool) {
+	for i, part := range m.parts {
+		if part != "" {
+			if !strings.HasPrefix(segment, part) {
+				return params, false
+			}
+			segment = segment[len(part):]
+			continue
+		}
+
+		// a parameter, until the next static text
+		end := len(segment)
+		if i+1 < len(m.parts) {
+			next := m.parts[i+1]
+			if i+2 == len(m.parts) {
+				// the static text is the suffix of the segment
+				if !strings.HasSuffix(segment, next) {
+					return params, false
+				}
+				end = len(segment) - len(next)
+			} else if end = strings.Index(segment, next); end == 0 {
+				// the parameter can't be empty, find the next one
+				if end = strings.Index(segment[1:], next); end != -1 {
+					end++
+				}
+			}
+		}
+		if end <= 0 {
+			return params, false
+		}
+		params = append(params, PathParameter{Value: segment[:end]})
+		segment = segment[end:]
+	}
+	return params, segment == ""
+}
+
+// lookup finds the route of the rest of the path, which starts with a slash, or it's empty if the path ends on this node
+func (n *segmentNode) lookup(path string, params PathParameters) (Middleware, PathParameters) {
+	if path == "" {
+		for _, c := range n.leaves {
+			if c.match(params) {
+				return c.middleware, params
+			}
+		}
+		return nil, params
+	}
+
+	end := strings.IndexByte(path[1:], SlashByte) + 1
+	if end == 0 {
+		end = len(path)
+	}
+	segment, rest := path[1:end], path[end:]
+	start := len(params)
+
+	if child := n.static[segment]; child != nil {
+		if middleware, p := child.lookup(rest, params); middleware != nil {
+			return middleware, p
+		}
+	}
+
+	for _, m := range n.mixed {
+		if p, ok := m.match(segment, params[:start]); ok {
+			if middleware, p := m.node.lookup(rest, p); middleware != nil {
+				return middleware, p
+			}
+		}
+	}
+
+	if n.param != nil && segment != "" {
+		if middleware, p := n.param.lookup(rest, append(params[:start], PathParameter{Value: segment})); middleware != nil {
+			return middleware, p
+		}
+	}
+
+	if n.wild != nil {
+		// the longest first, the value starts with the slash, ex: /css/main.css,
+		// as on the trie the value is the slash when the path ends on it, ex: /files/ of the /files/*path
+		for end := len(path); end > 1 || end == len(path); end = strings.LastIndexByte(path[:end], SlashByte) {
+			if middleware, p := n.wild.lookup(path[end:], append(params[:start], PathParameter{Value: path[:end]})); middleware != nil {
+				return middleware, p
+			}
+		}
+	}
+
+	return nil, params[:start]
+}
+
+// lookup finds the route of the path
+// mustRedirect is true if the route is not found but the path with or without the trailing slash has a route
+func (t *segmentTree) lookup(path string, params PathParameters) (middleware Middleware, _ PathParameters, mustRedirect bool) {
+	if cap(params) < int(t.paramsLen) {
+		params = make(PathParameters, 0, t.paramsLen)
+	}
+	start := len(params)
+	if middleware, params = t.root.lookup(path, params); middleware != nil {
+		return middleware, params, false
+	}
+
+	if len(path) > 1 {
+		if path[len(path)-1] == SlashByte {
+			path = path[:len(path)-1]
+		} else {
+			path += Slash
+		}
+		m, _ := t.root.lookup(path, params[:start])
+		mustRedirect = m != nil
+	}
+	return nil, params[:start], mustRedirect
+}
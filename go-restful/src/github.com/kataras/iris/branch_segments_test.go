@@ -0,0 +1,176 @@
+package iris
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBranchSegments(t *testing.T) {
+	routes := []string{
+		"/users/new",
+		"/users/:id(int)",
+		"/users/:name",
+		"/users/:id/files/*path/edit",
+		"/files/",
+		"/files/*path",
+		"/report-:year.:format",
+		"/report-:year",
+		"/docs/:version?/intro",
+		"/archive/iris-:version.tar.gz",
+		"/archive/iris-:version.:ext",
+	}
+
+	for _, cache := range []bool{true, false} {
+		options := defaultOptions()
+		options.Cache = cache
+		s := Custom(options)
+		for _, route := range routes {
+			route := route
+			s.Get(route, HandlerFunc(func(c *Context) {
+				c.Write("%s %s", route, c.Params.String())
+			}))
+		}
+		handler := s.Serve()
+
+		tests := []struct {
+			path string
+			body string
+		}{
+			// static > parameter
+			{"/users/new", "/users/new "},
+			{"/users/42", "/users/:id(int) id=42"},
+			{"/users/kataras", "/users/:name name=kataras"},
+			{"/users/42/files/a/b/edit", "/users/:id/files/*path/edit id=42,path=/a/b"},
+			{"/users/42/files/a/edit/edit", "/users/:id/files/*path/edit id=42,path=/a/edit"},
+			{"/files/", "/files/ "},
+			{"/files/css/main.css", "/files/*path path=/css/main.css"},
+			// in-segment parameters > parameter
+			{"/report-2016.pdf", "/report-:year.:format year=2016,format=pdf"},
+			{"/report-2016.tar.gz", "/report-:year.:format year=2016,format=tar.gz"},
+			{"/report-2016", "/report-:year year=2016"},
+			{"/docs/v1/intro", "/docs/:version?/intro version=v1"},
+			{"/docs/intro", "/docs/:version?/intro "},
+			// the one with the most static text first
+			{"/archive/iris-v6.tar.gz", "/archive/iris-:version.tar.gz version=v6"},
+			{"/archive/iris-v6.zip", "/archive/iris-:version.:ext version=v6,ext=zip"},
+		}
+
+		for i, tt := range tests {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if w.Code != http.StatusOK || w.Body.String() != tt.body {
+				t.Fatalf("[cache: %v, %d] %s expected %q but got %d %q", cache, i, tt.path, tt.body, w.Code, w.Body.String())
+			}
+		}
+
+		for _, path := range []string{"/report-", "/docs//intro", "/users/42/files/a/b/edit2", "/users/42/files/edit"} {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("[cache: %v] %s expected 404 but got %d %q", cache, path, w.Code, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestBranchSegmentsWholeSegmentNames(t *testing.T) {
+	// the name of a parameter which is the whole segment runs to the next slash, with or without the segment tree
+	for _, extended := range []bool{false, true} {
+		s := New()
+		s.Get("/users/:user-id", HandlerFunc(func(c *Context) { c.Write("%s", c.Params.String()) }))
+		s.Get("/f/:name.json", HandlerFunc(func(c *Context) { c.Write("%s", c.Params.String()) }))
+		s.Get("/files/:file-name(regex:[a-z.]+)/raw", HandlerFunc(func(c *Context) { c.Write("%s", c.Params.String()) }))
+		if extended {
+			s.Get("/report-:year.:format", HandlerFunc(testRoutesHandler))
+		}
+		handler := s.Serve()
+
+		tests := []struct {
+			path string
+			body string
+		}{
+			{"/users/42", "user-id=42"},
+			{"/f/x", "name.json=x"},
+			{"/files/main.go/raw", "file-name=main.go"},
+		}
+		for i, tt := range tests {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if w.Code != http.StatusOK || w.Body.String() != tt.body {
+				t.Fatalf("[extended: %v, %d] %s expected %q but got %d %q", extended, i, tt.path, tt.body, w.Code, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestBranchSegmentsWildcard(t *testing.T) {
+	// the same requests with the trie and with the segment tree, because of the /files/new
+	for _, sibling := range []bool{false, true} {
+		s := New()
+		s.Get("/files/*path", HandlerFunc(func(c *Context) { c.Write("%s", c.Params.String()) }))
+		if sibling {
+			s.Get("/files/new", HandlerFunc(func(c *Context) { c.Write("new") }))
+		}
+		handler := s.Serve()
+
+		tests := []struct {
+			path   string
+			status int
+			body   string
+		}{
+			{"/files/", http.StatusOK, "path=/"},
+			{"/files/css/main.css", http.StatusOK, "path=/css/main.css"},
+			{"/files/css/", http.StatusOK, "path=/css/"},
+			{"/files", http.StatusMovedPermanently, ""},
+		}
+		for i, tt := range tests {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if w.Code != tt.status || (tt.body != "" && w.Body.String() != tt.body) {
+				t.Fatalf("[sibling: %v, %d] %s expected %d %q but got %d %q", sibling, i, tt.path, tt.status, tt.body, w.Code, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestBranchSegmentsURL(t *testing.T) {
+	s := New()
+	s.Get("/docs/:version?/intro", HandlerFunc(testRoutesHandler)).Name("docs")
+	s.Get("/report-:year(int).:format", HandlerFunc(testRoutesHandler)).Name("report")
+	s.Get("/users/:id/files/*path/edit", HandlerFunc(testRoutesHandler)).Name("edit")
+
+	tests := []struct {
+		name   string
+		values []interface{}
+		url    string
+	}{
+		{"docs", []interface{}{"v1"}, "/docs/v1/intro"},
+		{"docs", []interface{}{""}, "/docs/intro"},
+		{"docs", []interface{}{nil}, "/docs/intro"},
+		{"report", []interface{}{2016, "pdf"}, "/report-2016.pdf"},
+		{"edit", []interface{}{42, "/a/b c"}, "/users/42/files/a/b%20c/edit"},
+	}
+	for i, tt := range tests {
+		if url, err := s.URL(tt.name, tt.values...); err != nil || url != tt.url {
+			t.Fatalf("[%d] Expected %s but got %s %v", i, tt.url, url, err)
+		}
+	}
+
+	if _, err := s.URL("report", "latest", "pdf"); err == nil {
+		t.Fatalf("Expected an error for the invalid value")
+	}
+}
+
+func TestBranchSegmentsSyntax(t *testing.T) {
+	for _, path := range []string{"/files/a*path", "/*a/*b", "/report-:year:format", "/docs/v:version?", "/users/:/posts"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s expected to panic", path)
+				}
+			}()
+			New().Get(path, HandlerFunc(testRoutesHandler))
+		}()
+	}
+}
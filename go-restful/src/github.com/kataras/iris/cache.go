@@ -30,28 +30,45 @@ import (
 	"sync"
 )
 
-// IContextCache is the interface of the ContextCache & SyncContextCache
+// contextCacheShards is the number of the shards of the ContextCache, each one has its own lock
+const contextCacheShards = 32
+
+// CachedRoute is the result of a route's lookup which the caches keep, the middleware and the parameters of the route
+// it's immutable, it's shared between the requests of the same url
+type CachedRoute struct {
+	Middleware Middleware
+	Params     PathParameters
+}
+
+// IContextCache is the interface of the router's caches, the ContextCache & BoundedContextCache
+// the implementations should be routine-thread-safe
 type IContextCache interface {
 	OnTick()
-	AddItem(method, url string, ctx *Context) // This is the faster method, just set&return just a *Context, I tried to return only params and middleware but it's add 10.000 nanoseconds, also +2k bytes of memory . So let's keep it as I did thee first time, I don't know what do do to make it's performance even better... It doesn't go much best, can't use channels because the performance will be get very low, locks are better for this purpose.
-	GetItem(method, url string) *Context
+	AddItem(method, url string, route *CachedRoute)
+	GetItem(method, url string) *CachedRoute
 	SetMaxItems(maxItems int)
 }
 
-// ContextCache creation done with just &ContextCache{}
-type ContextCache struct {
+// contextCacheShard is a part of the ContextCache, a url is always at the same shard
+type contextCacheShard struct {
+	mu sync.RWMutex
 	//1. map[string] ,key is HTTP Method(GET,POST...)
-	//2. map[string]*Context ,key is The Request URL Path
-	//the map in this case is the faster way, I tried with array of structs but it's 100 times slower on > 1 core because of async goroutes on addItem I sugges, so we keep the map
-	items    map[string]map[string]*Context
+	//2. map[string]*CachedRoute ,key is The Request URL Path
+	items map[string]map[string]*CachedRoute
+}
+
+// ContextCache is the routine-thread-safe cache which is cleaned on each tick of the CacheResetDuration
+// the urls are spread to shards, so the writes of the different urls don't wait each other
+// creation done with NewContextCache
+type ContextCache struct {
+	shards   [contextCacheShards]contextCacheShard
 	MaxItems int
 }
 
-// SyncContextCache is the cache version of routine-thread-safe ContextCache
+// SyncContextCache was the routine-thread-safe version of the ContextCache,
+// now the ContextCache is routine-thread-safe itself, it's kept for compatibility
 type SyncContextCache struct {
 	*ContextCache
-	//we need this mutex if we have running the iris at > 1 core, because we use map but maybe at the future I will change it.
-	mu *sync.RWMutex
 }
 
 var _ IContextCache = &ContextCache{}
@@ -64,66 +81,67 @@ func (mc *ContextCache) SetMaxItems(_itemslen int) {
 
 // NewContextCache returns the cache for a router, is used on the MemoryRouter
 func NewContextCache() *ContextCache {
-	mc := &ContextCache{items: make(map[string]map[string]*Context, 0)}
+	mc := &ContextCache{}
 	mc.resetBag()
 	return mc
 }
 
-// NewSyncContextCache returns the cache for a router, it's based on the one-thread ContextCache
+// NewSyncContextCache returns the underline ContextCache, it's kept for compatibility
 func NewSyncContextCache(underlineCache *ContextCache) *SyncContextCache {
-	mc := &SyncContextCache{ContextCache: underlineCache, mu: new(sync.RWMutex)}
-	mc.resetBag()
-	return mc
+	return &SyncContextCache{ContextCache: underlineCache}
 }
 
-// AddItem adds an item to the bag/cache, is a goroutine.
-func (mc *ContextCache) AddItem(method, url string, ctx *Context) {
-	mc.items[method][url] = ctx
+// shard returns the shard of the url, it's the FNV-1a of the url, without allocations
+func (mc *ContextCache) shard(url string) *contextCacheShard {
+	h := uint32(2166136261)
+	for i := 0; i < len(url); i++ {
+		h ^= uint32(url[i])
+		h *= 16777619
+	}
+	return &mc.shards[h%contextCacheShards]
 }
 
-// AddItem adds an item to the bag/cache, is a goroutine.
-func (mc *SyncContextCache) AddItem(method, url string, ctx *Context) {
-	go func(method, url string, c *Context) { //for safety on multiple fast calls
-		mc.mu.Lock()
-		mc.items[method][url] = ctx
-		mc.mu.Unlock()
-	}(method, url, ctx)
+// AddItem adds an item to the bag/cache
+func (mc *ContextCache) AddItem(method, url string, route *CachedRoute) {
+	shard := mc.shard(url)
+	shard.mu.Lock()
+	urls := shard.items[method]
+	if urls == nil {
+		urls = make(map[string]*CachedRoute)
+		shard.items[method] = urls
+	}
+	urls[url] = route
+	shard.mu.Unlock()
 }
 
 // GetItem returns an item from the bag/cache, if not exists it returns just nil.
-func (mc *ContextCache) GetItem(method, url string) *Context {
-	if ctx := mc.items[method][url]; ctx != nil {
-		return ctx
-	}
-
-	return nil
+func (mc *ContextCache) GetItem(method, url string) *CachedRoute {
+	shard := mc.shard(url)
+	shard.mu.RLock()
+	route := shard.items[method][url]
+	shard.mu.RUnlock()
+	return route
 }
 
-// GetItem returns an item from the bag/cache, if not exists it returns just nil.
-func (mc *SyncContextCache) GetItem(method, url string) *Context {
-	mc.mu.RLock()
-	if ctx := mc.items[method][url]; ctx != nil {
-		mc.mu.RUnlock()
-		return ctx
+// Len returns the number of the cached items
+func (mc *ContextCache) Len() int {
+	n := 0
+	for i := range mc.shards {
+		shard := &mc.shards[i]
+		shard.mu.RLock()
+		for _, urls := range shard.items {
+			n += len(urls)
+		}
+		shard.mu.RUnlock()
 	}
-	mc.mu.RUnlock()
-	return nil
+	return n
 }
 
-// DoOnTick raised every time the ticker ticks, can be called independed, it's just check for items len and resets the cache
+// DoOnTick raised every time the ticker ticks, can be called independed,
+// it resets the cache if MaxItems is 0 or if the cached items are at least MaxItems
 func (mc *ContextCache) DoOnTick() {
-
-	if mc.MaxItems == 0 {
-		//just reset to complete new maps all methods
+	if mc.MaxItems == 0 || mc.Len() >= mc.MaxItems {
 		mc.resetBag()
-	} else {
-		//loop each method on bag and clear it if it's len is more than MaxItems
-		for k, v := range mc.items {
-			if len(v) >= mc.MaxItems {
-				//we just create a new map, no delete each manualy because this number maybe be very long.
-				mc.items[k] = make(map[string]*Context, 0)
-			}
-		}
 	}
 }
 
@@ -133,17 +151,12 @@ func (mc *ContextCache) OnTick() {
 	mc.DoOnTick()
 }
 
-// OnTick is the implementation of the ITick
-// it makes the ContextCache a ticker's listener
-func (mc *SyncContextCache) OnTick() {
-	mc.mu.Lock()
-	mc.ContextCache.DoOnTick()
-	mc.mu.Unlock()
-}
-
 // resetBag clears the cached items
 func (mc *ContextCache) resetBag() {
-	for _, m := range HTTPMethods.ANY {
-		mc.items[m] = make(map[string]*Context, 0)
+	for i := range mc.shards {
+		shard := &mc.shards[i]
+		shard.mu.Lock()
+		shard.items = make(map[string]map[string]*CachedRoute, len(HTTPMethods.ANY))
+		shard.mu.Unlock()
 	}
 }
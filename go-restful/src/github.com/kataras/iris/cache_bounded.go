@@ -0,0 +1,341 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package iris
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultCacheMaxItems is the maximum number of the cached routes of the BoundedContextCache when the CacheMaxItems is <= 0
+const DefaultCacheMaxItems = 500
+
+// CachePolicy is the type of the router's cache, see StationOptions.CachePolicy
+type CachePolicy int
+
+const (
+	// CacheTick is the ContextCache, the cached routes are cleaned on each tick of the CacheResetDuration
+	CacheTick CachePolicy = iota
+	// CacheLRU is the BoundedContextCache, it never has more than CacheMaxItems routes, the least recently used route is evicted
+	CacheLRU
+	// CacheTinyLFU is the BoundedContextCache with the TinyLFU admission,
+	// a new route evicts the least recently used route only if it's requested more frequently than it
+	CacheTinyLFU
+)
+
+// ContextCacheStats are the counters of a cache
+type ContextCacheStats struct {
+	// Items is the number of the cached routes
+	Items int
+	// Hits is the number of the lookups which found a cached route
+	Hits uint64
+	// Misses is the number of the lookups which didn't find a cached route
+	Misses uint64
+	// Evictions is the number of the cached routes which are removed in order to add others
+	Evictions uint64
+	// Rejections is the number of the routes which are not cached because they are less frequent than the cached ones (TinyLFU)
+	Rejections uint64
+}
+
+// IContextCacheStats is implemented by the caches which keep counters, the BoundedContextCache
+type IContextCacheStats interface {
+	Stats() ContextCacheStats
+}
+
+const (
+	// boundedShardItems is the minimum number of the routes of a shard of the BoundedContextCache
+	boundedShardItems = 64
+	// boundedMaxShards is the maximum number of the shards of the BoundedContextCache
+	boundedMaxShards = 16
+)
+
+// boundedItem is an item of the BoundedContextCache
+type boundedItem struct {
+	key   string
+	route *CachedRoute
+}
+
+// BoundedContextCache is the cache which never has more than MaxItems routes,
+// it evicts the least recently used route (LRU) and, optionally, it admits a new route only if it's more frequent than the evicted one (TinyLFU)
+// it's routine-thread-safe
+//
+// the routes are split to shards by the hash of the url, each shard has its own lock, order and frequency sketch,
+// so the lookups of different urls don't wait each other. A shard has at least 64 routes, a small cache has one shard
+type BoundedContextCache struct {
+	shards atomic.Value // []*boundedShard, their number is a power of two
+	mu     sync.Mutex   // serializes the SetMaxItems
+	tiny   bool
+	// stats are the counters of the shards which are replaced by the SetMaxItems
+	stats ContextCacheStats
+}
+
+// boundedShard is a part of the BoundedContextCache, it's the LRU of its routes
+type boundedShard struct {
+	mu       sync.Mutex
+	maxItems int
+	items    map[string]*list.Element
+	order    *list.List // the front is the most recently used
+	sketch   *frequencySketch
+	stats    ContextCacheStats
+}
+
+var _ IContextCache = &BoundedContextCache{}
+var _ IContextCacheStats = &BoundedContextCache{}
+
+// NewLRUContextCache returns a cache with the LRU eviction, if maxItems <= 0 then the DefaultCacheMaxItems is used
+func NewLRUContextCache(maxItems int) *BoundedContextCache {
+	mc := &BoundedContextCache{}
+	mc.SetMaxItems(maxItems)
+	return mc
+}
+
+// NewTinyLFUContextCache returns a cache with the LRU eviction and the TinyLFU admission, if maxItems <= 0 then the DefaultCacheMaxItems is used
+func NewTinyLFUContextCache(maxItems int) *BoundedContextCache {
+	mc := &BoundedContextCache{tiny: true}
+	mc.SetMaxItems(maxItems)
+	return mc
+}
+
+// SetMaxItems receives int and set max cached items to this number, the extra items are evicted
+// the routes are moved to new shards, the most recently used are kept (the order between the shards is approximate)
+func (mc *BoundedContextCache) SetMaxItems(maxItems int) {
+	if maxItems <= 0 {
+		maxItems = DefaultCacheMaxItems
+	}
+	n := 1
+	for n < boundedMaxShards && n*2*boundedShardItems <= maxItems {
+		n *= 2
+	}
+	shards := make([]*boundedShard, n)
+	for i := range shards {
+		// the rest routes are given to the first shards, so the sum is the maxItems
+		shardItems := maxItems / n
+		if i < maxItems%n {
+			shardItems++
+		}
+		shards[i] = &boundedShard{maxItems: shardItems, items: make(map[string]*list.Element), order: list.New()}
+		if mc.tiny {
+			shards[i].sketch = &frequencySketch{}
+			shards[i].sketch.reset(shardItems)
+		}
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	old, _ := mc.shards.Load().([]*boundedShard)
+	// the items of the same rank of each shard are used at about the same time, the most recently used are first
+	var items []*boundedItem
+	fronts := make([]*list.Element, len(old))
+	for i, shard := range old {
+		shard.mu.Lock()
+		defer shard.mu.Unlock()
+		fronts[i] = shard.order.Front()
+		mc.stats.Hits += shard.stats.Hits
+		mc.stats.Misses += shard.stats.Misses
+		mc.stats.Evictions += shard.stats.Evictions
+		mc.stats.Rejections += shard.stats.Rejections
+	}
+	for more := true; more; {
+		more = false
+		for i, el := range fronts {
+			if el != nil {
+				items = append(items, el.Value.(*boundedItem))
+				fronts[i] = el.Next()
+				more = true
+			}
+		}
+	}
+	// from the least to the most recently used, so they have the same order in the new shards
+	for i := len(items) - 1; i >= 0; i-- {
+		shards[hashKey(items[i].key)>>48&uint64(n-1)].push(items[i])
+	}
+	mc.shards.Store(shards)
+}
+
+// shard returns the shard of the key's hash
+func (mc *BoundedContextCache) shard(h uint64) *boundedShard {
+	shards := mc.shards.Load().([]*boundedShard)
+	return shards[h>>48&uint64(len(shards)-1)]
+}
+
+// AddItem adds an item to the cache, if the cache is full the least recently used item is evicted
+func (mc *BoundedContextCache) AddItem(method, url string, route *CachedRoute) {
+	key := method + url
+	h := hashKey(key)
+	mc.shard(h).add(key, h, route)
+}
+
+// GetItem returns an item from the cache, if not exists it returns just nil.
+func (mc *BoundedContextCache) GetItem(method, url string) *CachedRoute {
+	key := method + url
+	h := hashKey(key)
+	return mc.shard(h).get(key, h)
+}
+
+// OnTick does nothing, the BoundedContextCache is bounded without the ticker
+func (mc *BoundedContextCache) OnTick() {}
+
+// Stats returns the counters of the cache, the sum of its shards
+func (mc *BoundedContextCache) Stats() ContextCacheStats {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	stats := mc.stats
+	for _, shard := range mc.shards.Load().([]*boundedShard) {
+		shard.mu.Lock()
+		stats.Items += shard.order.Len()
+		stats.Hits += shard.stats.Hits
+		stats.Misses += shard.stats.Misses
+		stats.Evictions += shard.stats.Evictions
+		stats.Rejections += shard.stats.Rejections
+		shard.mu.Unlock()
+	}
+	return stats
+}
+
+func (s *boundedShard) add(key string, h uint64, route *CachedRoute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, found := s.items[key]; found {
+		el.Value.(*boundedItem).route = route
+		s.order.MoveToFront(el)
+		return
+	}
+
+	if s.order.Len() >= s.maxItems {
+		if s.sketch != nil {
+			victim := s.order.Back().Value.(*boundedItem)
+			if s.sketch.estimate(h) <= s.sketch.estimate(hashKey(victim.key)) {
+				s.stats.Rejections++
+				return
+			}
+		}
+		s.evict()
+	}
+	s.items[key] = s.order.PushFront(&boundedItem{key: key, route: route})
+}
+
+func (s *boundedShard) get(key string, h uint64) *CachedRoute {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sketch != nil {
+		s.sketch.increment(h)
+	}
+	if el, found := s.items[key]; found {
+		s.stats.Hits++
+		s.order.MoveToFront(el)
+		return el.Value.(*boundedItem).route
+	}
+	s.stats.Misses++
+	return nil
+}
+
+// push adds an item of the previous shards as the most recently used, without the admission, the shard is not shared yet
+func (s *boundedShard) push(item *boundedItem) {
+	if s.order.Len() >= s.maxItems {
+		s.evict()
+	}
+	s.items[item.key] = s.order.PushFront(item)
+}
+
+// evict removes the least recently used item, s.mu should be locked
+func (s *boundedShard) evict() {
+	el := s.order.Back()
+	if el == nil {
+		return
+	}
+	s.order.Remove(el)
+	delete(s.items, el.Value.(*boundedItem).key)
+	s.stats.Evictions++
+}
+
+// hashKey is the FNV-1a of the key, without allocations
+func hashKey(key string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// frequencySketch is a count-min sketch with 4 rows of 4-bit counters, it estimates how many times a key is requested
+// the counters are halved every 10*maxItems increments, so the old popular keys are forgotten
+type frequencySketch struct {
+	counters   []uint64 // each uint64 keeps 16 counters of 4 bits
+	mask       uint64
+	additions  int
+	sampleSize int
+}
+
+// reset creates the counters for the maxItems
+func (s *frequencySketch) reset(maxItems int) {
+	width := 16
+	for width < maxItems {
+		width <<= 1
+	}
+	s.counters = make([]uint64, width)
+	s.mask = uint64(width - 1)
+	s.additions = 0
+	s.sampleSize = 10 * maxItems
+}
+
+// position returns the index of the uint64 and the shift of the counter of the row i
+func (s *frequencySketch) position(h uint64, i int) (int, uint) {
+	h += uint64(i) * (h>>32 | 1)
+	return int((h >> 8) & s.mask), uint((h&3)<<2 + uint64(i)*16)
+}
+
+// increment increments the counters of the key's hash, see hashKey
+func (s *frequencySketch) increment(h uint64) {
+	for i := 0; i < 4; i++ {
+		idx, shift := s.position(h, i)
+		if (s.counters[idx]>>shift)&0xf < 15 {
+			s.counters[idx] += 1 << shift
+		}
+	}
+	if s.additions++; s.additions >= s.sampleSize {
+		for i := range s.counters {
+			s.counters[i] = (s.counters[i] >> 1) & 0x7777777777777777
+		}
+		s.additions /= 2
+	}
+}
+
+// estimate returns the minimum counter of the key's hash, see hashKey
+func (s *frequencySketch) estimate(h uint64) uint64 {
+	min := uint64(15)
+	for i := 0; i < 4; i++ {
+		idx, shift := s.position(h, i)
+		if count := (s.counters[idx] >> shift) & 0xf; count < min {
+			min = count
+		}
+	}
+	return min
+}
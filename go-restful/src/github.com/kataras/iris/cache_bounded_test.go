@@ -0,0 +1,131 @@
+package iris
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestLRUContextCache(t *testing.T) {
+	mc := NewLRUContextCache(2)
+	a, b, c := &CachedRoute{}, &CachedRoute{}, &CachedRoute{}
+	mc.AddItem("GET", "/a", a)
+	mc.AddItem("GET", "/b", b)
+	if mc.GetItem("GET", "/a") != a {
+		t.Fatal("Expecting the cached /a")
+	}
+	// /b is the least recently used now
+	mc.AddItem("GET", "/c", c)
+	if mc.GetItem("GET", "/b") != nil {
+		t.Fatal("Expecting /b to be evicted")
+	}
+	if mc.GetItem("GET", "/a") != a || mc.GetItem("GET", "/c") != c {
+		t.Fatal("Expecting /a and /c to be cached")
+	}
+	if mc.GetItem("POST", "/a") != nil {
+		t.Fatal("Expecting the method to be part of the key")
+	}
+
+	stats := mc.Stats()
+	if stats.Items != 2 || stats.Hits != 3 || stats.Misses != 2 || stats.Evictions != 1 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+
+	mc.SetMaxItems(1)
+	if stats = mc.Stats(); stats.Items != 1 || mc.GetItem("GET", "/c") != c {
+		t.Fatalf("Expecting only the most recently used item after SetMaxItems(1), stats: %+v", stats)
+	}
+}
+
+func TestLRUContextCacheShards(t *testing.T) {
+	mc := NewLRUContextCache(1000)
+	shards := mc.shards.Load().([]*boundedShard)
+	sum := 0
+	for _, shard := range shards {
+		sum += shard.maxItems
+	}
+	if len(shards) != 8 || sum != 1000 {
+		t.Fatalf("Expecting 8 shards of 1000 routes but got %d shards of %d routes", len(shards), sum)
+	}
+
+	for i := 0; i < 2000; i++ {
+		mc.AddItem("GET", "/"+strconv.Itoa(i), &CachedRoute{})
+	}
+	if stats := mc.Stats(); stats.Items > 1000 || stats.Items+int(stats.Evictions) != 2000 {
+		t.Fatalf("Expecting at most 1000 routes, stats: %+v", stats)
+	}
+	if mc.GetItem("GET", "/1999") == nil {
+		t.Fatal("Expecting the most recently added route")
+	}
+
+	// the routes are moved to the new shards
+	mc.SetMaxItems(4000)
+	if shards = mc.shards.Load().([]*boundedShard); len(shards) != 16 || mc.GetItem("GET", "/1999") == nil {
+		t.Fatal("Expecting the routes to be moved to 16 shards")
+	}
+	// a small cache has one shard
+	mc.SetMaxItems(2)
+	if shards = mc.shards.Load().([]*boundedShard); len(shards) != 1 {
+		t.Fatalf("Expecting one shard but got %d", len(shards))
+	}
+	if stats := mc.Stats(); stats.Items != 2 || stats.Hits != 2 {
+		t.Fatalf("Expecting the counters of the previous shards, stats: %+v", stats)
+	}
+}
+
+func TestTinyLFUContextCache(t *testing.T) {
+	mc := NewTinyLFUContextCache(2)
+	for i := 0; i < 5; i++ {
+		mc.GetItem("GET", "/popular")
+	}
+	mc.AddItem("GET", "/popular", &CachedRoute{})
+	mc.GetItem("GET", "/other")
+	mc.AddItem("GET", "/other", &CachedRoute{})
+
+	// a one-time url should not evict the popular one
+	for i := 0; i < 100; i++ {
+		mc.GetItem("GET", "/popular")
+		url := "/users/" + strconv.Itoa(i)
+		if mc.GetItem("GET", url) == nil {
+			mc.AddItem("GET", url, &CachedRoute{})
+		}
+	}
+	if mc.GetItem("GET", "/popular") == nil {
+		t.Fatal("Expecting the popular url to stay in the cache")
+	}
+	if stats := mc.Stats(); stats.Items != 2 || stats.Rejections == 0 {
+		t.Fatalf("Expecting the one-time urls to be rejected, stats: %+v", stats)
+	}
+
+	// a url which becomes popular is admitted
+	for i := 0; i < 10; i++ {
+		if mc.GetItem("GET", "/trending") == nil {
+			mc.AddItem("GET", "/trending", &CachedRoute{})
+		}
+	}
+	if mc.GetItem("GET", "/trending") == nil {
+		t.Fatal("Expecting the frequent url to be admitted")
+	}
+}
+
+func TestStationCachePolicy(t *testing.T) {
+	options := defaultOptions()
+	options.CachePolicy = CacheLRU
+	options.CacheMaxItems = 2
+	s := Custom(options)
+	s.Get("/users/:id", HandlerFunc(func(c *Context) { c.Write("%s", c.Param("id")) }))
+	handler := s.Serve()
+
+	for _, id := range []string{"1", "2", "3", "3", "1"} {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest("GET", "/users/"+id, nil))
+		if res.Body.String() != id {
+			t.Fatalf("Expecting %s but got %q", id, res.Body.String())
+		}
+	}
+
+	stats := s.CacheStats()
+	if stats.Items != 2 || stats.Hits != 1 || stats.Misses != 4 || stats.Evictions != 2 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,158 @@
+package iris
+
+import (
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// stressContextCache adds, gets and cleans the cache from many goroutines, run it with -race
+func stressContextCache(t *testing.T, cache IContextCache) {
+	const workers, urls = 16, 200
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				url := "/users/" + strconv.Itoa((i*7+w)%urls)
+				if route := cache.GetItem("GET", url); route != nil {
+					if route.Params.Get("id") != url[len("/users/"):] {
+						t.Errorf("Expecting the route of %s but got the params %s", url, route.Params)
+						return
+					}
+					continue
+				}
+				cache.AddItem("GET", url, &CachedRoute{Params: PathParameters{{Key: "id", Value: url[len("/users/"):]}}})
+				if i%250 == 0 {
+					cache.OnTick()
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+func TestContextCacheStress(t *testing.T) {
+	caches := map[string]IContextCache{
+		"ContextCache":     NewContextCache(),
+		"SyncContextCache": NewSyncContextCache(NewContextCache()),
+		"LRU":              NewLRUContextCache(50),
+		"TinyLFU":          NewTinyLFUContextCache(50),
+	}
+	for name, cache := range caches {
+		t.Run(name, func(t *testing.T) {
+			cache.SetMaxItems(100)
+			stressContextCache(t, cache)
+		})
+	}
+}
+
+func TestContextCacheMaxItems(t *testing.T) {
+	cache := NewContextCache()
+	cache.SetMaxItems(3)
+	for i := 0; i < 2; i++ {
+		cache.AddItem("GET", "/"+strconv.Itoa(i), &CachedRoute{})
+	}
+	cache.OnTick()
+	if cache.Len() != 2 {
+		t.Fatalf("Expecting the cache to keep its items under the MaxItems but has %d", cache.Len())
+	}
+	cache.AddItem("POST", "/0", &CachedRoute{})
+	cache.OnTick()
+	if cache.Len() != 0 {
+		t.Fatalf("Expecting the cache to be cleaned when it reaches the MaxItems but has %d", cache.Len())
+	}
+}
+
+// TestMemoryRouterStress serves the same urls from many goroutines, each response should have the parameters of its own request
+func TestMemoryRouterStress(t *testing.T) {
+	for _, policy := range []CachePolicy{CacheTick, CacheLRU, CacheTinyLFU} {
+		options := defaultOptions()
+		options.CachePolicy = policy
+		options.CacheMaxItems = 20
+		s := Custom(options)
+		s.Get("/users/:id/posts/:post", HandlerFunc(func(c *Context) {
+			runtime.Gosched()
+			c.Write("%s-%s", c.Param("id"), c.Param("post"))
+		}))
+		handler := s.Serve()
+
+		var wg sync.WaitGroup
+		for w := 0; w < 16; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 300; i++ {
+					id, post := strconv.Itoa((i+w)%40), strconv.Itoa(i%3)
+					res := httptest.NewRecorder()
+					handler.ServeHTTP(res, httptest.NewRequest("GET", "/users/"+id+"/posts/"+post, nil))
+					if expected := id + "-" + post; res.Body.String() != expected {
+						t.Errorf("Expecting %s but got %q", expected, res.Body.String())
+						return
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+	}
+}
+
+func TestMemoryRouterCachedParams(t *testing.T) {
+	s := New()
+	s.Get("/about", HandlerFunc(func(c *Context) {}))
+	s.Get("/users/:id", HandlerFunc(func(c *Context) { c.Write("%s", c.Param("id")) }))
+	handler := s.Serve()
+	for _, path := range []string{"/about", "/users/42", "/users/42"} {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest("GET", path, nil))
+	}
+
+	cache := s.IRouter.(IMemoryRouter).getCache()
+	if route := cache.GetItem("GET", "/about"); route == nil || route.Params != nil {
+		t.Fatalf("Expecting the cached route of /about without parameters but got %#v", route)
+	}
+	if route := cache.GetItem("GET", "/users/42"); route == nil || route.Params.Get("id") != "42" {
+		t.Fatalf("Expecting the cached route of /users/42 with its parameters but got %#v", route)
+	}
+}
+
+func TestMemoryRouterCachedRequestsIsolated(t *testing.T) {
+	s := New()
+	s.Get("/x", HandlerFunc(func(c *Context) {
+		if c.URLParam("login") == "1" {
+			c.Set("user", "admin")
+		}
+		c.Write("%v", c.Get("user"))
+	}))
+	s.Get("/users/:id", HandlerFunc(func(c *Context) {
+		c.Write("%s", c.Param("id"))
+		if c.URLParam("change") == "1" {
+			c.Params[0].Value = "changed"
+		}
+	}))
+	handler := s.Serve()
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/x", "<nil>"},
+		{"/x?login=1", "admin"},
+		// the value of the previous request is not kept
+		{"/x", "<nil>"},
+		{"/users/42", "42"},
+		// the handler of a cached request changes only its own parameters
+		{"/users/42?change=1", "42"},
+		{"/users/42", "42"},
+	}
+	for i, tt := range tests {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest("GET", tt.path, nil))
+		if res.Body.String() != tt.body {
+			t.Fatalf("[%d] %s expected %q but got %q", i, tt.path, tt.body, res.Body.String())
+		}
+	}
+}
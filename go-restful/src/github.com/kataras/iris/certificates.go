@@ -0,0 +1,197 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package iris
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCertificateCheckInterval is the default interval which the CertificateManager checks the certificate files for changes
+const DefaultCertificateCheckInterval = 10 * time.Second
+
+// ICertificateProvider is the interface which the certificate providers of the ListenTLSProvider should implement
+// the *CertificateManager and the *ACMEManager are implementing it
+type ICertificateProvider interface {
+	// GetCertificate returns the certificate for the tls handshake, see tls.Config.GetCertificate
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+}
+
+// IDomainsReceiver is implemented by the certificate providers which want to know the domains of the registed routes
+// see Route.GetDomain()
+type IDomainsReceiver interface {
+	// SetDomains it's being called only one time, before the server is started
+	// if it returns an error then the server is not started
+	SetDomains(domains []string) error
+}
+
+// certificatePair is a certificate and key files pair which is reloaded when the files are changed
+type certificatePair struct {
+	certFile, keyFile string
+	mu                sync.Mutex
+	cert              *tls.Certificate
+	modTime           time.Time
+	lastCheck         time.Time
+}
+
+// load loads the certificate and key files
+func (p *certificatePair) load() error {
+	cert, err := tls.LoadX509KeyPair(p.certFile, p.keyFile)
+	if err != nil {
+		return err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return err
+		}
+	}
+	p.cert = &cert
+	p.modTime = p.lastModified()
+	return nil
+}
+
+// lastModified returns the latest modification time of the certificate and key files
+func (p *certificatePair) lastModified() (modTime time.Time) {
+	for _, file := range []string{p.certFile, p.keyFile} {
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return
+}
+
+// get returns the certificate, it reloads the files first if they are changed, at most once per interval
+// if the reload fails (ex: the files are being written right now) the previous certificate is returned
+func (p *certificatePair) get(interval time.Duration) *tls.Certificate {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if now := time.Now(); now.Sub(p.lastCheck) >= interval {
+		p.lastCheck = now
+		if !p.lastModified().Equal(p.modTime) {
+			p.load()
+		}
+	}
+	return p.cert
+}
+
+// CertificateManager is an ICertificateProvider which keeps one or more certificate and key file pairs
+// it selects the certificate by the server name of the client (SNI) and reloads the files when they are changed,
+// so a certificate can be rotated without restarting the server
+//
+// Create it with NewCertificateManager, it's used by the ListenTLS also
+type CertificateManager struct {
+	// CheckInterval is the minimum interval which the files are checked for changes
+	// Default is 10 * time.Second
+	CheckInterval time.Duration
+
+	mu     sync.RWMutex
+	pairs  []*certificatePair
+	byName map[string]*certificatePair
+}
+
+var _ ICertificateProvider = &CertificateManager{}
+var _ IDomainsReceiver = &CertificateManager{}
+
+// NewCertificateManager creates and returns a new, empty, CertificateManager
+func NewCertificateManager() *CertificateManager {
+	return &CertificateManager{CheckInterval: DefaultCertificateCheckInterval, byName: make(map[string]*certificatePair)}
+}
+
+// AddPair loads a certificate and key file pair and adds it to the manager
+// the domains are the server names which this certificate is used for, ex: admin.ideopod.com or *.ideopod.com
+// if no domains given then the names of the certificate are used
+//
+// the first pair added is the default, it is used when the client's server name doesn't match with any domain
+func (m *CertificateManager) AddPair(certFile, keyFile string, domains ...string) error {
+	p := &certificatePair{certFile: certFile, keyFile: keyFile}
+	if err := p.load(); err != nil {
+		return err
+	}
+	p.lastCheck = time.Now()
+
+	if len(domains) == 0 {
+		domains = p.cert.Leaf.DNSNames
+		if cn := p.cert.Leaf.Subject.CommonName; cn != "" {
+			domains = append(domains, cn)
+		}
+	}
+
+	m.mu.Lock()
+	m.pairs = append(m.pairs, p)
+	for _, domain := range domains {
+		m.byName[strings.ToLower(domain)] = p
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// SetDomains checks that each domain of the registed routes has a certificate, it's called by the ListenTLSProvider
+// the default certificate doesn't count, a domain should match with the domains of a pair, ex: *.ideopod.com for admin.ideopod.com
+func (m *CertificateManager) SetDomains(domains []string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, domain := range domains {
+		if m.match(strings.ToLower(domain)) == nil {
+			return errors.New("[Iris] CertificateManager: no certificate for the domain " + domain)
+		}
+	}
+	return nil
+}
+
+// match returns the pair of the server name, or the pair of its wildcard, m.mu should be locked
+func (m *CertificateManager) match(name string) *certificatePair {
+	if p, found := m.byName[name]; found {
+		return p
+	}
+	// try the wildcard, ex: *.ideopod.com for admin.ideopod.com
+	if idx := strings.IndexByte(name, '.'); idx != -1 {
+		return m.byName["*"+name[idx:]]
+	}
+	return nil
+}
+
+// GetCertificate returns the certificate for the client's server name, see tls.Config.GetCertificate
+func (m *CertificateManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+
+	m.mu.RLock()
+	p := m.match(name)
+	if p == nil && len(m.pairs) > 0 {
+		p = m.pairs[0]
+	}
+	m.mu.RUnlock()
+
+	if p == nil {
+		return nil, errors.New("[Iris] CertificateManager: no certificates")
+	}
+	return p.get(m.CheckInterval), nil
+}
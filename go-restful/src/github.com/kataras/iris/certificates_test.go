@@ -0,0 +1,178 @@
+package iris
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate creates a self-signed certificate for the names and writes it to the dir
+func writeTestCertificate(t *testing.T, dir string, serial int64, names ...string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: names[0]},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     names,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, names[0]+".crt"), filepath.Join(dir, names[0]+".key")
+	if err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func testCertificateSerial(t *testing.T, m *CertificateManager, serverName string) int64 {
+	cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.Leaf.SerialNumber.Int64()
+}
+
+func TestCertificateManagerSNI(t *testing.T) {
+	dir := t.TempDir()
+	m := NewCertificateManager()
+	if err := m.AddPair(writeTestCertificate(t, dir, 1, "ideopod.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddPair(writeTestCertificate(t, dir, 2, "*.kataras.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		serverName string
+		serial     int64
+	}{
+		{"ideopod.com", 1},
+		{"IDEOPOD.com.", 1},
+		{"admin.kataras.com", 2},
+		{"kataras.com", 1}, // the wildcard doesn't match, the default (first) is used
+		{"", 1},
+	}
+	for _, tt := range tests {
+		if serial := testCertificateSerial(t, m, tt.serverName); serial != tt.serial {
+			t.Fatalf("Expecting the certificate %d for %q but got %d", tt.serial, tt.serverName, serial)
+		}
+	}
+
+	if _, err := NewCertificateManager().GetCertificate(&tls.ClientHelloInfo{}); err == nil {
+		t.Fatal("Expecting an error from an empty CertificateManager")
+	}
+}
+
+func TestCertificateManagerReload(t *testing.T) {
+	dir := t.TempDir()
+	m := NewCertificateManager()
+	m.CheckInterval = 0
+	certFile, keyFile := writeTestCertificate(t, dir, 1, "ideopod.com")
+	if err := m.AddPair(certFile, keyFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// a broken file keeps the previous certificate
+	os.WriteFile(certFile, []byte("broken"), 0600)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	if serial := testCertificateSerial(t, m, "ideopod.com"); serial != 1 {
+		t.Fatalf("Expecting the previous certificate while the files are broken but got %d", serial)
+	}
+
+	writeTestCertificate(t, dir, 2, "ideopod.com")
+	later = later.Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+	if serial := testCertificateSerial(t, m, "ideopod.com"); serial != 2 {
+		t.Fatalf("Expecting the certificate to be reloaded but got %d", serial)
+	}
+}
+
+func TestStationListenTLSProvider(t *testing.T) {
+	dir := t.TempDir()
+	m := NewCertificateManager()
+	if err := m.AddPair(writeTestCertificate(t, dir, 1, "ideopod.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddPair(writeTestCertificate(t, dir, 2, "kataras.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New()
+	s.Get("/", HandlerFunc(func(c *Context) { c.Write("secure") }))
+	result := listenTestWith(t, s, func() error { return s.ListenTLSProvider("127.0.0.1:0", m) })
+	defer func() {
+		s.Close()
+		<-result
+	}()
+
+	conn, err := tls.Dial("tcp", s.Server.listener.Addr().String(), &tls.Config{ServerName: "kataras.com", InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if serial := conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(); serial != 2 {
+		t.Fatalf("Expecting the certificate of kataras.com but got %d", serial)
+	}
+}
+
+func TestCertificateManagerSetDomains(t *testing.T) {
+	dir := t.TempDir()
+	m := NewCertificateManager()
+	if err := m.AddPair(writeTestCertificate(t, dir, 1, "ideopod.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddPair(writeTestCertificate(t, dir, 2, "*.kataras.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		domains []string
+		valid   bool
+	}{
+		{nil, true},
+		{[]string{"ideopod.com", "admin.kataras.com"}, true},
+		{[]string{"IDEOPOD.com"}, true},
+		// the default certificate doesn't count
+		{[]string{"ideopod.com", "kataras.com"}, false},
+		{[]string{"mydomain.com"}, false},
+	}
+	for i, tt := range tests {
+		if err := m.SetDomains(tt.domains); (err == nil) != tt.valid {
+			t.Fatalf("[%d] Expecting valid: %v for %v but got %v", i, tt.valid, tt.domains, err)
+		}
+	}
+
+	// the server is not started
+	s := New()
+	s.Get("/", HandlerFunc(func(c *Context) { c.Write("secure") }))
+	admin := s.Party("admin.mydomain.com")
+	admin.Get("/", HandlerFunc(func(c *Context) { c.Write("admin") }))
+	if err := s.ListenTLSProvider("127.0.0.1:0", m); err == nil || !strings.Contains(err.Error(), "admin.mydomain.com") {
+		t.Fatalf("Expecting an error for the domain without certificate but got %v", err)
+	}
+}
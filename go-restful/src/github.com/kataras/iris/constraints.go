@@ -0,0 +1,250 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package iris
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ConstraintStartByte is the byte which opens a parameter's constraint, ex: /users/:id(int)
+	ConstraintStartByte = byte('(')
+	// ConstraintEndByte is the byte which closes a parameter's constraint
+	ConstraintEndByte = byte(')')
+)
+
+// ParamConstraint is the interface which a named path parameter's constraint must implement
+// Match receives the raw value of the parameter and returns the typed value (if any) and true if the value is accepted
+type ParamConstraint interface {
+	Match(value string) (interface{}, bool)
+}
+
+// ParamConstraintFunc is an adapter to allow the use of ordinary functions as ParamConstraint
+type ParamConstraintFunc func(value string) (interface{}, bool)
+
+// Match calls the function itself
+func (f ParamConstraintFunc) Match(value string) (interface{}, bool) {
+	return f(value)
+}
+
+// ParamConstraints contains the constraints of a route, the key is the parameter's name
+type ParamConstraints map[string]ParamConstraint
+
+// ParamConstraintMaker is the function which creates a ParamConstraint, receives the argument of the constraint
+// for example the argument of the :slug(regex:[a-z-]+) is the [a-z-]+
+type ParamConstraintMaker func(arg string) (ParamConstraint, error)
+
+var paramConstraintMakers = map[string]ParamConstraintMaker{
+	"int": func(arg string) (ParamConstraint, error) {
+		return ParamConstraintFunc(func(value string) (interface{}, bool) {
+			v, err := strconv.Atoi(value)
+			return v, err == nil
+		}), nil
+	},
+	"uuid": func(arg string) (ParamConstraint, error) {
+		return ParamConstraintFunc(func(value string) (interface{}, bool) {
+			return value, isUUID(value)
+		}), nil
+	},
+	"regex": func(arg string) (ParamConstraint, error) {
+		expr, err := regexp.Compile("^(?:" + arg + ")$")
+		if err != nil {
+			return nil, err
+		}
+		return ParamConstraintFunc(func(value string) (interface{}, bool) {
+			return value, expr.MatchString(value)
+		}), nil
+	},
+}
+
+// RegisterParamConstraint registers a custom constraint which can be used on the route's path by it's name
+// ex: iris.RegisterParamConstraint("bool", maker) then /flags/:enabled(bool)
+//
+// must be called before the routes which are using it
+func RegisterParamConstraint(name string, maker ParamConstraintMaker) {
+	paramConstraintMakers[name] = maker
+}
+
+// parseParamConstraint creates a ParamConstraint from it's string representation, ex: int, regex:[a-z]+
+func parseParamConstraint(spec string) (ParamConstraint, error) {
+	name, arg := spec, ""
+	if idx := strings.IndexByte(spec, ':'); idx != -1 {
+		name, arg = spec[:idx], spec[idx+1:]
+	}
+
+	maker, found := paramConstraintMakers[name]
+	if !found {
+		return nil, fmt.Errorf("unknown constraint '%s'", name)
+	}
+	return maker(arg)
+}
+
+// stripParamConstraints removes the constraints from a registed path and returns the clean path and the parsed constraints
+// ex: /users/:id(int)/:slug(regex:[a-z-]+) returns /users/:id/:slug and the two constraints
+func stripParamConstraints(path string) (string, ParamConstraints, error) {
+	if strings.IndexByte(path, ConstraintStartByte) == -1 {
+		return path, nil, nil
+	}
+
+	var constraints ParamConstraints
+	clean := make([]byte, 0, len(path))
+
+	for i := 0; i < len(path); i++ {
+		clean = append(clean, path[i])
+		if path[i] != ParameterStartByte {
+			continue
+		}
+		// read the parameter's name
+		start := i + 1
+		end := paramNameEnd(path, i)
+		clean = append(clean, path[start:end]...)
+		name := path[start:end]
+		i = end - 1
+		if i+1 == len(path) || path[i+1] != ConstraintStartByte {
+			continue
+		}
+		// find the closing parenthesis, the constraint's argument may contain parenthesis too
+		depth := 0
+		end = -1
+		for j := i + 1; j < len(path); j++ {
+			switch path[j] {
+			case '\\':
+				j++
+			case ConstraintStartByte:
+				depth++
+			case ConstraintEndByte:
+				depth--
+			}
+			if depth == 0 {
+				end = j
+				break
+			}
+		}
+		if end == -1 {
+			return "", nil, fmt.Errorf("missing '%c' on the constraint of the parameter '%s'", ConstraintEndByte, name)
+		}
+
+		if isWholeSegmentParam(path, start-1) && end+1 < len(path) && path[end+1] != SlashByte {
+			return "", nil, fmt.Errorf("the constraint of the parameter '%s' should be at the end of the segment", name)
+		}
+
+		constraint, err := parseParamConstraint(path[i+2 : end])
+		if err != nil {
+			return "", nil, fmt.Errorf("parameter '%s': %s", name, err.Error())
+		}
+		if constraints == nil {
+			constraints = make(ParamConstraints)
+		}
+		constraints[name] = constraint
+		i = end
+	}
+
+	return string(clean), constraints, nil
+}
+
+// paramKeys returns the names of the named parameters and the match everything parameter of a path, by order
+func paramKeys(path string) []string {
+	keys := make([]string, 0, GetParamsLen(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] != ParameterStartByte && path[i] != MatchEverythingByte {
+			continue
+		}
+		end := paramNameEnd(path, i)
+		keys = append(keys, path[i+1:end])
+		i = end - 1
+	}
+	return keys
+}
+
+// isUUID returns true if the value has the form of xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx, hex digits
+func isUUID(value string) bool {
+	if len(value) != 36 {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// constrainedMiddleware is the middleware of a route which has constraints on it's parameters
+// a Branch keeps one of these for each route which is registed with the same path but different constraints
+type constrainedMiddleware struct {
+	// keys are the parameters' names of this route, because the Branch keeps the names of the first registed route
+	// nil when the names are the same as the Branch's
+	keys []string
+	// constraints by the parameter's position, nil if the parameter has no constraint
+	constraints []ParamConstraint
+	middleware  Middleware
+}
+
+func newConstrainedMiddleware(fullPath string, middleware Middleware, constraints ParamConstraints) *constrainedMiddleware {
+	c := &constrainedMiddleware{keys: paramKeys(fullPath), middleware: middleware}
+	if len(constraints) > 0 {
+		c.constraints = make([]ParamConstraint, len(c.keys))
+		for i, key := range c.keys {
+			c.constraints[i] = constraints[key]
+		}
+	}
+	return c
+}
+
+// match checks the request's parameters against the constraints and sets their typed values
+func (c *constrainedMiddleware) match(params PathParameters) bool {
+	if c.keys != nil && len(params) != len(c.keys) {
+		return false
+	}
+	for i := range params {
+		params[i].Typed = nil
+		if c.constraints == nil || c.constraints[i] == nil {
+			continue
+		}
+		typed, ok := c.constraints[i].Match(params[i].Value)
+		if !ok {
+			return false
+		}
+		params[i].Typed = typed
+	}
+	// the Branch keeps the parameters' names of the first registed route, so set the correct ones
+	for i := range c.keys {
+		params[i].Key = c.keys[i]
+	}
+	return true
+}
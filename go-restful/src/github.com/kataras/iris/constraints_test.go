@@ -0,0 +1,86 @@
+package iris
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStripParamConstraints(t *testing.T) {
+	path, constraints, err := stripParamConstraints("/users/:id(int)/posts/:slug(regex:[a-z-]+(/[0-9]+)?)/*file")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if expected := "/users/:id/posts/:slug/*file"; path != expected {
+		t.Fatalf("Expecting path %s but we got %s", expected, path)
+	}
+	if len(constraints) != 2 || constraints["id"] == nil || constraints["slug"] == nil {
+		t.Fatalf("Expecting the constraints of id and slug but we got %v", constraints)
+	}
+
+	if _, _, err = stripParamConstraints("/users/:id(unknown)"); err == nil {
+		t.Fatal("Expecting error for unknown constraint")
+	}
+	if _, _, err = stripParamConstraints("/users/:id(int"); err == nil {
+		t.Fatal("Expecting error for missing ')'")
+	}
+}
+
+func TestRouteParamConstraints(t *testing.T) {
+	s := New()
+	s.Get("/users/:id(int)", HandlerFunc(func(c *Context) {
+		id, _ := c.ParamTyped("id").(int)
+		c.Write("user %d", id)
+	}))
+	s.Get("/items/:id(int)", HandlerFunc(func(c *Context) {
+		c.Write("item id %d", c.ParamTyped("id"))
+	}))
+	s.Get("/items/:slug(regex:[a-z-]+)", HandlerFunc(func(c *Context) {
+		c.Write("item slug %s", c.Param("slug"))
+	}))
+	s.Get("/files/:uuid(uuid)/*path", HandlerFunc(func(c *Context) {
+		c.Write("file %s%s", c.Param("uuid"), c.Param("path"))
+	}))
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/users/42", 200, "user 42"},
+		{"/users/abc", 404, ""},
+		{"/items/7", 200, "item id 7"},
+		{"/items/foo-bar", 200, "item slug foo-bar"},
+		{"/items/FOO", 404, ""},
+		{"/files/123e4567-e89b-12d3-a456-426614174000/a/b.txt", 200, "file 123e4567-e89b-12d3-a456-426614174000/a/b.txt"},
+		{"/files/123e4567/a/b.txt", 404, ""},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		res := httptest.NewRecorder()
+		s.ServeHTTP(res, req)
+		if res.Code != tt.status {
+			t.Fatalf("%s: Expecting status %d but we got %d", tt.path, tt.status, res.Code)
+		}
+		if body, _ := ioutil.ReadAll(res.Body); tt.status == 200 && string(body) != tt.body {
+			t.Fatalf("%s: Expecting body '%s' but we got '%s'", tt.path, tt.body, string(body))
+		}
+	}
+}
+
+func TestRouteParamConstraintsNames(t *testing.T) {
+	s := New()
+	s.Get("/orders/:id(int)", HandlerFunc(func(c *Context) {}))
+	s.Get("/orders/:name/lines", HandlerFunc(func(c *Context) {
+		c.Write("%s", c.Params.String())
+	}))
+
+	req, _ := http.NewRequest("GET", "/orders/first/lines", nil)
+	res := httptest.NewRecorder()
+	s.ServeHTTP(res, req)
+	if body := res.Body.String(); body != "name=first" {
+		t.Fatalf("Expecting parameters 'name=first' but we got '%s'", body)
+	}
+}
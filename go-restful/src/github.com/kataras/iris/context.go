@@ -36,8 +36,6 @@ import (
 	"net/http"
 	"net/url"
 	"path"
-	"reflect"
-	"runtime"
 	"strconv"
 	"strings"
 	"sync"
@@ -55,6 +53,7 @@ type IContext interface {
 	SetMemoryResponseWriter(MemoryWriter)
 	Param(key string) string
 	ParamInt(key string) (int, error)
+	ParamTyped(key string) interface{}
 	URLParam(key string) string
 	URLParamInt(key string) (int, error)
 	Get(key string) interface{}
@@ -69,11 +68,23 @@ type IContext interface {
 	NotFound()
 	Panic()
 	EmitError(statusCode int)
+	Error(err error)
+	HandleError(err error)
+	GetError() *HTTPError
 	StopExecution()
 	//
 	Redirect(path string, statusHeader ...int) error
+	RedirectTo(name string, values ...interface{}) error
+	Push(target string, opts *http.PushOptions) error
+	Bind(dst interface{}) error
+	MustBind(dst interface{}) bool
+	Negotiate(httpStatus int, offers map[string]func() error) error
+	Respond(httpStatus int, value interface{}) error
+	SSE() *SSEWriter
+	Stream(step func(w io.Writer) bool) bool
 	SendStatus(statusCode int, message string)
 	RequestIP() string
+	Host() string
 	Close()
 	End()
 	IsStopped() bool
@@ -103,6 +114,14 @@ type IContext interface {
 	RenderXML(hhttpStatus int, xmlStructs ...interface{}) error
 	ReadXML(xmlObject interface{}) error
 	GetHandlerName() string
+	GetRoutePath() string
+	Logger() *Logger
+	RequestID() string
+	SetRequestID(id string)
+	TraceContext() TraceContext
+	SetTraceContext(t TraceContext)
+	StartSpan(name string) *Span
+	Span() *Span
 }
 
 // Charset is defaulted to UTF-8, you can change it
@@ -130,6 +149,10 @@ const (
 	ContentXML = "application/xml"
 	// ContentXMLText is the  string of text/xml response headers
 	ContentXMLText = "text/xml"
+	// ContentForm is the  string of application/x-www-form-urlencoded request headers
+	ContentForm = "application/x-www-form-urlencoded"
+	// ContentMultipart is the  string of multipart/form-data request headers
+	ContentMultipart = "multipart/form-data"
 
 	// stopExecutionPosition the number which shows us that the context's middleware manualy stop the execution
 	stopExecutionPosition = 255 // is the biggest uint8
@@ -153,6 +176,19 @@ type Context struct {
 	// use iris/sessions for cookie/filesystem storage
 	values map[string]interface{}
 	mu     sync.Mutex
+	// err is the error which the error handler sends, see Context.Error
+	err *HTTPError
+	// logger is the request's logger, created by the first call of the Context.Logger
+	logger *Logger
+	// requestID and trace are set by the requestid middleware, see Context.RequestID and Context.TraceContext
+	requestID string
+	trace     TraceContext
+	// span is the current span of the request, nil if the request is not traced, see Station.Tracing
+	span *Span
+	// traceHandlers is true if each handler has its own span, see TracingOptions.MiddlewareSpans
+	traceHandlers bool
+	// routePath is the registed path of the route which serves the request, see Context.GetRoutePath
+	routePath string
 }
 
 var _ IContext = &Context{}
@@ -199,10 +235,19 @@ func (ctx *Context) Param(key string) string {
 
 // ParamInt returns the int representation of the key's path named parameter's value
 func (ctx *Context) ParamInt(key string) (int, error) {
+	if val, ok := ctx.Params.GetTyped(key).(int); ok {
+		return val, nil
+	}
 	val, err := strconv.Atoi(ctx.Params.Get(key))
 	return val, err
 }
 
+// ParamTyped returns the typed value of the key's path named parameter, which the parameter's constraint produced
+// ex: int for the /users/:id(int), nil if the parameter has no constraint
+func (ctx *Context) ParamTyped(key string) interface{} {
+	return ctx.Params.GetTyped(key)
+}
+
 // URLParam returns the get parameter from a request , if any
 func (ctx *Context) URLParam(key string) string {
 	return URLParam(ctx.Request, key)
@@ -240,7 +285,7 @@ func (ctx *Context) SetCookie(name string, value string) {
 // if no custom errors provided then it sends the default http.NotFound
 func (ctx *Context) NotFound() {
 	ctx.StopExecution()
-	ctx.station.EmitError(404, ctx)
+	ctx.EmitError(404)
 }
 
 // Panic stops the executions of the context and returns the registed panic handler
@@ -249,14 +294,46 @@ func (ctx *Context) NotFound() {
 // This function is useful when you use the recovery middleware, which is auto-executing the (custom, registed) 500 internal server error.
 func (ctx *Context) Panic() {
 	ctx.StopExecution()
-	ctx.station.EmitError(500, ctx)
+	ctx.EmitError(500)
 }
 
 // EmitError executes the custom error by the http status code passed to the function
 func (ctx *Context) EmitError(statusCode int) {
+	ctx.err = nil
 	ctx.station.EmitError(statusCode, ctx)
 }
 
+// Error stops the executions of the context and sends the error by the error handler of its http status code,
+// the code of an *HTTPError, the mapped code (see Station.MapError) or 500 for other errors, the handler gets the error by the GetError
+//
+// Example:
+// ctx.Error(&iris.HTTPError{Code: 422, Message: "invalid user", Details: map[string]string{"email": "required"}})
+func (ctx *Context) Error(err error) {
+	ctx.StopExecution()
+	ctx.err = ctx.station.httpError(err)
+	ctx.station.EmitError(ctx.err.Code, ctx)
+}
+
+// HandleError stops the executions of the context and passes the error to the station's error handler (see Station.SetErrorHandler),
+// it's called with the error which a HandlerFuncErr returns, by default it's the Context.Error
+func (ctx *Context) HandleError(err error) {
+	ctx.StopExecution()
+	if ctx.station.errorHandler != nil {
+		ctx.station.errorHandler(ctx, err)
+		return
+	}
+	ctx.Error(err)
+}
+
+// GetError returns the error which the error handler sends, see Context.Error
+// if the error emitted by its http status code (ctx.EmitError) then it has only the code
+func (ctx *Context) GetError() *HTTPError {
+	if ctx.err == nil {
+		return &HTTPError{Code: ctx.ResponseWriter.Status()}
+	}
+	return ctx.err
+}
+
 // StopExecution just sets the .pos to 255 in order to  not move to the next middlewares(if any)
 func (ctx *Context) StopExecution() {
 	ctx.pos = stopExecutionPosition
@@ -294,6 +371,34 @@ func (ctx *Context) Redirect(urlToRedirect string, statusHeader ...int) error {
 	return err
 }
 
+// RedirectTo redirects the client to the route which has this name, the parameters are filled with the values by order, see Station.URL
+// ex: ctx.RedirectTo("user.edit", 42), the status is 302
+func (ctx *Context) RedirectTo(name string, values ...interface{}) error {
+	urlToRedirect, err := ctx.station.URL(name, values...)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(urlToRedirect, "//") {
+		// a route with domain
+		scheme := "http:"
+		if ctx.Request.TLS != nil {
+			scheme = "https:"
+		}
+		urlToRedirect = scheme + urlToRedirect
+	}
+	return ctx.Redirect(urlToRedirect)
+}
+
+// Push initiates an HTTP/2 server push of the target, ex: ctx.Push("/public/style.css", nil)
+// it does nothing if the connection doesn't support it (HTTP/1.x or the client disabled the push),
+// it should be called before the response is written
+func (ctx *Context) Push(target string, opts *http.PushOptions) error {
+	if err := ctx.ResponseWriter.Push(target, opts); err != http.ErrNotSupported {
+		return err
+	}
+	return nil
+}
+
 // SendStatus sends a http status to the client
 // it receives status code (int) and a message (string)
 func (ctx *Context) SendStatus(statusCode int, message string) {
@@ -313,6 +418,11 @@ func (ctx *Context) RequestIP() string {
 	return ""
 }
 
+// Host returns the host which the client requested, lowercase, it's the X-Forwarded-Host if the StationOptions.TrustForwardedHost is true
+func (ctx *Context) Host() string {
+	return ctx.station.requestHost(ctx.Request)
+}
+
 // RemoteAddr is like RequestIP but it checks for proxy servers also, tries to get the real client's request IP
 func (ctx *Context) RemoteAddr() string {
 	header := ctx.Request.Header.Get("X-Real-Ip")
@@ -356,6 +466,10 @@ func (ctx *Context) Next() {
 	midLen := uint8(len(ctx.middleware)) // max 255 handlers, we don't except more than these logically ...
 	//run the next
 	if ctx.pos < midLen {
+		if ctx.traceHandlers {
+			ctx.serveTraced(ctx.middleware[ctx.pos])
+			return
+		}
 		ctx.middleware[ctx.pos].Serve(ctx)
 	}
 
@@ -364,6 +478,10 @@ func (ctx *Context) Next() {
 // Do calls the first handler only, it's like Next with negative pos, used only on Router&MemoryRouter
 func (ctx *Context) Do() {
 	ctx.pos = 0
+	if ctx.traceHandlers {
+		ctx.serveTraced(ctx.middleware[0])
+		return
+	}
 	ctx.middleware[0].Serve(ctx)
 }
 
@@ -373,23 +491,33 @@ func (ctx *Context) Do() {
 func (ctx *Context) Reset(res http.ResponseWriter, req *http.Request) {
 	ctx.Params = ctx.Params[0:0]
 	ctx.middleware = nil
+	ctx.routePath = ""
+	ctx.resetRequest(res, req)
+}
+
+// resetRequest resets the values of the previous request, it keeps the parameters and the middleware of the route, see Redo
+func (ctx *Context) resetRequest(res http.ResponseWriter, req *http.Request) {
+	ctx.err = nil
+	ctx.logger = nil
+	ctx.requestID = ""
+	ctx.trace = TraceContext{}
+	ctx.span = nil
+	ctx.traceHandlers = false
 	ctx.memoryResponseWriter.Reset(res)
+	if ctx.station.Server != nil {
+		ctx.memoryResponseWriter.hijacked = &ctx.station.Server.hijacked
+	}
 	ctx.ResponseWriter = &ctx.memoryResponseWriter
 	ctx.Request = req
-	/*if len(ctx.values) > 0 {
-		for k, _ := range ctx.values {
-			delete(ctx.values, k)
-		}
-	}*/
-
+	// the values are set by the previous request's middleware, the next client should never see them
+	for k := range ctx.values {
+		delete(ctx.values, k)
+	}
 }
 
 // Redo is used inside the MemoryRouter from a cached Context, do whatever Do does but it sets the newresponsewriter and the request before that
 func (ctx *Context) Redo(res http.ResponseWriter, req *http.Request) {
-	//ctx.memoryResponseWriter = MemoryWriter{res, -1, 200}
-	ctx.memoryResponseWriter.Reset(res)
-	ctx.ResponseWriter = &ctx.memoryResponseWriter
-	ctx.Request = req
+	ctx.resetRequest(res, req)
 	ctx.Do()
 	ctx.memoryResponseWriter.ForceHeader()
 
@@ -398,12 +526,12 @@ func (ctx *Context) Redo(res http.ResponseWriter, req *http.Request) {
 // Clone before we had (c Context) inscope and  (c *Context) for outscope like goroutines
 // now we have (c *Context) for both sittuations ,and call .Clone() if we need to pass the context in a gorotoune or to a time func
 // example:
-// api.Get("/user/:id", func(ctx *iris.Context) {
+// api.Get("/user/:id", iris.HandlerFunc(func(ctx *iris.Context) {
 //		c:= ctx.Clone()
 //		time.AfterFunc(20 * time.Second, func() {
 //			println(" 20 secs after: from user with id:", c.Param("id"), " context req path:", c.Request.URL.Path)
 //		})
-//	})
+//	}))
 func (ctx *Context) Clone() *Context {
 	var cloneContext = *ctx
 	cloneContext.pos = 0
@@ -412,11 +540,13 @@ func (ctx *Context) Clone() *Context {
 	params := cloneContext.Params
 	cpP := make(PathParameters, len(params))
 	copy(cpP, params)
+	cloneContext.Params = cpP
 	//copy middleware
 	middleware := ctx.middleware
 	cpM := make(Middleware, len(middleware))
 	copy(cpM, middleware)
-	cloneContext.middleware = middleware
+	cloneContext.middleware = cpM
+	cloneContext.routePath = ctx.GetRoutePath()
 
 	cloneContext.memoryResponseWriter.ResponseWriter = nil
 	cloneContext.ResponseWriter = &cloneContext.memoryResponseWriter
@@ -474,13 +604,26 @@ func (ctx *Context) Set(key string, value interface{}) {
 
 // RenderFile renders a file by its path and a context passed to the function
 func (ctx *Context) RenderFile(file string, pageContext interface{}) error {
-	return ctx.station.GetTemplates().ExecuteTemplate(ctx.GetResponseWriter(), file, pageContext)
+	span := ctx.startSpan("iris.render", SpanKindInternal)
+	span.SetAttribute("iris.template", file)
+	err := ctx.station.GetTemplates().ExecuteTemplate(ctx.GetResponseWriter(), file, pageContext)
+	if err != nil {
+		span.SetStatus(SpanStatusError, err.Error())
+	}
+	span.End()
+	return err
 
 }
 
 // Render renders the template file html which is already registed to the template cache, with it's pageContext passed to the function
 func (ctx *Context) Render(pageContext interface{}) error {
-	return ctx.station.GetTemplates().Execute(ctx.GetResponseWriter(), pageContext)
+	span := ctx.startSpan("iris.render", SpanKindInternal)
+	err := ctx.station.GetTemplates().Execute(ctx.GetResponseWriter(), pageContext)
+	if err != nil {
+		span.SetStatus(SpanStatusError, err.Error())
+	}
+	span.End()
+	return err
 
 }
 
@@ -527,7 +670,7 @@ func (ctx *Context) Write(format string, a ...interface{}) {
 
 // WriteStatus write/or/and/sends to client, to the response writer a given status code
 func (ctx *Context) WriteStatus(statusCode int) {
-	ctx.memoryResponseWriter.WriteHeader(statusCode)
+	ctx.ResponseWriter.WriteHeader(statusCode)
 }
 
 // SetContentType sets the response writer's header key 'Content-Type' to a given value(s)
@@ -680,6 +823,58 @@ func (ctx *Context) RenderXML(httpStatus int, xmlStructs ...interface{}) error {
 
 // GetHandlerName as requested returns the stack-name of the function which the Middleware is setted from
 func (ctx *Context) GetHandlerName() string {
-	return runtime.FuncForPC(reflect.ValueOf(ctx.middleware[len(ctx.middleware)-1]).Pointer()).Name()
+	return handlerName(ctx.middleware[len(ctx.middleware)-1])
+
+}
 
+// Logger returns a child of the station's logger which writes the request id and the trace id (if any), the method and the path with each message,
+// ex: c.Logger().Info("user created", "id", user.ID)
+func (ctx *Context) Logger() *Logger {
+	if ctx.logger == nil {
+		keyvals := make([]interface{}, 0, 8)
+		if id := ctx.RequestID(); id != "" {
+			keyvals = append(keyvals, "request_id", id)
+		}
+		if ctx.trace.IsValid() {
+			keyvals = append(keyvals, "trace_id", ctx.trace.TraceID.String())
+		}
+		keyvals = append(keyvals, "method", ctx.Request.Method, "path", ctx.Request.URL.Path)
+		ctx.logger = ctx.station.GetLogger().With(keyvals...)
+	}
+	return ctx.logger
+}
+
+// RequestID returns the id of the request, which is set by the requestid middleware (see SetRequestID)
+// or the X-Request-Id header of the request if it's not set
+func (ctx *Context) RequestID() string {
+	if ctx.requestID != "" {
+		return ctx.requestID
+	}
+	return ctx.Request.Header.Get(RequestIDHeader)
+}
+
+// SetRequestID sets the id of the request, it doesn't send it to the client
+func (ctx *Context) SetRequestID(id string) {
+	ctx.requestID = id
+}
+
+// TraceContext returns the W3C trace context of the request which is set by the Station.Tracing or the requestid middleware (see SetTraceContext),
+// the zero TraceContext if it's not set
+func (ctx *Context) TraceContext() TraceContext {
+	return ctx.trace
+}
+
+// SetTraceContext sets the W3C trace context of the request, it doesn't send it to the client
+func (ctx *Context) SetTraceContext(t TraceContext) {
+	ctx.trace = t
+}
+
+// GetRoutePath returns the registed path of the route which serves the request, ex: /users/:id
+// returns empty string if the request is not served by a route, ex: from the http error handlers
+func (ctx *Context) GetRoutePath() string {
+	if ctx.routePath == "" && len(ctx.middleware) > 0 {
+		// found once per request, the Context of a cached route keeps it for the next requests
+		ctx.routePath = ctx.station.routePaths[&ctx.middleware[0]]
+	}
+	return ctx.routePath
 }
@@ -28,6 +28,7 @@ package iris
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"encoding/xml"
 	"strings"
 	"testing"
@@ -104,3 +105,67 @@ func TestContext_ReadXML(t *testing.T) {
 		t.Fatalf("ReadXML should return \"John\" and \"Doe\", but returned: %s and %s", obj.FirstName, obj.LastName)
 	}
 }
+
+func TestContext_GetRoutePath(t *testing.T) {
+	s := New()
+	var routePath string
+	record := HandlerFunc(func(c *Context) {
+		routePath = c.GetRoutePath()
+	})
+	api := s.Party("/api")
+	api.UseFunc(func(c *Context) { c.Next() })
+	api.Get("/users/:id", record)
+	api.Get("/files/*path", record)
+	s.Get("/", record)
+	s.OnError(404, record)
+	handler := s.Serve()
+
+	tests := map[string]string{
+		"/api/users/42":      "/api/users/:id",
+		"/api/files/a/b.txt": "/api/files/*path",
+		"/":                  "/",
+		"/missing":           "",
+	}
+	// the second time from the cache
+	for i := 0; i < 2; i++ {
+		for path, expected := range tests {
+			routePath = "-"
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+			if routePath != expected {
+				t.Fatalf("[%d] expected the route path of %s to be %q but got %q", i, path, expected, routePath)
+			}
+		}
+	}
+}
+
+// the routes of a party must not share the party's middleware slice
+func TestContext_PartyMiddlewareIsolation(t *testing.T) {
+	s := New()
+	next := HandlerFunc(func(c *Context) { c.Next() })
+	p := s.Party("/p")
+	p.UseFunc(next, next, next)
+	p.Get("/a", HandlerFunc(func(c *Context) { c.Write("a") }))
+	p.Get("/b", HandlerFunc(func(c *Context) { c.Write("b") }))
+	// sibling parties of the same hoster
+	c1 := p.Party("/c1")
+	c2 := p.Party("/c2")
+	c1.UseFunc(func(c *Context) {
+		c.Write("c1 ")
+		c.Next()
+	})
+	c2.UseFunc(func(c *Context) {
+		c.Write("c2 ")
+		c.Next()
+	})
+	c1.Get("/x", HandlerFunc(func(c *Context) { c.Write("x") }))
+	c2.Get("/x", HandlerFunc(func(c *Context) { c.Write("x") }))
+	handler := s.Serve()
+
+	for path, expected := range map[string]string{"/p/a": "a", "/p/b": "b", "/p/c1/x": "c1 x", "/p/c2/x": "c2 x"} {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest("GET", path, nil))
+		if res.Body.String() != expected {
+			t.Fatalf("expected %s to respond %q but got %q", path, expected, res.Body.String())
+		}
+	}
+}
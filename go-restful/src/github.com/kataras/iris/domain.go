@@ -0,0 +1,216 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package iris
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// ForwardedHostHeader is the header which a proxy sets with the host which the client requested, see StationOptions.TrustForwardedHost
+	ForwardedHostHeader = "X-Forwarded-Host"
+	// SubdomainParamKey is the parameter's key of a match everything subdomain without name, ex: *.mydomain.com
+	SubdomainParamKey = "subdomain"
+)
+
+// hostKind is the kind of a route's domain, the domains are checked by this order
+type hostKind uint8
+
+const (
+	// hostLiteral is a domain without parameters, ex: admin.mydomain.com
+	hostLiteral hostKind = iota
+	// hostParams is a domain with named parameters, ex: :tenant.mydomain.com
+	hostParams
+	// hostWildcard is a domain with a match everything subdomain, ex: *.mydomain.com
+	hostWildcard
+	// hostNone is the kind of the routes without domain
+	hostNone
+)
+
+// hostPattern is the parsed domain of a route
+//
+// a label (the parts between the dots) can be a named parameter, ex: :tenant.mydomain.com,
+// the first label can be a match everything subdomain which matches one or more labels, ex: *.mydomain.com or *sub.mydomain.com,
+// a domain without port matches all the ports, ex: mydomain.com matches the mydomain.com:8080 too
+type hostPattern struct {
+	kind   hostKind
+	labels []string
+	port   string
+	// keys are the parameters' names, by order
+	keys []string
+}
+
+// parseHostPattern parses the domain of a route
+func parseHostPattern(domain string) (*hostPattern, error) {
+	h := &hostPattern{kind: hostLiteral}
+	domain, h.port = splitHostPort(domain)
+	if domain == "" {
+		return nil, errors.New("empty domain")
+	}
+	h.labels = strings.Split(domain, ".")
+	for i, label := range h.labels {
+		if label == "" {
+			return nil, fmt.Errorf("empty label on the domain '%s'", domain)
+		}
+		switch label[0] {
+		case MatchEverythingByte:
+			if i != 0 {
+				return nil, fmt.Errorf("the match everything subdomain should be the first label, ex: *.mydomain.com")
+			}
+			key := label[1:]
+			if key == "" {
+				key = SubdomainParamKey
+			}
+			h.keys = append(h.keys, key)
+			h.kind = hostWildcard
+		case ParameterStartByte:
+			if len(label) == 1 {
+				return nil, fmt.Errorf("missing the name of the parameter on the domain '%s'", domain)
+			}
+			h.keys = append(h.keys, label[1:])
+			if h.kind == hostLiteral {
+				h.kind = hostParams
+			}
+		}
+		for j := 1; j < len(label) && label[0] != MatchEverythingByte && label[0] != ParameterStartByte; j++ {
+			if label[j] == MatchEverythingByte || label[j] == ParameterStartByte {
+				return nil, fmt.Errorf("a parameter should be the whole label of the domain '%s'", domain)
+			}
+		}
+	}
+	return h, nil
+}
+
+// splitHostPort splits a host to the host and the port, if any, ex: mydomain.com:8080 or [::1]:8080
+func splitHostPort(host string) (string, string) {
+	idx := strings.LastIndexByte(host, ':')
+	if idx <= 0 || idx < strings.LastIndexByte(host, ']') {
+		return host, ""
+	}
+	port := host[idx+1:]
+	if port == "" {
+		return host[:idx], ""
+	}
+	for i := 0; i < len(port); i++ {
+		if port[i] < '0' || port[i] > '9' {
+			// :tenant.mydomain.com
+			return host, ""
+		}
+	}
+	return host[:idx], port
+}
+
+// match returns true if the host matches the domain, it appends the values of the domain's parameters to the params
+func (h *hostPattern) match(host string, params PathParameters) (PathParameters, bool) {
+	host, port := splitHostPort(host)
+	if h.port != "" && h.port != port {
+		return params, false
+	}
+
+	start := len(params)
+	k := len(h.keys)
+	ended := false
+	for i := len(h.labels) - 1; i >= 0; i-- {
+		if ended {
+			return params[:start], false
+		}
+		label := h.labels[i]
+
+		var value string
+		if label[0] == MatchEverythingByte {
+			// the rest of the host, one or more labels
+			value, host, ended = host, "", true
+		} else if idx := strings.LastIndexByte(host, '.'); idx != -1 {
+			value, host = host[idx+1:], host[:idx]
+		} else {
+			value, host, ended = host, "", true
+		}
+		if value == "" {
+			return params[:start], false
+		}
+
+		if label[0] == ParameterStartByte || label[0] == MatchEverythingByte {
+			k--
+			params = append(params, PathParameter{Key: h.keys[k], Value: value})
+		} else if !strings.EqualFold(label, value) {
+			return params[:start], false
+		}
+	}
+	if !ended {
+		return params[:start], false
+	}
+
+	// the values are appended from the last label, reverse them in order to be by the keys' order
+	for i, j := start, len(params)-1; i < j; i, j = i+1, j-1 {
+		params[i], params[j] = params[j], params[i]
+	}
+	return params, true
+}
+
+// build returns the host with the values of the parameters, by order
+func (h *hostPattern) build(values []interface{}) (string, error) {
+	labels := make([]string, len(h.labels))
+	v := 0
+	for i, label := range h.labels {
+		if label[0] != ParameterStartByte && label[0] != MatchEverythingByte {
+			labels[i] = label
+			continue
+		}
+		value := ""
+		if values[v] != nil {
+			value = fmt.Sprint(values[v])
+		}
+		if value == "" || strings.IndexAny(value, "/:") != -1 {
+			return "", fmt.Errorf("the value '%s' is not valid for the subdomain '%s'", value, h.keys[v])
+		}
+		labels[i] = value
+		v++
+	}
+	host := strings.Join(labels, ".")
+	if h.port != "" {
+		host += ":" + h.port
+	}
+	return host, nil
+}
+
+// requestHost returns the host which the client requested, the X-Forwarded-Host if the StationOptions.TrustForwardedHost is true, lowercase
+func (s *Station) requestHost(req *http.Request) string {
+	host := req.Host
+	if s.options.TrustForwardedHost {
+		if forwarded := req.Header.Get(ForwardedHostHeader); forwarded != "" {
+			// the first one is the client's, the next ones are added by the proxies between
+			if idx := strings.IndexByte(forwarded, ','); idx != -1 {
+				forwarded = forwarded[:idx]
+			}
+			host = strings.TrimSpace(forwarded)
+		}
+	}
+	return strings.ToLower(host)
+}
@@ -0,0 +1,105 @@
+package iris
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testDomainHandler(c *Context) {
+	c.Write("%s %s", c.Request.URL.Path, c.Params.String())
+}
+
+func TestDomainPatterns(t *testing.T) {
+	for _, cache := range []bool{true, false} {
+		options := defaultOptions()
+		options.Cache = cache
+		options.TrustForwardedHost = true
+		s := Custom(options)
+		s.Get("admin.mydomain.com/users/:id", HandlerFunc(testDomainHandler))
+		s.Get(":tenant.mydomain.com/users/:id", HandlerFunc(testDomainHandler))
+		s.Get("*.mydomain.com/", HandlerFunc(testDomainHandler))
+		s.Get("*sub.mydomain.com:8080/port", HandlerFunc(testDomainHandler))
+		s.Get("/users/:id", HandlerFunc(testDomainHandler))
+		s.Get("/about", HandlerFunc(testDomainHandler))
+		handler := s.Serve()
+
+		tests := []struct {
+			host      string
+			forwarded string
+			path      string
+			status    int
+			body      string
+		}{
+			{"admin.mydomain.com", "", "/users/42", http.StatusOK, "/users/42 id=42"},
+			{"Admin.MyDomain.com:8080", "", "/users/42", http.StatusOK, "/users/42 id=42"},
+			{"kataras.mydomain.com", "", "/users/42", http.StatusOK, "/users/42 id=42,tenant=kataras"},
+			{"a.b.mydomain.com", "", "/", http.StatusOK, "/ subdomain=a.b"},
+			{"a.b.mydomain.com:8080", "", "/port", http.StatusOK, "/port sub=a.b"},
+			{"a.b.mydomain.com:9090", "", "/port", http.StatusNotFound, "404 not found"},
+			{"proxy.local", "kataras.mydomain.com, proxy.local", "/users/42", http.StatusOK, "/users/42 id=42,tenant=kataras"},
+			// fallback to the routes without domain
+			{"kataras.mydomain.com", "", "/about", http.StatusOK, "/about "},
+			{"mydomain.com", "", "/users/42", http.StatusOK, "/users/42 id=42"},
+			{"other.com", "", "/missing", http.StatusNotFound, "404 not found"},
+			{"kataras.mydomain.com", "", "/users/42/", http.StatusMovedPermanently, "<a href=\"/users/42\">Moved Permanently</a>.\n"},
+		}
+
+		for i, tt := range tests {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Host = tt.host
+			if tt.forwarded != "" {
+				req.Header.Set(ForwardedHostHeader, tt.forwarded)
+			}
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.status || w.Body.String() != tt.body {
+				t.Fatalf("[cache: %v, %d] %s%s expected %d %q but got %d %q", cache, i, tt.host, tt.path, tt.status, tt.body, w.Code, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestDomainForwardedHostUntrusted(t *testing.T) {
+	s := New()
+	s.Get(":tenant.mydomain.com/", HandlerFunc(testDomainHandler))
+	s.Get("/", HandlerFunc(testDomainHandler))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "proxy.local"
+	req.Header.Set(ForwardedHostHeader, "kataras.mydomain.com")
+	s.Serve().ServeHTTP(w, req)
+	if w.Body.String() != "/ " {
+		t.Fatalf("Expected the route without domain but got %q", w.Body.String())
+	}
+}
+
+func TestDomainURL(t *testing.T) {
+	s := New()
+	s.Get(":tenant.mydomain.com/users/:id", HandlerFunc(testRoutesHandler)).Name("user")
+	s.Get("*.mydomain.com:8080/", HandlerFunc(testRoutesHandler)).Name("home")
+
+	if url, err := s.URL("user", "kataras", 42); err != nil || url != "//kataras.mydomain.com/users/42" {
+		t.Fatalf("Unexpected url %s %v", url, err)
+	}
+	if url, err := s.URL("home", "a.b"); err != nil || url != "//a.b.mydomain.com:8080/" {
+		t.Fatalf("Unexpected url %s %v", url, err)
+	}
+	if _, err := s.URL("user", "", 42); err == nil {
+		t.Fatalf("Expected an error for the empty subdomain")
+	}
+}
+
+func TestDomainSyntax(t *testing.T) {
+	for _, domain := range []string{"admin.*.mydomain.com/", "api-:tenant.mydomain.com/", "..mydomain.com/"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s expected to panic", domain)
+				}
+			}()
+			New().Get(domain, HandlerFunc(testRoutesHandler))
+		}()
+	}
+}
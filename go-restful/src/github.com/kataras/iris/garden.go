@@ -32,6 +32,16 @@ type tree struct {
 	domain     string
 	hosts      bool //if domain != "" we set it directly on .Plant
 	cors       bool // if cross domain allow enabled
+	// host is the parsed domain, nil if the tree has no domain
+	host *hostPattern
+}
+
+// hostKind returns the kind of the tree's domain, hostNone if the tree has no domain
+func (t tree) hostKind() hostKind {
+	if t.host == nil {
+		return hostNone
+	}
+	return t.host.kind
 }
 
 // Garden is the main area which routes are planted/placed
@@ -81,13 +91,23 @@ func (g Garden) getRootByMethodAndDomain(method string, domain string) *Branch {
 func (g Garden) Plant(_route IRoute) Garden {
 
 	//we have a domain to assign too
-	theRoot := g.getRootByMethodAndDomain(_route.GetMethod(), _route.GetDomain())
+	domain := _route.GetDomain()
+	theRoot := g.getRootByMethodAndDomain(_route.GetMethod(), domain)
 	if theRoot == nil {
 		theRoot = new(Branch)
-		g = append(g, tree{_route.GetMethod(), theRoot, _route.GetDomain(), _route.GetDomain() != "", hasCors(_route)}) //hasCors is inside utils.go
+		_tree := tree{method: _route.GetMethod(), rootBranch: theRoot, domain: domain, hosts: domain != "", cors: hasCors(_route)} //hasCors is inside utils.go
+		if _tree.hosts {
+			host, err := parseHostPattern(domain)
+			if err != nil {
+				panic("[Iris] Error on route " + _route.GetMethod() + ":" + domain + _route.GetPath() + " " + err.Error())
+			}
+			_tree.host = host
+		}
+		g = append(g, _tree)
 
 	}
-	theRoot.AddBranch(_route.GetDomain()+_route.GetPath(), _route.GetMiddleware())
+	// the domain is matched by the tree's host, the branches have only the path
+	theRoot.AddBranch(_route.GetPath(), _route.GetMiddleware(), _route.GetParamConstraints())
 
 	return g
 }
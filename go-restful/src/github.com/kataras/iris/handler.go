@@ -47,6 +47,19 @@ func (h HandlerFunc) Serve(ctx *Context) {
 	h(ctx)
 }
 
+// HandlerFuncErr is an adapter to allow the use of functions which return an error as Handlers, the verbs, the Handle and the Use accept it,
+// ex: iris.Get("/users/:id", iris.HandlerFuncErr(getUser)),
+// a returned error stops the execution of the next handlers, as the Context.StopExecution does,
+// and it's sent by the station's error handler, see Station.SetErrorHandler and Station.MapError
+type HandlerFuncErr func(*Context) error
+
+// Serve serves the handler, a returned error is passed to the Context.HandleError
+func (h HandlerFuncErr) Serve(ctx *Context) {
+	if err := h(ctx); err != nil {
+		ctx.HandleError(err)
+	}
+}
+
 //IMiddlewareSupporter is an interface which all routers must implement
 type IMiddlewareSupporter interface {
 	Use(handlers ...Handler)
@@ -75,11 +88,15 @@ func Static(SystemPath string, PathToStrip ...string) HandlerFunc {
 
 }
 
-// ToHandler converts http.Handler or func(http.ResponseWriter, *http.Request) to an iris.Handler
+// ToHandler converts func(*Context), func(*Context) error, http.Handler or func(http.ResponseWriter, *http.Request) to an iris.Handler
 func ToHandler(handler interface{}) Handler {
 	switch handler.(type) {
 	case Handler:
 		return handler.(Handler)
+	case func(*Context):
+		return HandlerFunc(handler.(func(*Context)))
+	case func(*Context) error:
+		return HandlerFuncErr(handler.(func(*Context) error))
 	case http.Handler:
 		return HandlerFunc((func(ctx *Context) {
 			handler.(http.Handler).ServeHTTP(ctx.GetResponseWriter(), ctx.GetRequest())
@@ -90,7 +107,7 @@ func ToHandler(handler interface{}) Handler {
 			handler.(func(http.ResponseWriter, *http.Request))(ctx.GetResponseWriter(), ctx.GetRequest())
 		}))
 	default:
-		panic(fmt.Sprintf("Error on Iris: handler is not func(*Context) either an object which implements the iris.Handler with  func Serve(ctx *Context)\n It seems to be a  %T Point to: %v:", handler, handler))
+		panic(fmt.Sprintf("Error on Iris: handler is not func(*Context), func(*Context) error either an object which implements the iris.Handler with  func Serve(ctx *Context)\n It seems to be a  %T Point to: %v:", handler, handler))
 	}
 }
 
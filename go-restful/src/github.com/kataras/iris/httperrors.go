@@ -27,9 +27,103 @@
 package iris
 
 import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"html"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 )
 
+const (
+	// ContentProblemJSON is the media type of the RFC 7807 problem details as JSON
+	ContentProblemJSON = "application/problem+json"
+	// ContentProblemXML is the media type of the RFC 7807 problem details as XML
+	ContentProblemXML = "application/problem+xml"
+)
+
+// HTTPError is an error with an http status code, the Context.Error sends it by the error handler of its code
+// the default error handlers send its message and details, see Problem
+type HTTPError struct {
+	Code int
+	// Message is sent to the client, if empty the default message of the code is sent
+	Message string
+	// Details are any values which describe the error, ex: the invalid fields
+	Details interface{}
+	// Err is the cause of the error, it's not sent to the client
+	Err error
+}
+
+// NewHTTPError returns an *HTTPError with an http status code and a message
+func NewHTTPError(code int, message string) *HTTPError {
+	return &HTTPError{Code: code, Message: message}
+}
+
+func (e *HTTPError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = strconv.Itoa(e.Code) + " " + http.StatusText(e.Code)
+	}
+	if e.Err != nil {
+		return message + ": " + e.Err.Error()
+	}
+	return message
+}
+
+// Unwrap returns the cause of the error
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// errorStatus is the http status code of the errors which are the target, see Station.MapError
+type errorStatus struct {
+	target error
+	code   int
+}
+
+// MapError maps an error to an http status code, the errors which are the target (see errors.Is), even wrapped, are sent with this code,
+// ex: iris.MapError(sql.ErrNoRows, 404), the first mapped target is used
+func (s *Station) MapError(target error, statusCode int) {
+	s.errorStatuses = append(s.errorStatuses, errorStatus{target: target, code: statusCode})
+}
+
+// SetErrorHandler sets the handler of the errors which the handlers return (see HandlerFuncErr) and the recovery middleware recovers,
+// the default sends the error by the error handler of its status code, see Context.Error
+func (s *Station) SetErrorHandler(handler func(ctx *Context, err error)) {
+	s.errorHandler = handler
+}
+
+// httpError returns the *HTTPError of the error, an other error is the cause of an *HTTPError with the mapped code (see Station.MapError) or 500
+func (s *Station) httpError(err error) *HTTPError {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+	for _, e := range s.errorStatuses {
+		if errors.Is(err, e.target) {
+			return &HTTPError{Code: e.code, Err: err}
+		}
+	}
+	return &HTTPError{Code: http.StatusInternalServerError, Err: err}
+}
+
+// Problem is the RFC 7807 problem details which the default error handlers send as JSON or XML
+type Problem struct {
+	XMLName xml.Name `json:"-" xml:"urn:ietf:rfc:7807 problem"`
+	// Type is about:blank, the problem has no other semantics than its status
+	Type   string `json:"type" xml:"type"`
+	Title  string `json:"title" xml:"title"`
+	Status int    `json:"status" xml:"status"`
+	Detail string `json:"detail,omitempty" xml:"detail,omitempty"`
+	// Instance is the requested path
+	Instance string `json:"instance,omitempty" xml:"instance,omitempty"`
+	// Details are the HTTPError's Details, only on JSON
+	Details interface{} `json:"details,omitempty" xml:"-"`
+}
+
 // IErrorHandler is the interface which an http error handler should implement
 type IErrorHandler interface {
 	GetCode() int
@@ -51,6 +145,51 @@ func ErrorHandlerFunc(statusCode int, message string) HandlerFunc {
 	}
 }
 
+// errorMediaTypes are the media types of the default error handlers
+var errorMediaTypes = []string{ContentTEXT, ContentHTML, ContentJSON, ContentXML, ContentProblemJSON, ContentProblemXML}
+
+// DefaultErrorHandler creates the default handler of an http error status, the message is sent if the error has no message (see Context.Error)
+//
+// it sends the error by the media type which the client prefers, text/plain, text/html, application/json, application/xml,
+// application/problem+json or application/problem+xml (the RFC 7807 problem details, see Problem),
+// the text/plain is sent if the request has no Accept header or none of them is acceptable
+func DefaultErrorHandler(statusCode int, message string) HandlerFunc {
+	return func(ctx *Context) {
+		e := ctx.GetError()
+		msg := message
+		if e.Message != "" {
+			msg = e.Message
+		}
+
+		mediaType := ContentTEXT
+		if accept := ctx.Request.Header.Get(AcceptHeader); strings.TrimSpace(accept) != "" {
+			ctx.addVaryAccept()
+			if mediaType = negotiate(accept, errorMediaTypes); mediaType == "" {
+				mediaType = ContentTEXT
+			}
+		}
+
+		problem := Problem{Type: "about:blank", Title: http.StatusText(statusCode), Status: statusCode, Detail: msg,
+			Instance: ctx.Request.URL.Path, Details: e.Details}
+		var body bytes.Buffer
+		switch mediaType {
+		case ContentJSON, ContentProblemJSON:
+			json.NewEncoder(&body).Encode(problem)
+		case ContentXML, ContentProblemXML:
+			xml.NewEncoder(&body).Encode(problem)
+		case ContentHTML:
+			title := html.EscapeString(strconv.Itoa(statusCode) + " " + problem.Title)
+			body.WriteString("<!DOCTYPE html><html><head><title>" + title + "</title></head><body><h1>" + title + "</h1><p>" +
+				html.EscapeString(msg) + "</p></body></html>")
+		default:
+			ctx.ResponseWriter.Header().Set("X-Content-Type-Options", "nosniff")
+			body.WriteString(msg)
+		}
+		ctx.ResponseWriter.Header().Set(ContentType, contentTypeOf(mediaType))
+		ctx.ResponseWriter.Write(body.Bytes())
+	}
+}
+
 // ErrorHandler is just an object which stores a http status code and a handler
 type ErrorHandler struct {
 	code    int
@@ -94,8 +233,10 @@ var _ IHTTPErrors = &HTTPErrors{}
 func defaultHTTPErrors() *HTTPErrors {
 	httperrors := new(HTTPErrors)
 	httperrors.ErrorHanders = make([]IErrorHandler, 0)
-	httperrors.On(http.StatusNotFound, ErrorHandlerFunc(http.StatusNotFound, "404 not found"))
-	httperrors.On(http.StatusInternalServerError, ErrorHandlerFunc(http.StatusInternalServerError, "The server encountered an unexpected condition which prevented it from fulfilling the request."))
+	httperrors.On(http.StatusNotFound, DefaultErrorHandler(http.StatusNotFound, "404 not found"))
+	httperrors.On(http.StatusMethodNotAllowed, DefaultErrorHandler(http.StatusMethodNotAllowed, "405 method not allowed"))
+	httperrors.On(http.StatusNotAcceptable, DefaultErrorHandler(http.StatusNotAcceptable, "406 not acceptable"))
+	httperrors.On(http.StatusInternalServerError, DefaultErrorHandler(http.StatusInternalServerError, "The server encountered an unexpected condition which prevented it from fulfilling the request."))
 	return httperrors
 }
 
@@ -138,3 +279,77 @@ func (he *HTTPErrors) Emit(errCode int, ctx *Context) {
 		errHandler.GetHandler().Serve(ctx)
 	}
 }
+
+// partyErrors are the error handlers of a Party, see GardenParty.OnError
+type partyErrors struct {
+	// domain is the party's domain as registed, empty if the party has no domain
+	domain string
+	// host is the parsed domain, nil if the party has no domain
+	host   *hostPattern
+	path   string
+	errors *HTTPErrors
+}
+
+// match returns true if the request's path starts with the party's path, the parameters of the party's path match any segment
+func (p *partyErrors) match(ctx *Context) bool {
+	if p.host != nil {
+		if _, ok := p.host.match(ctx.Host(), nil); !ok {
+			return false
+		}
+	}
+	reqSegments := strings.Split(ctx.Request.URL.Path, Slash)
+	for i, segment := range strings.Split(p.path, Slash) {
+		if segment != "" && segment[0] == MatchEverythingByte {
+			return true
+		}
+		if i >= len(reqSegments) {
+			return false
+		}
+		if segment != reqSegments[i] && (segment == "" || segment[0] != ParameterStartByte || reqSegments[i] == "") {
+			return false
+		}
+	}
+	return true
+}
+
+// onPartyError registers an error handler of a party's path, the parties with the longest path are first
+func (s *Station) onPartyError(partyPath string, statusCode int, handlerFunc HandlerFunc) {
+	domain, path := "", partyPath
+	if isDomainPath(partyPath) {
+		domain, path = partyPath, ""
+		if idx := strings.IndexByte(partyPath, SlashByte); idx != -1 {
+			domain, path = partyPath[:idx], partyPath[idx:]
+		}
+	}
+	for _, p := range s.partyErrors {
+		if p.domain == domain && p.path == path {
+			p.errors.On(statusCode, handlerFunc)
+			return
+		}
+	}
+
+	p := &partyErrors{domain: domain, path: path, errors: &HTTPErrors{}}
+	if domain != "" {
+		host, err := parseHostPattern(domain)
+		if err != nil {
+			panic("[Iris] Error on OnError of the party " + partyPath + " " + err.Error())
+		}
+		p.host = host
+	}
+	p.errors.On(statusCode, handlerFunc)
+	s.partyErrors = append(s.partyErrors, p)
+	sort.SliceStable(s.partyErrors, func(i, j int) bool {
+		return len(s.partyErrors[i].path) > len(s.partyErrors[j].path) ||
+			(len(s.partyErrors[i].path) == len(s.partyErrors[j].path) && s.partyErrors[i].host != nil && s.partyErrors[j].host == nil)
+	})
+}
+
+// partyErrorHandler returns the error handler of the party with the longest path which the request's path starts with, if any
+func (s *Station) partyErrorHandler(statusCode int, ctx *Context) IErrorHandler {
+	for _, p := range s.partyErrors {
+		if h := p.errors.GetByCode(statusCode); h != nil && p.match(ctx) {
+			return h
+		}
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package iris
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPartyOnError(t *testing.T) {
+	s := New()
+	api := s.Party("/api")
+	api.OnError(http.StatusNotFound, func(c *Context) { c.Write("api not found") })
+	v2 := api.Party("/v2")
+	v2.OnError(http.StatusNotFound, func(c *Context) { c.Write("v2 not found") })
+	users := s.Party("/users/:id")
+	users.OnError(http.StatusNotFound, func(c *Context) { c.Write("user not found") })
+	admin := s.Party("admin.mydomain.com")
+	admin.OnError(http.StatusNotFound, func(c *Context) { c.Write("admin not found") })
+	s.OnError(http.StatusNotFound, func(c *Context) { c.Write("not found") })
+	api.Get("/users/:id", HandlerFunc(func(c *Context) { c.NotFound() }))
+	api.Get("/fail", HandlerFunc(func(c *Context) { c.Panic() }))
+	handler := s.Serve()
+
+	tests := []struct {
+		host string
+		path string
+		body string
+	}{
+		{"", "/api/missing", "api not found"},
+		{"", "/api", "api not found"},
+		{"", "/api/users/42", "api not found"},
+		{"", "/api/v2/missing", "v2 not found"},
+		{"", "/apiary", "not found"},
+		{"", "/users/42/posts", "user not found"},
+		{"", "/users", "not found"},
+		{"admin.mydomain.com", "/missing", "admin not found"},
+		{"", "/missing", "not found"},
+		// the party has no 500 handler
+		{"", "/api/fail", "The server encountered an unexpected condition which prevented it from fulfilling the request."},
+	}
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.host != "" {
+			req.Host = tt.host
+		}
+		handler.ServeHTTP(w, req)
+		if w.Body.String() != tt.body {
+			t.Fatalf("[%d] %s expected %q but got %d %q", i, tt.path, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestContextError(t *testing.T) {
+	s := New()
+	s.Get("/invalid", HandlerFunc(func(c *Context) {
+		c.Error(&HTTPError{Code: http.StatusUnprocessableEntity, Message: "invalid user", Details: map[string]string{"email": "required"}})
+	}))
+	s.Get("/fail", HandlerFunc(func(c *Context) { c.Error(errors.New("database is down")) }))
+	s.Get("/missing", HandlerFunc(func(c *Context) { c.Error(NewHTTPError(http.StatusNotFound, "")) }))
+	s.OnError(http.StatusUnprocessableEntity, DefaultErrorHandler(http.StatusUnprocessableEntity, "422 unprocessable entity"))
+	handler := s.Serve()
+
+	tests := []struct {
+		path        string
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"/invalid", "", 422, ContentTEXT, "invalid user"},
+		{"/invalid", "text/html", 422, ContentHTML, "<!DOCTYPE html><html><head><title>422 Unprocessable Entity</title></head><body><h1>422 Unprocessable Entity</h1><p>invalid user</p></body></html>"},
+		{"/invalid", "application/problem+json", 422, ContentProblemJSON,
+			`{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"invalid user","instance":"/invalid","details":{"email":"required"}}` + "\n"},
+		{"/invalid", "application/xml", 422, ContentXML,
+			`<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type><title>Unprocessable Entity</title><status>422</status><detail>invalid user</detail><instance>/invalid</instance></problem>`},
+		// the cause is not sent
+		{"/fail", "", 500, ContentTEXT, "The server encountered an unexpected condition which prevented it from fulfilling the request."},
+		{"/missing", "image/png", 404, ContentTEXT, "404 not found"},
+	}
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.accept != "" {
+			req.Header.Set(AcceptHeader, tt.accept)
+		}
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.status || !strings.HasPrefix(w.Header().Get(ContentType), tt.contentType) || w.Body.String() != tt.body {
+			t.Fatalf("[%d] Expected %d %s %q but got %d %s %q", i, tt.status, tt.contentType, tt.body, w.Code, w.Header().Get(ContentType), w.Body.String())
+		}
+	}
+}
+
+func TestContextGetError(t *testing.T) {
+	s := New()
+	var got *HTTPError
+	s.OnError(http.StatusInternalServerError, func(c *Context) {
+		got = c.GetError()
+		c.JSON(map[string]string{"error": got.Error()})
+	})
+	cause := errors.New("database is down")
+	s.Get("/fail", HandlerFunc(func(c *Context) { c.Error(cause) }))
+	s.Get("/panic", HandlerFunc(func(c *Context) { c.Panic() }))
+	handler := s.Serve()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/fail", nil))
+	var body map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != 500 || !errors.Is(got, cause) ||
+		body["error"] != "500 Internal Server Error: database is down" {
+		t.Fatalf("Unexpected %d %q %v", w.Code, w.Body.String(), got)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if got.Code != 500 || got.Err != nil {
+		t.Fatalf("Expected only the code of the emitted error but got %#v", got)
+	}
+}
+
+var (
+	errTestNotFound = errors.New("user not found")
+	errTestConflict = errors.New("conflict")
+)
+
+func TestHandlerFuncErr(t *testing.T) {
+	s := New()
+	s.MapError(errTestNotFound, http.StatusNotFound)
+	s.MapError(errTestConflict, http.StatusConflict)
+	auth := HandlerFuncErr(func(c *Context) error {
+		if c.Request.Header.Get("Authorization") == "" {
+			return NewHTTPError(http.StatusUnauthorized, "missing authorization")
+		}
+		c.Next()
+		return nil
+	})
+	s.Get("/users/:id", auth, HandlerFuncErr(func(c *Context) error {
+		switch c.Param("id") {
+		case "0":
+			return fmt.Errorf("finding user 0: %w", errTestNotFound)
+		case "1":
+			return errTestConflict
+		case "2":
+			return errors.New("database is down")
+		}
+		c.Write("user %s", c.Param("id"))
+		c.Next()
+		return nil
+	}), HandlerFunc(func(c *Context) { c.Write(" and the next handler") }))
+	handler := s.Serve()
+
+	tests := []struct {
+		path   string
+		auth   bool
+		status int
+		body   string
+	}{
+		{"/users/42", true, http.StatusOK, "user 42 and the next handler"},
+		{"/users/42", false, http.StatusUnauthorized, "missing authorization"},
+		{"/users/0", true, http.StatusNotFound, "404 not found"},
+		// no handler for 409
+		{"/users/1", true, http.StatusConflict, "409 conflict"},
+		{"/users/2", true, http.StatusInternalServerError, "The server encountered an unexpected condition which prevented it from fulfilling the request."},
+	}
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.auth {
+			req.Header.Set("Authorization", "token")
+		}
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.status || w.Body.String() != tt.body {
+			t.Fatalf("[%d] Expected %d %q but got %d %q", i, tt.status, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestStationErrorHandler(t *testing.T) {
+	s := New()
+	var handled error
+	s.SetErrorHandler(func(c *Context, err error) {
+		handled = err
+		c.Error(NewHTTPError(http.StatusServiceUnavailable, "try again later"))
+	})
+	s.Get("/", HandlerFuncErr(func(c *Context) error { return errTestConflict }), HandlerFunc(func(c *Context) { c.Write("not executed") }))
+
+	w := httptest.NewRecorder()
+	s.Serve().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if handled != errTestConflict || w.Code != http.StatusServiceUnavailable || w.Body.String() != "try again later" {
+		t.Fatalf("Unexpected %v %d %q", handled, w.Code, w.Body.String())
+	}
+}
+
+func TestDefaultErrorHandlerMessage(t *testing.T) {
+	s := New()
+	s.Get("/users/:id", HandlerFunc(func(c *Context) {
+		c.Error(NewHTTPError(http.StatusNotFound, "user "+c.Param("id")+" (secret@x.com) not found"))
+	}))
+	handler := s.Serve()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/users/42", nil))
+	if w.Code != http.StatusNotFound || w.Body.String() != "user 42 (secret@x.com) not found" {
+		t.Fatalf("Unexpected %d %q", w.Code, w.Body.String())
+	}
+
+	// the message of the previous request should not be kept
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/nothing", nil))
+	if w.Code != http.StatusNotFound || w.Body.String() != "404 not found" {
+		t.Fatalf("Expected the default message but got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestDomainPartyOnErrorTwice(t *testing.T) {
+	s := New()
+	admin := s.Party("admin.mydomain.com")
+	admin.OnError(http.StatusNotFound, func(c *Context) { c.Write("first") })
+	admin.OnError(http.StatusNotFound, func(c *Context) { c.Write("second") })
+	admin.OnError(http.StatusForbidden, func(c *Context) { c.Write("forbidden") })
+	users := s.Party("admin.mydomain.com/users")
+	users.OnError(http.StatusNotFound, func(c *Context) { c.Write("users") })
+	users.OnError(http.StatusNotFound, func(c *Context) { c.Write("users again") })
+	if len(s.partyErrors) != 2 {
+		t.Fatalf("Expected the error handlers of 2 parties but got %d", len(s.partyErrors))
+	}
+	handler := s.Serve()
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/missing", "second"},
+		{"/users/missing", "users again"},
+	}
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Host = "admin.mydomain.com"
+		handler.ServeHTTP(w, req)
+		if w.Body.String() != tt.body {
+			t.Fatalf("[%d] %s expected %q but got %d %q", i, tt.path, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
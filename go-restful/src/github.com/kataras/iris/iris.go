@@ -27,7 +27,10 @@
 package iris
 
 import (
+	"context"
+	"net"
 	"net/http"
+	"os"
 	"time"
 )
 
@@ -49,7 +52,17 @@ func defaultOptions() StationOptions {
 		Cache:              true,
 		CacheMaxItems:      0,
 		CacheResetDuration: 5 * time.Minute,
+		CachePolicy:        CacheTick,
 		PathCorrection:     true,
+		MethodNotAllowed:   true,
+		AutoHead:           true,
+		AutoOptions:        true,
+		TrustForwardedHost: false,
+		ShutdownTimeout:    10 * time.Second,
+		MaxBodySize:        DefaultMaxBodySize,
+		Server: ServerOptions{
+			KeepAlivePeriod: DefaultKeepAlivePeriod,
+		},
 	}
 }
 
@@ -71,6 +84,14 @@ func Custom(options StationOptions) *Station {
 		options.CacheResetDuration = 5 * time.Minute
 	}
 
+	if options.ShutdownTimeout <= 0 {
+		options.ShutdownTimeout = 10 * time.Second
+	}
+
+	if options.MaxBodySize <= 0 {
+		options.MaxBodySize = DefaultMaxBodySize
+	}
+
 	return newStation(options)
 }
 
@@ -95,9 +116,40 @@ func ListenTLS(fullAddress string, certFile, keyFile string) error {
 	return DefaultStation.ListenTLS(fullAddress, certFile, keyFile)
 }
 
+// ListenTLSProvider Starts a httpS/http2 server which takes the certificates from the provider,
+// ex: a CertificateManager with a certificate for each domain or an ACMEManager
+func ListenTLSProvider(fullAddress string, provider ICertificateProvider) error {
+	return DefaultStation.ListenTLSProvider(fullAddress, provider)
+}
+
+// CacheStats returns the counters of the router's cache, see Station.CacheStats
+func CacheStats() ContextCacheStats {
+	return DefaultStation.CacheStats()
+}
+
+// OnBindError sets the handler which renders the errors of the Context.MustBind, see Station.OnBindError
+func OnBindError(handler func(ctx *Context, err *BindError)) {
+	DefaultStation.OnBindError(handler)
+}
+
+// ListenUNIX starts the standalone http server which listens to a unix domain socket
+// first parameter is the path of the socket file, an old socket file with this path is removed
+// second parameter is the file mode of the socket file, ex: 0666
+func ListenUNIX(path string, mode os.FileMode) error {
+	return DefaultStation.ListenUNIX(path, mode)
+}
+
+// ListenOn starts the standalone http server which serves an already created listener
+func ListenOn(listener net.Listener) error {
+	return DefaultStation.ListenOn(listener)
+}
+
 // Close is used to close the net.Listener of the standalone http server which has already running via .Listen
 func Close() { DefaultStation.Close() }
 
+// Shutdown gracefully shutdowns the standalone http server, waits for the active requests until the context is done
+func Shutdown(ctx context.Context) error { return DefaultStation.Shutdown(ctx) }
+
 // Router implementation
 
 // Party is just a group joiner of routes which have the same prefix and share same middleware(s) also.
@@ -107,13 +159,13 @@ func Party(rootPath string) IParty {
 }
 
 // Handle registers a route to the server's router
-func Handle(method string, registedPath string, handlers ...Handler) {
-	DefaultStation.Handle(method, registedPath, handlers...)
+func Handle(method string, registedPath string, handlers ...Handler) IRoute {
+	return DefaultStation.Handle(method, registedPath, handlers...)
 }
 
 // HandleFunc registers a route with a method, path string, and a handler
-func HandleFunc(method string, path string, handlersFn ...HandlerFunc) {
-	DefaultStation.HandleFunc(method, path, handlersFn...)
+func HandleFunc(method string, path string, handlersFn ...HandlerFunc) IRoute {
+	return DefaultStation.HandleFunc(method, path, handlersFn...)
 }
 
 // HandleAnnotated registers a route handler using a Struct implements iris.Handler (as anonymous property)
@@ -136,58 +188,58 @@ func UseFunc(handlersFn ...HandlerFunc) {
 }
 
 // Get registers a route for the Get http method
-func Get(path string, handlersFn ...HandlerFunc) {
-	DefaultStation.Get(path, handlersFn...)
+func Get(path string, handlers ...Handler) IRoute {
+	return DefaultStation.Get(path, handlers...)
 }
 
 // Post registers a route for the Post http method
-func Post(path string, handlersFn ...HandlerFunc) {
-	DefaultStation.Post(path, handlersFn...)
+func Post(path string, handlers ...Handler) IRoute {
+	return DefaultStation.Post(path, handlers...)
 }
 
 // Put registers a route for the Put http method
-func Put(path string, handlersFn ...HandlerFunc) {
-	DefaultStation.Put(path, handlersFn...)
+func Put(path string, handlers ...Handler) IRoute {
+	return DefaultStation.Put(path, handlers...)
 }
 
 // Delete registers a route for the Delete http method
-func Delete(path string, handlersFn ...HandlerFunc) {
-	DefaultStation.Delete(path, handlersFn...)
+func Delete(path string, handlers ...Handler) IRoute {
+	return DefaultStation.Delete(path, handlers...)
 }
 
 // Connect registers a route for the Connect http method
-func Connect(path string, handlersFn ...HandlerFunc) {
-	DefaultStation.Connect(path, handlersFn...)
+func Connect(path string, handlers ...Handler) IRoute {
+	return DefaultStation.Connect(path, handlers...)
 }
 
 // Head registers a route for the Head http method
-func Head(path string, handlersFn ...HandlerFunc) {
-	DefaultStation.Head(path, handlersFn...)
+func Head(path string, handlers ...Handler) IRoute {
+	return DefaultStation.Head(path, handlers...)
 }
 
 // Options registers a route for the Options http method
-func Options(path string, handlersFn ...HandlerFunc) {
-	DefaultStation.Options(path, handlersFn...)
+func Options(path string, handlers ...Handler) IRoute {
+	return DefaultStation.Options(path, handlers...)
 }
 
 // Patch registers a route for the Patch http method
-func Patch(path string, handlersFn ...HandlerFunc) {
-	DefaultStation.Patch(path, handlersFn...)
+func Patch(path string, handlers ...Handler) IRoute {
+	return DefaultStation.Patch(path, handlers...)
 }
 
 // Trace registers a route for the Trace http methodd
-func Trace(path string, handlersFn ...HandlerFunc) {
-	DefaultStation.Trace(path, handlersFn...)
+func Trace(path string, handlers ...Handler) IRoute {
+	return DefaultStation.Trace(path, handlers...)
 }
 
 // Any registers a route for ALL of the http methods (Get,Post,Put,Head,Patch,Options,Connect,Delete)
-func Any(path string, handlersFn ...HandlerFunc) {
-	DefaultStation.Any(path, handlersFn...)
+func Any(path string, handlers ...Handler) IRoute {
+	return DefaultStation.Any(path, handlers...)
 }
 
 // Ws registers a websocket route
-func Ws(path string, handler Handler) {
-	DefaultStation.Ws(path, handler)
+func Ws(path string, handler Handler) IRoute {
+	return DefaultStation.Ws(path, handler)
 }
 
 // ServeHTTP serves an http request,
@@ -215,6 +267,18 @@ func EmitError(statusCode int, ctx *Context) {
 	DefaultStation.EmitError(statusCode, ctx)
 }
 
+// MapError maps an error to an http status code, the errors which are the target (see errors.Is), even wrapped, are sent with this code,
+// ex: iris.MapError(sql.ErrNoRows, 404), the first mapped target is used
+func MapError(target error, statusCode int) {
+	DefaultStation.MapError(target, statusCode)
+}
+
+// SetErrorHandler sets the handler of the errors which the handlers return (see HandlerFuncErr) and the recovery middleware recovers,
+// the default sends the error by the error handler of its status code, see Context.Error
+func SetErrorHandler(handler func(ctx *Context, err error)) {
+	DefaultStation.SetErrorHandler(handler)
+}
+
 // OnNotFound sets the handler for http status 404,
 // default is a response with text: 'Not Found' and status: 404
 func OnNotFound(handlerFunc HandlerFunc) {
@@ -228,6 +292,30 @@ func OnPanic(handlerFunc HandlerFunc) {
 }
 
 //
+// URL returns the path of the route which has this name, the parameters (:name and *name) are filled with the values by order
+// ex: iris.Get("/users/:id/edit", h).Name("user.edit") and iris.URL("user.edit", 42) returns /users/42/edit
+func URL(name string, values ...interface{}) (string, error) {
+	return DefaultStation.URL(name, values...)
+}
+
+// Routes returns the registed routes, sorted by domain, path and method
+// print them at the startup: fmt.Println(iris.Routes())
+func Routes() RoutesTable {
+	return DefaultStation.Routes()
+}
+
+// GetLogger returns the logger of the default station, the parent of the parties', the plugins' and the requests' loggers
+// ex: iris.GetLogger().SetLevel(iris.LevelDebug)
+func GetLogger() *Logger {
+	return DefaultStation.GetLogger()
+}
+
+// Tracing enables the tracing of the default station's requests, ex: with the middleware/tracing's OTLP/HTTP exporter
+// iris.Tracing(iris.TracingOptions{Processor: exporter, MiddlewareSpans: true}), see Station.Tracing
+func Tracing(options TracingOptions) {
+	DefaultStation.Tracing(options)
+}
+
 // Templates sets the templates glob path for the web app
 func Templates(pathGlob string) {
 	DefaultStation.Templates(pathGlob)
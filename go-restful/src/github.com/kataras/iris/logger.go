@@ -1,4 +1,4 @@
-// Copyright (c) 2016, Gerasimos Maropoulos and Go Authors using for the 'log,io,os' packages
+// Copyright (c) 2016, Gerasimos Maropoulos
 // All rights reserved.
 //
 // Redistribution and use in source and binary forms, with or without modification,
@@ -27,9 +27,14 @@
 package iris
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"strconv"
+	"sync"
+	"time"
 )
 
 const (
@@ -40,9 +45,70 @@ const (
 // LoggerOutTerminal os.Stdout , it's the default io.Writer to the Iris' logger
 var LoggerOutTerminal = os.Stdout
 
-// Logger is just a log.Logger
+// Level is the severity of a log record, the Logger writes the records with level >= its level, see Logger.SetLevel
+type Level int
+
+const (
+	// LevelDebug is the level of the messages which help to debug an application
+	LevelDebug Level = iota
+	// LevelInfo is the default level, the Print functions of the Logger write with this level
+	LevelInfo
+	// LevelWarn is the level of the problems which don't stop the application
+	LevelWarn
+	// LevelError is the level of the errors
+	LevelError
+)
+
+var levelNames = [...]string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+func (l Level) String() string {
+	if l >= LevelDebug && l <= LevelError {
+		return levelNames[l]
+	}
+	return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+}
+
+// Field is a key-value of a log record
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Record is a log message which the Logger passes to its sinks
+type Record struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	// Fields are the fields of the logger (the parents' first) and after the fields of the message
+	Fields []Field
+}
+
+// Sink writes the records of a Logger, ex: to a file, to the syslog or to the memory
+// the Log is called by many goroutines
+type Sink interface {
+	Log(*Record) error
+}
+
+// loggerCore is the level and the sinks which a logger and its children share
+type loggerCore struct {
+	mu    sync.RWMutex
+	level Level
+	sinks []Sink
+	// errorOut is the writer which the errors of the sinks are written to
+	errorOut io.Writer
+}
+
+// Logger is the leveled logger of the station, the plugins and the requests
+//
+// The messages have key-value fields, ex: logger.Info("user created", "id", 42),
+// the children (see With) have the fields of their parents and they share the level and the sinks.
+//
+// The Print functions of the embedded log.Logger write records with LevelInfo,
+// the default sink writes the records as lines to the out writer of the NewLogger
 type Logger struct {
 	*log.Logger
+	core   *loggerCore
+	fields []Field
 }
 
 // NewLogger creates a new Logger.   The out variable sets the
@@ -53,5 +119,187 @@ func NewLogger(out io.Writer, prefix string, flag int) *Logger {
 	if out == nil {
 		out = LoggerOutTerminal
 	}
-	return &Logger{log.New(out, LoggerIrisPrefix+prefix, flag)}
+	core := &loggerCore{level: LevelInfo, sinks: []Sink{NewWriterSink(out, LoggerIrisPrefix+prefix, flag)}, errorOut: os.Stderr}
+	return newLogger(core, nil)
+}
+
+func newLogger(core *loggerCore, fields []Field) *Logger {
+	l := &Logger{core: core, fields: fields}
+	l.Logger = log.New(printWriter{l}, "", 0)
+	return l
+}
+
+// printWriter writes the lines of the Print functions as records with LevelInfo
+type printWriter struct {
+	logger *Logger
+}
+
+func (w printWriter) Write(p []byte) (int, error) {
+	msg := string(p)
+	if n := len(msg); n > 0 && msg[n-1] == '\n' {
+		msg = msg[:n-1]
+	}
+	w.logger.log(LevelInfo, msg, nil)
+	return len(p), nil
+}
+
+// appendFields appends the key-value pairs to the fields,
+// a key which is not a string is formatted with %v and a key without value has the "!BADKEY" key
+func appendFields(fields []Field, keyvals []interface{}) []Field {
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 == len(keyvals) {
+			fields = append(fields, Field{"!BADKEY", keyvals[i]})
+			break
+		}
+		key, ok := keyvals[i].(string)
+		if !ok {
+			key = fmt.Sprint(keyvals[i])
+		}
+		fields = append(fields, Field{key, keyvals[i+1]})
+	}
+	return fields
+}
+
+// With returns a child logger which writes the key-value pairs with each message,
+// it shares the level and the sinks with its parent
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]Field, len(l.fields), len(l.fields)+(len(keyvals)+1)/2)
+	copy(fields, l.fields)
+	return newLogger(l.core, appendFields(fields, keyvals))
+}
+
+// SetLevel sets the minimum level of the messages which are written, of the logger, its parents and its children
+// Default is LevelInfo
+func (l *Logger) SetLevel(level Level) {
+	l.core.mu.Lock()
+	l.core.level = level
+	l.core.mu.Unlock()
+}
+
+// GetLevel returns the minimum level of the messages which are written
+func (l *Logger) GetLevel() Level {
+	l.core.mu.RLock()
+	defer l.core.mu.RUnlock()
+	return l.core.level
+}
+
+// Enabled returns true if the messages of this level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.GetLevel()
+}
+
+// AddSink adds a sink to the logger, its parents and its children
+func (l *Logger) AddSink(sink Sink) {
+	l.core.mu.Lock()
+	l.core.sinks = append(l.core.sinks[:len(l.core.sinks):len(l.core.sinks)], sink)
+	l.core.mu.Unlock()
+}
+
+// SetSinks replaces the sinks of the logger, its parents and its children, ex: to remove the default sink
+func (l *Logger) SetSinks(sinks ...Sink) {
+	l.core.mu.Lock()
+	l.core.sinks = append([]Sink(nil), sinks...)
+	l.core.mu.Unlock()
+}
+
+// Log writes a message with a level and key-value pairs
+func (l *Logger) Log(level Level, msg string, keyvals ...interface{}) {
+	l.log(level, msg, keyvals)
+}
+
+// Debug writes a message with LevelDebug, the keyvals are key-value pairs, ex: logger.Debug("cache miss", "key", key)
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(LevelDebug, msg, keyvals)
+}
+
+// Info writes a message with LevelInfo, the keyvals are key-value pairs, ex: logger.Info("user created", "id", 42)
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(LevelInfo, msg, keyvals)
+}
+
+// Warn writes a message with LevelWarn, the keyvals are key-value pairs
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(LevelWarn, msg, keyvals)
+}
+
+// Error writes a message with LevelError, the keyvals are key-value pairs, ex: logger.Error("payment failed", "err", err)
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(LevelError, msg, keyvals)
+}
+
+func (l *Logger) log(level Level, msg string, keyvals []interface{}) {
+	l.core.mu.RLock()
+	if level < l.core.level {
+		l.core.mu.RUnlock()
+		return
+	}
+	sinks := l.core.sinks
+	errorOut := l.core.errorOut
+	l.core.mu.RUnlock()
+
+	fields := l.fields
+	if len(keyvals) > 0 {
+		fields = appendFields(fields[:len(fields):len(fields)], keyvals)
+	}
+	r := &Record{Time: time.Now(), Level: level, Message: msg, Fields: fields}
+	for _, sink := range sinks {
+		if err := sink.Log(r); err != nil {
+			fmt.Fprintf(errorOut, "[Iris] Error on logger sink: %s\n", err.Error())
+		}
+	}
+}
+
+// writeLogfmtValue writes the value, it's quoted if it's empty or it has spaces, quotes, '=' or control characters
+func writeLogfmtValue(b *bytes.Buffer, value string) {
+	quote := value == ""
+	for _, r := range value {
+		if r <= ' ' || r == '"' || r == '=' || r == '\\' || r == 0x7f {
+			quote = true
+			break
+		}
+	}
+	if quote {
+		b.WriteString(strconv.Quote(value))
+		return
+	}
+	b.WriteString(value)
+}
+
+// formatRecord writes the level, the message and the fields as key=value pairs, without the time and the new line
+// ex: INFO user created id=42
+func formatRecord(b *bytes.Buffer, r *Record) {
+	b.WriteString(r.Level.String())
+	b.WriteByte(' ')
+	formatMessage(b, r)
+}
+
+// formatMessage writes the message and the fields as key=value pairs
+func formatMessage(b *bytes.Buffer, r *Record) {
+	b.WriteString(r.Message)
+	for _, f := range r.Fields {
+		b.WriteByte(' ')
+		b.WriteString(f.Key)
+		b.WriteByte('=')
+		writeLogfmtValue(b, fmt.Sprint(f.Value))
+	}
+}
+
+// WriterSink writes the records as lines to a writer, by a log.Logger
+type WriterSink struct {
+	log *log.Logger
+}
+
+var _ Sink = &WriterSink{}
+
+// NewWriterSink returns a sink which writes the records as lines to the out writer,
+// the prefix and the flag are these of the log.New, ex: [IRIS] 2016/06/01 10:00:00 INFO user created id=42
+func NewWriterSink(out io.Writer, prefix string, flag int) *WriterSink {
+	return &WriterSink{log: log.New(out, prefix, flag)}
+}
+
+// Log implements the Sink
+func (w *WriterSink) Log(r *Record) error {
+	var b bytes.Buffer
+	formatRecord(&b, r)
+	return w.log.Output(2, b.String())
 }
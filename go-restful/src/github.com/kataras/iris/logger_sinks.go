@@ -0,0 +1,317 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package iris
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var errSinkClosed = errors.New("the sink is closed")
+
+// FileSink writes the records as lines to a file, the file is rotated when it reaches its max size
+// ex: 2016-06-01T10:00:00.000000001Z INFO user created id=42
+type FileSink struct {
+	mu         sync.Mutex
+	filename   string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+	closed     bool
+}
+
+var _ Sink = &FileSink{}
+
+// NewFileSink opens (or creates) the file and returns a sink which appends the records to it,
+// when the file's size is going to pass the maxSize (bytes) it's renamed to filename.1, the filename.1 to filename.2 and so on
+// until the maxBackups, the older is removed, and a new file is created
+// the maxSize <= 0 means no rotation, the maxBackups <= 0 means the old lines are removed on rotation
+func NewFileSink(filename string, maxSize int64, maxBackups int) (*FileSink, error) {
+	f := &FileSink{filename: filename, maxSize: maxSize, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FileSink) open() error {
+	file, err := os.OpenFile(f.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("[Iris] Error on logger: can't open the log file %s: %s", f.filename, err.Error())
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// backup returns the name of the nth backup, ex: app.log.1
+func (f *FileSink) backup(n int) string {
+	return f.filename + "." + strconv.Itoa(n)
+}
+
+// rotate moves the file to the backups and opens a new one,
+// if it fails then the current file is reopened, so the next records are appended to it until the next rotation
+func (f *FileSink) rotate() error {
+	err := f.file.Close()
+	f.file = nil
+	if err == nil {
+		err = f.shift()
+	}
+	if oerr := f.open(); err == nil {
+		err = oerr
+	}
+	return err
+}
+
+// shift renames the file and its backups, the file is removed if there are no backups
+func (f *FileSink) shift() error {
+	if f.maxBackups > 0 {
+		os.Remove(f.backup(f.maxBackups))
+		for n := f.maxBackups - 1; n >= 1; n-- {
+			if err := os.Rename(f.backup(n), f.backup(n+1)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := os.Rename(f.filename, f.backup(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(f.filename); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Log implements the Sink
+func (f *FileSink) Log(r *Record) error {
+	var b bytes.Buffer
+	b.WriteString(r.Time.Format(time.RFC3339Nano))
+	b.WriteByte(' ')
+	formatRecord(&b, r)
+	b.WriteByte('\n')
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return errSinkClosed
+	}
+	// the file couldn't be reopened after a rotation
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+	var rotateErr error
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(b.Len()) > f.maxSize {
+		if rotateErr = f.rotate(); f.file == nil {
+			return rotateErr
+		}
+	}
+	n, err := f.file.Write(b.Bytes())
+	f.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	return err
+}
+
+// Close closes the file, the next records are not written
+func (f *FileSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// syslogFacilityUser is the facility of the messages, the user-level messages
+const syslogFacilityUser = 1
+
+// syslogSeverity are the severities of the levels, RFC 5424 6.2.1
+var syslogSeverity = map[Level]int{
+	LevelDebug: 7,
+	LevelInfo:  6,
+	LevelWarn:  4,
+	LevelError: 3,
+}
+
+// syslogSockets are the sockets of the local syslog daemon
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogSink writes the records to the syslog daemon, ex: <14>Jun  1 10:00:00 myapp[42]: user created id=42
+type SyslogSink struct {
+	mu      sync.Mutex
+	network string
+	addr    string
+	tag     string
+	conn    net.Conn
+}
+
+var _ Sink = &SyslogSink{}
+
+// NewSyslogSink connects to the syslog daemon and returns a sink which writes the records to it,
+// the network and the addr are these of the net.Dial, ex: "unixgram" and "/dev/log",
+// if they are empty it connects to the local syslog socket
+// the tag is the name of the application, the default is the name of the executable
+func NewSyslogSink(network, addr, tag string) (*SyslogSink, error) {
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+	s := &SyslogSink{network: network, addr: addr, tag: tag}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SyslogSink) connect() error {
+	if s.network != "" || s.addr != "" {
+		conn, err := net.Dial(s.network, s.addr)
+		if err != nil {
+			return fmt.Errorf("[Iris] Error on logger: can't connect to the syslog %s %s: %s", s.network, s.addr, err.Error())
+		}
+		s.conn = conn
+		return nil
+	}
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range syslogSockets {
+			if conn, err := net.Dial(network, path); err == nil {
+				s.conn = conn
+				return nil
+			}
+		}
+	}
+	return errors.New("[Iris] Error on logger: the local syslog socket is not found")
+}
+
+// Log implements the Sink
+func (s *SyslogSink) Log(r *Record) error {
+	severity, ok := syslogSeverity[r.Level]
+	if !ok {
+		severity = syslogSeverity[LevelError]
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>%s %s[%d]: ", syslogFacilityUser<<3|severity, r.Time.Format(time.Stamp), s.tag, os.Getpid())
+	formatMessage(&b, r)
+	b.WriteByte('\n')
+	msg := b.Bytes()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return errSinkClosed
+	}
+	if _, err := s.conn.Write(msg); err != nil {
+		// the daemon may be restarted, reconnect one time
+		s.conn.Close()
+		if cerr := s.connect(); cerr != nil {
+			s.conn = nil
+			return err
+		}
+		_, err = s.conn.Write(msg)
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection to the syslog, the next records are not written
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// RingSink keeps the last records in memory, ex: to check the logs on tests or to show them on a debug page
+type RingSink struct {
+	mu      sync.Mutex
+	records []Record
+	next    int
+	full    bool
+}
+
+var _ Sink = &RingSink{}
+
+// NewRingSink returns a sink which keeps the last size records
+func NewRingSink(size int) *RingSink {
+	if size <= 0 {
+		size = 1
+	}
+	return &RingSink{records: make([]Record, size)}
+}
+
+// Log implements the Sink
+func (s *RingSink) Log(r *Record) error {
+	s.mu.Lock()
+	s.records[s.next] = *r
+	s.next++
+	if s.next == len(s.records) {
+		s.next = 0
+		s.full = true
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// Records returns the kept records, the older first
+func (s *RingSink) Records() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.full {
+		return append([]Record(nil), s.records[:s.next]...)
+	}
+	return append(append(make([]Record, 0, len(s.records)), s.records[s.next:]...), s.records[:s.next]...)
+}
+
+// Reset removes the kept records
+func (s *RingSink) Reset() {
+	s.mu.Lock()
+	s.records = make([]Record, len(s.records))
+	s.next = 0
+	s.full = false
+	s.mu.Unlock()
+}
@@ -0,0 +1,270 @@
+package iris
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRecordFields(r Record) string {
+	var b bytes.Buffer
+	formatMessage(&b, &r)
+	return b.String()
+}
+
+func TestLoggerLevelsAndFields(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewLogger(&out, "app ", 0)
+	ring := NewRingSink(10)
+	logger.AddSink(ring)
+
+	logger.Debug("hidden")
+	logger.Info("user created", "id", 42, "name", "John Doe")
+	child := logger.With("component", "billing")
+	child.Warn("slow payment", "ms", 1200)
+	child.With("attempt", 2).Error("payment failed", "err", fmt.Errorf("declined"))
+	logger.Printf("plain %d", 1)
+	logger.Info("odd", "lonely")
+
+	logger.SetLevel(LevelDebug)
+	child.Debug("visible")
+	if !logger.Enabled(LevelDebug) || child.GetLevel() != LevelDebug {
+		t.Fatalf("expected the children to share the level")
+	}
+
+	expected := []struct {
+		level Level
+		line  string
+	}{
+		{LevelInfo, `user created id=42 name="John Doe"`},
+		{LevelWarn, `slow payment component=billing ms=1200`},
+		{LevelError, `payment failed component=billing attempt=2 err=declined`},
+		{LevelInfo, `plain 1`},
+		{LevelInfo, `odd !BADKEY=lonely`},
+		{LevelDebug, `visible component=billing`},
+	}
+	records := ring.Records()
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records but got %d: %v", len(expected), len(records), records)
+	}
+	for i, e := range expected {
+		if records[i].Level != e.level || testRecordFields(records[i]) != e.line {
+			t.Fatalf("expected record %d to be %s %q but got %s %q", i, e.level, e.line, records[i].Level, testRecordFields(records[i]))
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != len(expected) || lines[0] != `[IRIS] app INFO user created id=42 name="John Doe"` || lines[3] != "[IRIS] app INFO plain 1" {
+		t.Fatalf("unexpected lines of the writer sink %q", lines)
+	}
+
+	logger.SetSinks(ring)
+	ring.Reset()
+	out.Reset()
+	child.Info("only the ring")
+	if out.Len() != 0 || len(ring.Records()) != 1 {
+		t.Fatalf("expected the sinks to be replaced for the children too")
+	}
+}
+
+func TestLoggerRingSink(t *testing.T) {
+	ring := NewRingSink(3)
+	logger := NewLogger(nil, "", 0)
+	logger.SetSinks(ring)
+	for i := 1; i <= 5; i++ {
+		logger.Info(fmt.Sprintf("message %d", i))
+	}
+	records := ring.Records()
+	if len(records) != 3 || records[0].Message != "message 3" || records[2].Message != "message 5" {
+		t.Fatalf("expected the last 3 messages, the older first, but got %v", records)
+	}
+}
+
+func TestLoggerFileSinkRotation(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	sink, err := NewFileSink(filename, 100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := NewLogger(nil, "", 0)
+	logger.SetSinks(sink)
+	// each line is ~50 bytes, 2 lines per file
+	for i := 1; i <= 7; i++ {
+		logger.Info("message", "n", i)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) string {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	files := map[string][]string{
+		filename:        {"n=7"},
+		filename + ".1": {"n=5", "n=6"},
+		filename + ".2": {"n=3", "n=4"},
+	}
+	for name, contains := range files {
+		content := read(name)
+		if len(content) > 100 {
+			t.Fatalf("expected %s to be smaller than the max size but it's %d bytes", name, len(content))
+		}
+		for _, c := range contains {
+			if !strings.Contains(content, " INFO message "+c+"\n") {
+				t.Fatalf("expected %s to contain %s:\n%s", name, c, content)
+			}
+		}
+	}
+	if _, err := os.Stat(filename + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected only 2 backups")
+	}
+	if err := sink.Log(&Record{}); err != errSinkClosed {
+		t.Fatalf("expected the closed sink to return an error but got %v", err)
+	}
+}
+
+func TestLoggerFileSinkFailedRotation(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	sink, err := NewFileSink(filename, 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	record := func(n int) *Record {
+		return &Record{Time: time.Now(), Level: LevelInfo, Message: "message", Fields: []Field{{Key: "n", Value: n}}}
+	}
+	for n := 1; n <= 2; n++ {
+		if err = sink.Log(record(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the file can't be renamed to a directory which is not empty
+	backup := filename + ".1"
+	if err = os.MkdirAll(filepath.Join(backup, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for n := 3; n <= 4; n++ {
+		if err = sink.Log(record(n)); err == nil {
+			t.Fatalf("[%d] expected the error of the rotation", n)
+		}
+	}
+	if err = os.RemoveAll(backup); err != nil {
+		t.Fatal(err)
+	}
+	// the next rotation moves all the records of the failed ones
+	if err = sink.Log(record(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		contains []string
+	}{
+		{filename, []string{"n=5"}},
+		{backup, []string{"n=1", "n=2", "n=3", "n=4"}},
+	}
+	for i, tt := range tests {
+		data, err := os.ReadFile(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range tt.contains {
+			if !strings.Contains(string(data), " INFO message "+c+"\n") {
+				t.Fatalf("[%d] expected %s to contain %s:\n%s", i, tt.name, c, data)
+			}
+		}
+	}
+}
+
+func TestLoggerSyslogSink(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets are not supported: %v", err)
+	}
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("unixgram", socket, "myapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	logger := NewLogger(nil, "", 0)
+	logger.SetSinks(sink)
+	logger.Info("user created", "id", 42)
+	logger.Warn("disk almost full")
+
+	prefix := fmt.Sprintf(" myapp[%d]: ", os.Getpid())
+	for _, expected := range []struct{ pri, msg string }{{"<14>", "user created id=42\n"}, {"<12>", "disk almost full\n"}} {
+		buf := make([]byte, 1024)
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := string(buf[:n])
+		if !strings.HasPrefix(msg, expected.pri) || !strings.HasSuffix(msg, prefix+expected.msg) {
+			t.Fatalf("expected a syslog message %s...%s%s but got %q", expected.pri, prefix, expected.msg, msg)
+		}
+	}
+}
+
+// testLoggerPlugin is a plugin which logs from its Activate
+type testLoggerPlugin struct{}
+
+func (testLoggerPlugin) GetName() string        { return "testlogger" }
+func (testLoggerPlugin) GetDescription() string { return "logs" }
+func (testLoggerPlugin) Activate(container IPluginContainer) error {
+	container.GetLogger(testLoggerPlugin{}).Info("activated")
+	return nil
+}
+
+func TestLoggerScopes(t *testing.T) {
+	s := New()
+	ring := NewRingSink(10)
+	s.GetLogger().SetSinks(ring)
+
+	if err := s.Plugin(testLoggerPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	api := s.Party("/api")
+	api.Logger().Info("party")
+	api.Get("/users/:id", HandlerFunc(func(c *Context) {
+		c.Logger().Info("user loaded", "id", c.Param("id"))
+		if c.Logger() != c.Logger() {
+			t.Fatalf("expected one logger per request")
+		}
+	}))
+	handler := s.Serve()
+	req := httptest.NewRequest("GET", "/api/users/42", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/users/7", nil))
+
+	expected := []string{
+		"activated plugin=testlogger",
+		"party party=/api",
+		"user loaded request_id=req-1 method=GET path=/api/users/42 id=42",
+		"user loaded method=GET path=/api/users/7 id=7",
+	}
+	records := ring.Records()
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records but got %v", len(expected), records)
+	}
+	for i, e := range expected {
+		if got := testRecordFields(records[i]); got != e {
+			t.Fatalf("expected record %d to be %q but got %q", i, e, got)
+		}
+	}
+}
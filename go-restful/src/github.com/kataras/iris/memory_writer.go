@@ -45,6 +45,7 @@ type IMemoryWriter interface {
 	http.ResponseWriter
 	http.Hijacker
 	http.CloseNotifier
+	http.Pusher
 	Reset(underlineRes http.ResponseWriter)
 	WriteString(s string) (int, error)
 	//implement http response writer
@@ -65,6 +66,8 @@ type MemoryWriter struct {
 	http.ResponseWriter
 	size   int
 	status int
+	// hijacked keeps the hijacked connection if the standalone server is running, in order to wait for it on shutdown
+	hijacked *hijackedConns
 }
 
 var _ IMemoryWriter = &MemoryWriter{}
@@ -134,8 +137,9 @@ func (m *MemoryWriter) Size() int {
 	return m.size
 }
 
-// Flush flushes the contents of the writer
+// Flush flushes the contents of the writer, the headers are sent too
 func (m *MemoryWriter) Flush() {
+	m.ForceHeader()
 	flusher, done := m.ResponseWriter.(http.Flusher)
 	if done {
 		flusher.Flush()
@@ -147,10 +151,25 @@ func (m *MemoryWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if m.size == -1 {
 		m.size = 0
 	}
-	return m.ResponseWriter.(http.Hijacker).Hijack()
+	conn, buf, err := m.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil && m.hijacked != nil {
+		conn = m.hijacked.add(conn)
+	}
+	return conn, buf, err
 }
 
-// CloseNotify look inside net/http package
+// CloseNotify look inside net/http package, it returns a nil channel if the underline http.ResponseWriter isn't an http.CloseNotifier
 func (m *MemoryWriter) CloseNotify() <-chan bool {
-	return m.ResponseWriter.(http.CloseNotifier).CloseNotify()
+	if notifier, ok := m.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return nil
+}
+
+// Push look inside net/http package, it returns http.ErrNotSupported if the underline http.ResponseWriter isn't an http.Pusher (HTTP/1.x)
+func (m *MemoryWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := m.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
 }
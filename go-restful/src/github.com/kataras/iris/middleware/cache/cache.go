@@ -0,0 +1,504 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kataras/iris"
+)
+
+const (
+	// DefaultTTL is the default duration which a response is cached
+	DefaultTTL = 5 * time.Minute
+	// DefaultMaxBodySize is the default maximum size of a cached response's body
+	DefaultMaxBodySize = 1 << 20
+
+	headerAge             = "Age"
+	headerAuthorization   = "Authorization"
+	headerCacheControl    = "Cache-Control"
+	headerContentLength   = "Content-Length"
+	headerContentType     = "Content-Type"
+	headerETag            = "ETag"
+	headerIfNoneMatch     = "If-None-Match"
+	headerSecWebSocketKey = "Sec-WebSocket-Key"
+	headerSetCookie       = "Set-Cookie"
+	headerVary            = "Vary"
+)
+
+// cacheableStatus are the status codes which are cacheable by default, RFC 7231 6.1
+var cacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// Options are the options of the cache middleware
+type Options struct {
+	// TTL is the duration which a response is cached, when the response has no Cache-Control max-age or s-maxage
+	// Default is 5 minutes
+	TTL time.Duration
+	// MaxEntries is the maximum number of the cached responses, the least recently used is removed when it's reached
+	// Default is 0, no limit
+	MaxEntries int
+	// MaxBodySize is the maximum size of a cached response's body, bigger responses are served but they are not cached
+	// Default is 1MB
+	MaxBodySize int
+}
+
+// entry is a cached response
+type entry struct {
+	key        string
+	element    *list.Element // the element of the entry in the ResponseCache's order
+	status     int
+	header     http.Header
+	body       []byte
+	etag       string
+	vary       []string
+	varyValues []string
+	created    time.Time
+	expires    time.Time
+}
+
+// matches returns true if the request has the same values of the Vary headers as the request of this response
+func (e *entry) matches(req *http.Request) bool {
+	for i, name := range e.vary {
+		if strings.Join(req.Header.Values(name), ",") != e.varyValues[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ResponseCache is the cache middleware, it keeps the responses in memory, see New
+type ResponseCache struct {
+	options Options
+
+	mu        sync.Mutex
+	entries   map[string][]*entry // the variants of each url
+	order     *list.List          // the front is the most recently used
+	lastPrune time.Time
+}
+
+// New creates the cache middleware and returns it, use it on a route or on a party
+// it caches the responses of the GET requests, the HEAD requests are served from the same responses
+//
+// the responses are cached only if they have a cacheable status code and they have no Set-Cookie,
+// the Cache-Control of the response (no-store, private, no-cache, max-age, s-maxage) and of the request (no-cache, no-store) are respected,
+// the different values of the Vary headers are cached as different responses
+// and an ETag is set, if the handler hasn't set one, in order to answer the If-None-Match requests with 304 Not Modified
+func New(options Options) *ResponseCache {
+	if options.TTL <= 0 {
+		options.TTL = DefaultTTL
+	}
+	if options.MaxBodySize <= 0 {
+		options.MaxBodySize = DefaultMaxBodySize
+	}
+	return &ResponseCache{options: options, entries: make(map[string][]*entry), order: list.New(), lastPrune: time.Now()}
+}
+
+// Cache creates the cache middleware with a TTL and returns it, see New
+func Cache(ttl time.Duration) *ResponseCache {
+	return New(Options{TTL: ttl})
+}
+
+// Clear removes all the cached responses
+func (c *ResponseCache) Clear() {
+	c.mu.Lock()
+	c.entries = make(map[string][]*entry)
+	c.order.Init()
+	c.mu.Unlock()
+}
+
+// Serve serves the cached response or it executes the next handlers and caches their response
+func (c *ResponseCache) Serve(ctx *iris.Context) {
+	req := ctx.Request
+	if (req.Method != http.MethodGet && req.Method != http.MethodHead) || req.Header.Get(headerAuthorization) != "" || req.Header.Get(headerSecWebSocketKey) != "" {
+		ctx.Next()
+		return
+	}
+
+	requestCacheControl := parseCacheControl(req.Header.Get(headerCacheControl))
+	key := req.Host + req.URL.RequestURI()
+	now := time.Now()
+
+	if _, noCache := requestCacheControl["no-cache"]; !noCache {
+		if e := c.get(key, req, now); e != nil {
+			c.serve(ctx, e, now)
+			return
+		}
+	}
+
+	if req.Method == http.MethodHead {
+		ctx.Next()
+		return
+	}
+
+	res := ctx.ResponseWriter
+	rec := &recorder{IMemoryWriter: res, status: http.StatusOK, maxBodySize: c.options.MaxBodySize}
+	ctx.ResponseWriter = rec
+	ctx.Next()
+	ctx.ResponseWriter = res
+
+	if rec.passthrough {
+		return
+	}
+
+	header := res.Header()
+	ttl, cacheable := c.ttl(rec.status, header, requestCacheControl)
+	if !cacheable {
+		res.WriteHeader(rec.status)
+		if rec.body.Len() > 0 {
+			res.Write(rec.body.Bytes())
+		}
+		return
+	}
+
+	e := &entry{
+		key:     key,
+		status:  rec.status,
+		header:  header.Clone(),
+		body:    append([]byte(nil), rec.body.Bytes()...),
+		etag:    header.Get(headerETag),
+		created: now,
+		expires: now.Add(ttl),
+	}
+	if e.etag == "" {
+		hash := fnv.New64a()
+		hash.Write(e.body)
+		e.etag = `"` + strconv.FormatUint(hash.Sum64(), 36) + `"`
+		e.header.Set(headerETag, e.etag)
+	}
+	for _, value := range header.Values(headerVary) {
+		for _, name := range strings.Split(value, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" {
+				e.vary = append(e.vary, name)
+				e.varyValues = append(e.varyValues, strings.Join(req.Header.Values(name), ","))
+			}
+		}
+	}
+	c.set(key, e, now)
+
+	header.Set(headerETag, e.etag)
+	c.write(ctx, e)
+}
+
+// ttl returns the duration which the response can be cached and false if it's not cacheable
+func (c *ResponseCache) ttl(status int, header http.Header, requestCacheControl map[string]string) (time.Duration, bool) {
+	if !cacheableStatus[status] || len(header.Values(headerSetCookie)) > 0 || header.Get(headerVary) == "*" {
+		return 0, false
+	}
+	if _, noStore := requestCacheControl["no-store"]; noStore {
+		return 0, false
+	}
+
+	cacheControl := parseCacheControl(header.Get(headerCacheControl))
+	for _, directive := range []string{"no-store", "no-cache", "private"} {
+		if _, found := cacheControl[directive]; found {
+			return 0, false
+		}
+	}
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if value, found := cacheControl[directive]; found {
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds <= 0 {
+				return 0, false
+			}
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	return c.options.TTL, true
+}
+
+// serve serves a cached response
+func (c *ResponseCache) serve(ctx *iris.Context, e *entry, now time.Time) {
+	header := ctx.ResponseWriter.Header()
+	for k, v := range e.header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set(headerAge, strconv.Itoa(int(now.Sub(e.created)/time.Second)))
+	c.write(ctx, e)
+}
+
+// write writes the response, or 304 Not Modified if the request's If-None-Match matches the ETag
+func (c *ResponseCache) write(ctx *iris.Context, e *entry) {
+	res := ctx.ResponseWriter
+	if etagMatches(ctx.Request.Header.Get(headerIfNoneMatch), e.etag) {
+		res.Header().Del(headerContentLength)
+		res.Header().Del(headerContentType)
+		res.WriteHeader(http.StatusNotModified)
+		res.ForceHeader()
+		return
+	}
+
+	res.Header().Set(headerContentLength, strconv.Itoa(len(e.body)))
+	res.WriteHeader(e.status)
+	if ctx.Request.Method != http.MethodHead && len(e.body) > 0 {
+		res.Write(e.body)
+	} else {
+		res.ForceHeader()
+	}
+}
+
+// get returns the cached response of the key which matches the request, if it's not expired
+func (c *ResponseCache) get(key string, req *http.Request, now time.Time) *entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.entries[key] {
+		if now.Before(e.expires) && e.matches(req) {
+			c.order.MoveToFront(e.element)
+			return e
+		}
+	}
+	return nil
+}
+
+// set caches a response, it replaces the previous response of the same variant
+func (c *ResponseCache) set(key string, e *entry, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	variants := c.entries[key]
+	replaced := false
+	for i, old := range variants {
+		if equalStrings(old.vary, e.vary) && equalStrings(old.varyValues, e.varyValues) {
+			c.order.Remove(old.element)
+			variants[i] = e
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		c.entries[key] = append(variants, e)
+	}
+	e.element = c.order.PushFront(e)
+
+	if now.Sub(c.lastPrune) > c.options.TTL || (c.options.MaxEntries > 0 && c.order.Len() > c.options.MaxEntries) {
+		c.prune(now)
+	}
+	for c.options.MaxEntries > 0 && c.order.Len() > c.options.MaxEntries {
+		c.remove(c.order.Back().Value.(*entry))
+	}
+}
+
+// prune removes the expired responses, c.mu should be locked
+func (c *ResponseCache) prune(now time.Time) {
+	c.lastPrune = now
+	for key, variants := range c.entries {
+		alive := variants[:0]
+		for _, e := range variants {
+			if now.Before(e.expires) {
+				alive = append(alive, e)
+			} else {
+				c.order.Remove(e.element)
+			}
+		}
+		if len(alive) == 0 {
+			delete(c.entries, key)
+		} else {
+			c.entries[key] = alive
+		}
+	}
+}
+
+// remove removes a cached response, c.mu should be locked
+func (c *ResponseCache) remove(e *entry) {
+	c.order.Remove(e.element)
+	variants := c.entries[e.key]
+	if len(variants) == 1 {
+		delete(c.entries, e.key)
+		return
+	}
+	for i, variant := range variants {
+		if variant == e {
+			c.entries[e.key] = append(variants[:i:i], variants[i+1:]...)
+			return
+		}
+	}
+}
+
+// recorder keeps the response in memory instead of writing it, in order to cache it
+// if the handler flushes or hijacks the connection then the response is written directly and it's not cached
+type recorder struct {
+	iris.IMemoryWriter
+	status      int
+	written     bool
+	body        bytes.Buffer
+	maxBodySize int
+	passthrough bool
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	if r.passthrough {
+		return r.IMemoryWriter.Write(data)
+	}
+	r.written = true
+	if r.body.Len()+len(data) > r.maxBodySize {
+		r.flushRecorded()
+		return r.IMemoryWriter.Write(data)
+	}
+	return r.body.Write(data)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	return r.Write([]byte(s))
+}
+
+func (r *recorder) WriteHeader(statusCode int) {
+	if r.passthrough {
+		r.IMemoryWriter.WriteHeader(statusCode)
+		return
+	}
+	if statusCode > 0 && !r.written {
+		r.status = statusCode
+	}
+}
+
+func (r *recorder) Status() int {
+	if r.passthrough {
+		return r.IMemoryWriter.Status()
+	}
+	return r.status
+}
+
+func (r *recorder) Size() int {
+	if r.passthrough {
+		return r.IMemoryWriter.Size()
+	}
+	if !r.written {
+		return -1
+	}
+	return r.body.Len()
+}
+
+func (r *recorder) IsWritten() bool {
+	if r.passthrough {
+		return r.IMemoryWriter.IsWritten()
+	}
+	return r.written
+}
+
+func (r *recorder) ForceHeader() {
+	if r.passthrough {
+		r.IMemoryWriter.ForceHeader()
+		return
+	}
+	r.written = true
+}
+
+// Flush writes the recorded response and the rest of the response is written directly, for streaming
+func (r *recorder) Flush() {
+	r.flushRecorded()
+	if flusher, ok := r.IMemoryWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack stops the recording and hijacks the connection, the response is not cached
+func (r *recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if r.written {
+		r.flushRecorded()
+	} else {
+		r.passthrough = true
+	}
+	return r.IMemoryWriter.Hijack()
+}
+
+// flushRecorded writes the recorded response and stops the recording
+func (r *recorder) flushRecorded() {
+	if r.passthrough {
+		return
+	}
+	r.passthrough = true
+	r.IMemoryWriter.WriteHeader(r.status)
+	if r.body.Len() > 0 {
+		r.IMemoryWriter.Write(r.body.Bytes())
+	} else if r.written {
+		r.IMemoryWriter.ForceHeader()
+	}
+	r.body.Reset()
+}
+
+// parseCacheControl parses the directives of a Cache-Control header, ex: max-age=60, no-cache
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, arg := part, ""
+		if idx := strings.IndexByte(part, '='); idx != -1 {
+			name, arg = part[:idx], strings.Trim(part[idx+1:], `"`)
+		}
+		directives[strings.ToLower(name)] = arg
+	}
+	return directives
+}
+
+// etagMatches returns true if the If-None-Match header matches the etag, with the weak comparison
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,224 @@
+package cache
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kataras/iris"
+)
+
+// testCacheStation returns a station with cached routes and a pointer to the number of the executed handlers
+func testCacheStation(options Options) (http.Handler, *int) {
+	calls := 0
+	s := iris.New()
+	cached := s.Party("/cached")
+	cached.Use(New(options))
+	hello := iris.HandlerFunc(func(c *iris.Context) {
+		calls++
+		c.Write("hello %d", calls)
+	})
+	cached.Get("/hello", hello)
+	cached.Head("/hello", hello)
+	cached.Get("/lang", iris.HandlerFunc(func(c *iris.Context) {
+		calls++
+		c.SetHeader("Vary", []string{"Accept-Language"})
+		c.Write("%s %d", c.Request.Header.Get("Accept-Language"), calls)
+	}))
+	cached.Get("/private", iris.HandlerFunc(func(c *iris.Context) {
+		calls++
+		c.SetHeader("Cache-Control", []string{"private"})
+		c.Write("private %d", calls)
+	}))
+	cached.Get("/maxage", iris.HandlerFunc(func(c *iris.Context) {
+		calls++
+		c.SetHeader("Cache-Control", []string{"max-age=1"})
+		c.Write("maxage %d", calls)
+	}))
+	cached.Get("/created", iris.HandlerFunc(func(c *iris.Context) {
+		calls++
+		c.WriteStatus(http.StatusCreated)
+		c.Write("created %d", calls)
+	}))
+	cached.Get("/stream", iris.HandlerFunc(func(c *iris.Context) {
+		calls++
+		c.Write("stream %d", calls)
+		c.ResponseWriter.(http.Flusher).Flush()
+	}))
+	return s.Serve(), &calls
+}
+
+func testCacheRequest(t *testing.T, handler http.Handler, method, path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for i := 0; i < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	return res
+}
+
+func TestCache(t *testing.T) {
+	handler, calls := testCacheStation(Options{})
+
+	first := testCacheRequest(t, handler, "GET", "/cached/hello")
+	second := testCacheRequest(t, handler, "GET", "/cached/hello")
+	if *calls != 1 || second.Body.String() != "hello 1" || second.Body.String() != first.Body.String() {
+		t.Fatalf("Expecting the cached response but got %q after %d calls", second.Body.String(), *calls)
+	}
+	if second.Header().Get("Age") == "" || second.Header().Get("Content-Length") != "7" {
+		t.Fatalf("Expecting the Age and Content-Length headers but got %v", second.Header())
+	}
+
+	head := testCacheRequest(t, handler, "HEAD", "/cached/hello")
+	if *calls != 1 || head.Body.Len() != 0 || head.Header().Get("Content-Length") != "7" {
+		t.Fatalf("Expecting the HEAD to be served from the cache without body")
+	}
+
+	// the query is part of the key
+	testCacheRequest(t, handler, "GET", "/cached/hello?a=1")
+	if *calls != 2 {
+		t.Fatalf("Expecting a different query to be a different response")
+	}
+
+	// the request's no-cache refreshes the response
+	refreshed := testCacheRequest(t, handler, "GET", "/cached/hello", "Cache-Control", "no-cache")
+	if *calls != 3 || refreshed.Body.String() != "hello 3" {
+		t.Fatalf("Expecting the no-cache request to execute the handler")
+	}
+	if res := testCacheRequest(t, handler, "GET", "/cached/hello"); res.Body.String() != "hello 3" {
+		t.Fatalf("Expecting the refreshed response to be cached but got %q", res.Body.String())
+	}
+
+	// the Authorization requests are never cached
+	testCacheRequest(t, handler, "GET", "/cached/hello", "Authorization", "Basic a2F0YXJhczox")
+	if *calls != 4 {
+		t.Fatalf("Expecting the request with Authorization to execute the handler")
+	}
+}
+
+func TestCacheETag(t *testing.T) {
+	handler, calls := testCacheStation(Options{})
+
+	first := testCacheRequest(t, handler, "GET", "/cached/hello")
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expecting an ETag")
+	}
+
+	for _, ifNoneMatch := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		res := testCacheRequest(t, handler, "GET", "/cached/hello", "If-None-Match", ifNoneMatch)
+		if res.Code != http.StatusNotModified || res.Body.Len() != 0 || res.Header().Get("ETag") != etag {
+			t.Fatalf("Expecting 304 for If-None-Match: %s but got %d %q", ifNoneMatch, res.Code, res.Body.String())
+		}
+	}
+	if res := testCacheRequest(t, handler, "GET", "/cached/hello", "If-None-Match", `"other"`); res.Code != http.StatusOK {
+		t.Fatalf("Expecting 200 for a different ETag but got %d", res.Code)
+	}
+	if *calls != 1 {
+		t.Fatalf("Expecting the handler to be executed once but executed %d times", *calls)
+	}
+}
+
+func TestCacheVary(t *testing.T) {
+	handler, calls := testCacheStation(Options{})
+
+	for i, lang := range []string{"en", "el", "en", "el"} {
+		res := testCacheRequest(t, handler, "GET", "/cached/lang", "Accept-Language", lang)
+		if expected := lang + " " + strconv.Itoa(i%2+1); res.Body.String() != expected {
+			t.Fatalf("Expecting %q but got %q", expected, res.Body.String())
+		}
+	}
+	if *calls != 2 {
+		t.Fatalf("Expecting a response for each language but the handler executed %d times", *calls)
+	}
+}
+
+func TestCacheNotCacheable(t *testing.T) {
+	handler, calls := testCacheStation(Options{})
+
+	for _, path := range []string{"/cached/private", "/cached/created", "/cached/stream"} {
+		*calls = 0
+		testCacheRequest(t, handler, "GET", path)
+		res := testCacheRequest(t, handler, "GET", path)
+		if *calls != 2 {
+			t.Fatalf("Expecting %s to not be cached", path)
+		}
+		if path == "/cached/created" && res.Code != http.StatusCreated {
+			t.Fatalf("Expecting the status of the handler but got %d", res.Code)
+		}
+		if path == "/cached/stream" && !res.Flushed {
+			t.Fatal("Expecting the stream to be flushed")
+		}
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	handler, calls := testCacheStation(Options{TTL: time.Hour})
+
+	testCacheRequest(t, handler, "GET", "/cached/maxage")
+	testCacheRequest(t, handler, "GET", "/cached/maxage")
+	if *calls != 1 {
+		t.Fatal("Expecting the max-age response to be cached")
+	}
+	time.Sleep(1100 * time.Millisecond)
+	testCacheRequest(t, handler, "GET", "/cached/maxage")
+	if *calls != 2 {
+		t.Fatal("Expecting the max-age of the response to override the TTL")
+	}
+}
+
+func TestCacheMaxEntries(t *testing.T) {
+	handler, calls := testCacheStation(Options{MaxEntries: 2})
+
+	for _, path := range []string{"/cached/hello?1", "/cached/hello?2", "/cached/hello?3", "/cached/hello?3", "/cached/hello?2", "/cached/hello?1"} {
+		testCacheRequest(t, handler, "GET", path)
+	}
+	// the ?1 is removed when ?3 is cached
+	if *calls != 4 {
+		t.Fatalf("Expecting the oldest response to be removed but the handler executed %d times", *calls)
+	}
+	// the ?3 is the least recently used when ?1 is cached again, the ?2 is kept
+	testCacheRequest(t, handler, "GET", "/cached/hello?2")
+	if *calls != 4 {
+		t.Fatalf("Expecting the recently used response to be kept but the handler executed %d times", *calls)
+	}
+	testCacheRequest(t, handler, "GET", "/cached/hello?3")
+	if *calls != 5 {
+		t.Fatalf("Expecting the least recently used response to be removed but the handler executed %d times", *calls)
+	}
+}
+
+func TestCacheHijack(t *testing.T) {
+	calls := 0
+	s := iris.New()
+	s.Use(New(Options{}))
+	s.Get("/hijack", iris.HandlerFunc(func(c *iris.Context) {
+		calls++
+		conn, buf, err := c.ResponseWriter.Hijack()
+		if err != nil {
+			t.Errorf("Expecting the connection to be hijacked: %v", err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		buf.Flush()
+	}))
+	server := httptest.NewServer(s.Serve())
+	defer server.Close()
+
+	for i := 1; i <= 2; i++ {
+		res, err := http.Get(server.URL + "/hijack")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if string(body) != "hijacked" || calls != i {
+			t.Fatalf("Expecting the hijacked response to not be cached but got %q after %d calls", body, calls)
+		}
+	}
+}
@@ -72,6 +72,19 @@ func (res responseWriter) Write(b []byte) (int, error) {
 	return res.gzipWriter.Write(b)
 }
 
+// Flush writes the compressed data to the client, used by the streaming responses (Context.SSE, Context.Stream)
+func (res responseWriter) Flush() {
+	res.gzipWriter.Flush()
+	if flusher, ok := res.IMemoryWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// WriteString writes to the gzipWriter too
+func (res responseWriter) WriteString(s string) (int, error) {
+	return res.Write([]byte(s))
+}
+
 // Gzip creates the middleware and returns it, for direct Use
 // parameter compLevel value between BestSpeed and BestCompression inclusive.
 // check https://golang.org/src/compress/gzip/gzip.go
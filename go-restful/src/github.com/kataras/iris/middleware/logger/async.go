@@ -0,0 +1,111 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package logger
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBufferSize is the default number of the log lines which wait to be written by the Async logger
+const DefaultBufferSize = 1024
+
+// asyncWriter writes the lines from a goroutine through a bufio.Writer,
+// it's flushed every time there are no more lines to write, so the lines are written by batches when there are many requests
+type asyncWriter struct {
+	out     *bufio.Writer
+	lines   chan []byte
+	done    chan struct{}
+	dropped uint64
+
+	mu     sync.RWMutex
+	closed bool
+	err    error
+}
+
+func newAsyncWriter(out io.Writer, size int) *asyncWriter {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	w := &asyncWriter{
+		out:   bufio.NewWriter(out),
+		lines: make(chan []byte, size),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *asyncWriter) run() {
+	for line := range w.lines {
+		w.out.Write(line)
+		if len(w.lines) == 0 {
+			w.out.Flush()
+		}
+	}
+	w.err = w.out.Flush()
+	close(w.done)
+}
+
+// Write queues a copy of the line, the line is dropped if the queue is full or the writer is closed
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		atomic.AddUint64(&w.dropped, 1)
+		return len(p), nil
+	}
+	select {
+	case w.lines <- line:
+	default:
+		atomic.AddUint64(&w.dropped, 1)
+	}
+	w.mu.RUnlock()
+	return len(p), nil
+}
+
+// Dropped returns the number of the dropped lines
+func (w *asyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Close writes the queued lines and stops the goroutine, it returns the error of the last flush
+func (w *asyncWriter) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.lines)
+	}
+	w.mu.Unlock()
+	<-w.done
+	return w.err
+}
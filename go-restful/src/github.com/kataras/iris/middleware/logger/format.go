@@ -0,0 +1,235 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kataras/iris"
+)
+
+// Format is the format of the log lines, see Options.Format
+type Format int
+
+const (
+	// FormatText is the line of the Latency, Status, IP, Method and Path options, written by the iris.Logger
+	FormatText Format = iota
+	// FormatJSON writes each request as a JSON object,
+	// ex: {"time":"2016-06-01T10:00:00Z","status":200,"method":"GET","path":"/users/42","route":"/users/:id","ip":"127.0.0.1","latency_ms":0.25,"bytes":12}
+	FormatJSON
+	// FormatLogfmt writes each request as key=value pairs,
+	// ex: time=2016-06-01T10:00:00Z status=200 method=GET path=/users/42 route=/users/:id ip=127.0.0.1 latency=250µs bytes=12
+	FormatLogfmt
+	// FormatCombined writes each request in the Apache combined log format,
+	// ex: 127.0.0.1 - - [01/Jun/2016:10:00:00 +0000] "GET /users/42 HTTP/1.1" 200 12 "-" "curl/7.47.0"
+	FormatCombined
+)
+
+// Field is a key and a value of the context which is logged, see Options.Values
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Entry is the information of a request which is logged
+type Entry struct {
+	Time    time.Time
+	Latency time.Duration
+	Status  int
+	Method  string
+	Path    string
+	// Query is the raw query of the url, without the '?'
+	Query string
+	// Route is the registed path of the route, ex: /users/:id
+	Route     string
+	Proto     string
+	IP        string
+	Bytes     int
+	RequestID string
+	// Trace is the W3C trace context of the request, the trace_id and the span_id are logged if it's valid
+	Trace     iris.TraceContext
+	UserAgent string
+	Referer   string
+	Values    []Field
+}
+
+// formatter writes an entry to the buffer, without the new line
+type formatter func(*bytes.Buffer, *Entry)
+
+// formatters by Format, the FormatText has no formatter, it's written by the iris.Logger
+var formatters = map[Format]formatter{
+	FormatJSON:     formatJSON,
+	FormatLogfmt:   formatLogfmt,
+	FormatCombined: formatCombined,
+}
+
+func writeJSONValue(b *bytes.Buffer, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	b.Write(data)
+}
+
+func writeJSONField(b *bytes.Buffer, key string, v interface{}) {
+	b.WriteByte(',')
+	writeJSONValue(b, key)
+	b.WriteByte(':')
+	writeJSONValue(b, v)
+}
+
+func formatJSON(b *bytes.Buffer, e *Entry) {
+	b.WriteString(`{"time":`)
+	writeJSONValue(b, e.Time.Format(time.RFC3339Nano))
+	b.WriteString(`,"status":`)
+	b.WriteString(strconv.Itoa(e.Status))
+	writeJSONField(b, "method", e.Method)
+	writeJSONField(b, "path", e.Path)
+	if e.Query != "" {
+		writeJSONField(b, "query", e.Query)
+	}
+	if e.Route != "" {
+		writeJSONField(b, "route", e.Route)
+	}
+	writeJSONField(b, "proto", e.Proto)
+	writeJSONField(b, "ip", e.IP)
+	b.WriteString(`,"latency_ms":`)
+	b.WriteString(strconv.FormatFloat(float64(e.Latency)/float64(time.Millisecond), 'f', -1, 64))
+	b.WriteString(`,"bytes":`)
+	b.WriteString(strconv.Itoa(e.Bytes))
+	if e.RequestID != "" {
+		writeJSONField(b, "request_id", e.RequestID)
+	}
+	if e.Trace.IsValid() {
+		writeJSONField(b, "trace_id", e.Trace.TraceID.String())
+		writeJSONField(b, "span_id", e.Trace.SpanID.String())
+	}
+	if e.UserAgent != "" {
+		writeJSONField(b, "user_agent", e.UserAgent)
+	}
+	if e.Referer != "" {
+		writeJSONField(b, "referer", e.Referer)
+	}
+	for _, f := range e.Values {
+		writeJSONField(b, f.Key, f.Value)
+	}
+	b.WriteByte('}')
+}
+
+// writeLogfmtField writes the key=value, the value is quoted if it's empty or it has spaces, quotes, '=' or control characters
+func writeLogfmtField(b *bytes.Buffer, key string, value string) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(key)
+	b.WriteByte('=')
+	quote := value == ""
+	for _, r := range value {
+		if r <= ' ' || r == '"' || r == '=' || r == '\\' || r == 0x7f {
+			quote = true
+			break
+		}
+	}
+	if quote {
+		b.WriteString(strconv.Quote(value))
+		return
+	}
+	b.WriteString(value)
+}
+
+func formatLogfmt(b *bytes.Buffer, e *Entry) {
+	writeLogfmtField(b, "time", e.Time.Format(time.RFC3339Nano))
+	writeLogfmtField(b, "status", strconv.Itoa(e.Status))
+	writeLogfmtField(b, "method", e.Method)
+	writeLogfmtField(b, "path", e.Path)
+	if e.Query != "" {
+		writeLogfmtField(b, "query", e.Query)
+	}
+	if e.Route != "" {
+		writeLogfmtField(b, "route", e.Route)
+	}
+	writeLogfmtField(b, "proto", e.Proto)
+	writeLogfmtField(b, "ip", e.IP)
+	writeLogfmtField(b, "latency", e.Latency.String())
+	writeLogfmtField(b, "bytes", strconv.Itoa(e.Bytes))
+	if e.RequestID != "" {
+		writeLogfmtField(b, "request_id", e.RequestID)
+	}
+	if e.Trace.IsValid() {
+		writeLogfmtField(b, "trace_id", e.Trace.TraceID.String())
+		writeLogfmtField(b, "span_id", e.Trace.SpanID.String())
+	}
+	if e.UserAgent != "" {
+		writeLogfmtField(b, "user_agent", e.UserAgent)
+	}
+	if e.Referer != "" {
+		writeLogfmtField(b, "referer", e.Referer)
+	}
+	for _, f := range e.Values {
+		writeLogfmtField(b, f.Key, fmt.Sprint(f.Value))
+	}
+}
+
+// combinedValue returns the "-" for the empty values
+func combinedValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func formatCombined(b *bytes.Buffer, e *Entry) {
+	b.WriteString(combinedValue(e.IP))
+	b.WriteString(" - - [")
+	b.WriteString(e.Time.Format("02/Jan/2006:15:04:05 -0700"))
+	b.WriteString(`] "`)
+	b.WriteString(e.Method)
+	b.WriteByte(' ')
+	b.WriteString(e.Path)
+	if e.Query != "" {
+		b.WriteByte('?')
+		b.WriteString(e.Query)
+	}
+	b.WriteByte(' ')
+	b.WriteString(e.Proto)
+	b.WriteString(`" `)
+	b.WriteString(strconv.Itoa(e.Status))
+	b.WriteByte(' ')
+	if e.Bytes > 0 {
+		b.WriteString(strconv.Itoa(e.Bytes))
+	} else {
+		b.WriteByte('-')
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.Quote(combinedValue(e.Referer)))
+	b.WriteByte(' ')
+	b.WriteString(strconv.Quote(combinedValue(e.UserAgent)))
+}
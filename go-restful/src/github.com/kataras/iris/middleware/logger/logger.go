@@ -27,16 +27,21 @@
 package logger
 
 import (
+	"bytes"
 	"io"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kataras/iris"
 )
 
+// DefaultRequestIDHeader is the header of the response which the request id is read from, if the ctx.RequestID is empty
+const DefaultRequestIDHeader = iris.RequestIDHeader
+
 // Options are the options of the logger middlweare
-// contains 5 bools
-// Latency, Status, IP, Method, Path
+// the Latency, Status, IP, Method and Path are used by the FormatText,
 // if set to true then these will print
 type Options struct {
 	Latency bool
@@ -44,32 +49,67 @@ type Options struct {
 	IP      bool
 	Method  bool
 	Path    bool
+
+	// Format is the format of the log lines, FormatText, FormatJSON, FormatLogfmt or FormatCombined
+	// Default is FormatText
+	Format Format
+	// Skip are the paths which are not logged, the request's path or the route's registed path, ex: /health
+	Skip []string
+	// Sampling is the rate of the logged requests per route (the registed path), from 0 (none) to 1 (all),
+	// ex: {"/users/:id": 0.1} logs every tenth request of this route
+	// the routes which are not here and the responses with status code >= 500 are logged always
+	Sampling map[string]float64
+	// Values are the keys of the context's values (ctx.Set) which are logged, used by the FormatJSON and FormatLogfmt
+	Values []string
+	// RequestID returns the request id of the log line
+	// Default is the ctx.RequestID, or the DefaultRequestIDHeader of the response if it's empty
+	RequestID func(*iris.Context) string
+	// Async writes the log lines from a goroutine through a buffered writer, so the requests don't wait for the writes,
+	// call the logger's Close to flush the lines
+	// Default is false
+	Async bool
+	// BufferSize is the number of the log lines which wait to be written when Async, the next lines are dropped, see AccessLogger.Dropped
+	// Default is 1024
+	BufferSize int
 }
 
 // DefaultOptions returns an options which all properties are true
 func DefaultOptions() Options {
-	return Options{true, true, true, true, true}
+	return Options{Latency: true, Status: true, IP: true, Method: true, Path: true}
+}
+
+// sampler logs the requests of a route by a rate, it's deterministic, ex: the 0.25 rate logs the 4th, the 8th and so on
+type sampler struct {
+	rate float64
+	n    uint64
+}
+
+func (s *sampler) sample() bool {
+	n := atomic.AddUint64(&s.n, 1)
+	return uint64(float64(n)*s.rate) != uint64(float64(n-1)*s.rate)
 }
 
-type loggerMiddleware struct {
+// AccessLogger is the logger middleware, it logs the requests after the next handlers
+type AccessLogger struct {
 	*iris.Logger
-	options Options
+	options  Options
+	out      io.Writer
+	async    *asyncWriter
+	format   formatter
+	skip     map[string]bool
+	samplers map[string]*sampler
+	buffers  sync.Pool
 }
 
+var _ iris.Handler = &AccessLogger{}
+
 // a poor  and ugly implementation of a logger but no need to worry about this at the moment
-func (l *loggerMiddleware) Serve(ctx *iris.Context) {
+func (l *AccessLogger) serveText(ctx *iris.Context, startTime time.Time, path, method string) {
 	//all except latency to string
-	var date, status, ip, method, path string
+	var date, status, ip string
 	var latency time.Duration
-	var startTime, endTime time.Time
-	path = ctx.Request.URL.Path
-	method = ctx.Request.Method
-
-	if l.options.Latency {
-		startTime = time.Now()
-	}
+	var endTime time.Time
 
-	ctx.Next()
 	if l.options.Latency {
 		//no time.Since in order to format it well after
 		endTime = time.Now()
@@ -99,11 +139,86 @@ func (l *loggerMiddleware) Serve(ctx *iris.Context) {
 	} else {
 		l.Printf("%s %v %s %s %s", date, status, ip, method, path)
 	}
+}
+
+// Serve implements the iris.Handler
+func (l *AccessLogger) Serve(ctx *iris.Context) {
+	path := ctx.Request.URL.Path
+	if l.skip[path] {
+		ctx.Next()
+		return
+	}
+	method := ctx.Request.Method
+	startTime := time.Now()
+
+	ctx.Next()
+
+	route := ctx.GetRoutePath()
+	if route != "" && l.skip[route] {
+		return
+	}
+	status := ctx.ResponseWriter.Status()
+	if s := l.samplers[route]; s != nil && status < 500 && !s.sample() {
+		return
+	}
 
+	if l.format == nil {
+		l.serveText(ctx, startTime, path, method)
+		return
+	}
+
+	e := Entry{
+		Time:      startTime,
+		Latency:   time.Since(startTime),
+		Status:    status,
+		Method:    method,
+		Path:      path,
+		Query:     ctx.Request.URL.RawQuery,
+		Route:     route,
+		Proto:     ctx.Request.Proto,
+		IP:        ctx.RemoteAddr(),
+		Bytes:     ctx.ResponseWriter.Size(),
+		RequestID: l.options.RequestID(ctx),
+		Trace:     ctx.TraceContext(),
+		UserAgent: ctx.Request.UserAgent(),
+		Referer:   ctx.Request.Referer(),
+	}
+	if len(l.options.Values) > 0 {
+		e.Values = make([]Field, 0, len(l.options.Values))
+		for _, key := range l.options.Values {
+			if v := ctx.Get(key); v != nil {
+				e.Values = append(e.Values, Field{key, v})
+			}
+		}
+	}
+
+	b := l.buffers.Get().(*bytes.Buffer)
+	b.Reset()
+	l.format(b, &e)
+	b.WriteByte('\n')
+	l.out.Write(b.Bytes())
+	l.buffers.Put(b)
+}
+
+// Close flushes and stops the writer of the Async logger, the next lines are dropped
+// it doesn't close the logger's writer
+func (l *AccessLogger) Close() error {
+	if l.async != nil {
+		return l.async.Close()
+	}
+	return nil
+}
+
+// Dropped returns the number of the log lines which were dropped because the buffer of the Async logger was full
+func (l *AccessLogger) Dropped() uint64 {
+	if l.async != nil {
+		return l.async.Dropped()
+	}
+	return 0
 }
 
-func newLoggerMiddleware(writer io.Writer, prefix string, flag int, options ...Options) *loggerMiddleware {
-	l := &loggerMiddleware{Logger: iris.NewLogger(writer, prefix, flag)}
+func newLoggerMiddleware(writer io.Writer, prefix string, flag int, options ...Options) *AccessLogger {
+	l := &AccessLogger{buffers: sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}}
 
 	if len(options) > 0 {
 		l.options = options[0]
@@ -111,14 +226,53 @@ func newLoggerMiddleware(writer io.Writer, prefix string, flag int, options ...O
 		l.options = DefaultOptions()
 	}
 
+	if writer == nil {
+		writer = iris.LoggerOutTerminal
+	}
+	if l.options.Async {
+		l.async = newAsyncWriter(writer, l.options.BufferSize)
+		writer = l.async
+	}
+	l.out = writer
+	l.Logger = iris.NewLogger(writer, prefix, flag)
+	l.format = formatters[l.options.Format]
+
+	if l.options.RequestID == nil {
+		l.options.RequestID = func(ctx *iris.Context) string {
+			if id := ctx.RequestID(); id != "" {
+				return id
+			}
+			return ctx.ResponseWriter.Header().Get(DefaultRequestIDHeader)
+		}
+	}
+
+	l.skip = make(map[string]bool, len(l.options.Skip))
+	for _, path := range l.options.Skip {
+		l.skip[path] = true
+	}
+	l.samplers = make(map[string]*sampler, len(l.options.Sampling))
+	for route, rate := range l.options.Sampling {
+		if rate < 0 {
+			rate = 0
+		} else if rate > 1 {
+			rate = 1
+		}
+		l.samplers[route] = &sampler{rate: rate}
+	}
 	return l
 }
 
 //all bellow are just for flexibility
 
+// New returns the logger middleware which writes to the out writer, the default options are the DefaultOptions
+// use it with the FormatJSON, FormatLogfmt or FormatCombined Options.Format for the structured log lines
+func New(out io.Writer, options ...Options) *AccessLogger {
+	return newLoggerMiddleware(out, "", 0, options...)
+}
+
 // DefaultHandler returns the logger middleware with the default settings
 func DefaultHandler(options ...Options) iris.Handler {
-	return newLoggerMiddleware(iris.LoggerOutTerminal, "", 0)
+	return newLoggerMiddleware(iris.LoggerOutTerminal, "", 0, options...)
 }
 
 // Default returns the logger middleware as HandlerFunc with the default settings
@@ -0,0 +1,220 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kataras/iris"
+)
+
+// syncBuffer is a bytes.Buffer which is safe for the async logger
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) lines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := strings.TrimSuffix(s.b.String(), "\n")
+	if out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
+
+func testLoggerStation(l iris.Handler) http.Handler {
+	s := iris.New()
+	s.Use(l)
+	s.Get("/users/:id", iris.HandlerFunc(func(c *iris.Context) {
+		c.Set("user", c.Param("id"))
+		c.Write("user %s", c.Param("id"))
+	}))
+	s.Get("/health", iris.HandlerFunc(func(c *iris.Context) {
+		c.Write("ok")
+	}))
+	s.Get("/status/:code", iris.HandlerFunc(func(c *iris.Context) {
+		code, _ := c.ParamInt("code")
+		c.WriteStatus(code)
+	}))
+	return s.Serve()
+}
+
+func testLoggerRequest(handler http.Handler, path string, header ...string) {
+	req := httptest.NewRequest("GET", path, nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	for i := 0; i < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestLoggerJSON(t *testing.T) {
+	out := &syncBuffer{}
+	handler := testLoggerStation(New(out, Options{Format: FormatJSON, Values: []string{"user", "missing"}}))
+	testLoggerRequest(handler, "/users/42?tab=posts", "User-Agent", "test-agent", "Referer", "http://example.com/", DefaultRequestIDHeader, "req-1")
+
+	lines := out.lines()
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line but got %q", lines)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("expected a JSON line but got %q: %v", lines[0], err)
+	}
+	expected := map[string]interface{}{
+		"status":     float64(200),
+		"method":     "GET",
+		"path":       "/users/42",
+		"query":      "tab=posts",
+		"route":      "/users/:id",
+		"proto":      "HTTP/1.1",
+		"ip":         "10.0.0.1",
+		"bytes":      float64(len("user 42")),
+		"request_id": "req-1",
+		"user_agent": "test-agent",
+		"referer":    "http://example.com/",
+		"user":       "42",
+	}
+	for key, value := range expected {
+		if entry[key] != value {
+			t.Fatalf("expected %s=%v but got %v in %s", key, value, entry[key], lines[0])
+		}
+	}
+	if _, ok := entry["missing"]; ok {
+		t.Fatalf("expected the missing value to be omitted: %s", lines[0])
+	}
+	if _, ok := entry["latency_ms"].(float64); !ok {
+		t.Fatalf("expected the latency_ms: %s", lines[0])
+	}
+}
+
+func TestLoggerLogfmt(t *testing.T) {
+	out := &syncBuffer{}
+	handler := testLoggerStation(New(out, Options{Format: FormatLogfmt, Values: []string{"user"}}))
+	testLoggerRequest(handler, "/users/7", "User-Agent", `agent "quoted" 1.0`)
+
+	lines := out.lines()
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line but got %q", lines)
+	}
+	for _, expected := range []string{" status=200 ", " method=GET ", " path=/users/7 ", " route=/users/:id ", " ip=10.0.0.1 ", " bytes=6 ", ` user_agent="agent \"quoted\" 1.0"`, " user=7"} {
+		if !strings.Contains(lines[0], expected) {
+			t.Fatalf("expected %q in %s", expected, lines[0])
+		}
+	}
+	if !strings.HasPrefix(lines[0], "time=") {
+		t.Fatalf("expected the time first: %s", lines[0])
+	}
+}
+
+func TestLoggerCombined(t *testing.T) {
+	out := &syncBuffer{}
+	handler := testLoggerStation(New(out, Options{Format: FormatCombined}))
+	testLoggerRequest(handler, "/users/1?a=b", "User-Agent", "curl/7.47.0")
+	testLoggerRequest(handler, "/status/204")
+
+	lines := out.lines()
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines but got %q", lines)
+	}
+	combined := regexp.MustCompile(`^10\.0\.0\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /users/1\?a=b HTTP/1\.1" 200 6 "-" "curl/7\.47\.0"$`)
+	if !combined.MatchString(lines[0]) {
+		t.Fatalf("unexpected combined line %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], `"GET /status/204 HTTP/1.1" 204 - "-" "-"`) {
+		t.Fatalf("unexpected combined line %s", lines[1])
+	}
+}
+
+func TestLoggerSkipAndSampling(t *testing.T) {
+	out := &syncBuffer{}
+	handler := testLoggerStation(New(out, Options{
+		Format:   FormatLogfmt,
+		Skip:     []string{"/health", "/status/:code"},
+		Sampling: map[string]float64{"/users/:id": 0.5},
+	}))
+	testLoggerRequest(handler, "/health")
+	testLoggerRequest(handler, "/status/500")
+	for i := 0; i < 4; i++ {
+		testLoggerRequest(handler, "/users/1")
+	}
+	if lines := out.lines(); len(lines) != 2 {
+		t.Fatalf("expected 2 lines of the sampled route but got %q", lines)
+	}
+
+	out = &syncBuffer{}
+	handler = testLoggerStation(New(out, Options{
+		Format:   FormatLogfmt,
+		Sampling: map[string]float64{"/status/:code": 0},
+	}))
+	testLoggerRequest(handler, "/status/200")
+	testLoggerRequest(handler, "/status/503")
+	lines := out.lines()
+	if len(lines) != 1 || !strings.Contains(lines[0], "status=503") {
+		t.Fatalf("expected only the server error to be logged but got %q", lines)
+	}
+}
+
+// blockingWriter blocks the writes until it's released
+type blockingWriter struct {
+	syncBuffer
+	release chan struct{}
+}
+
+func (b *blockingWriter) Write(p []byte) (int, error) {
+	<-b.release
+	return b.syncBuffer.Write(p)
+}
+
+func TestLoggerAsync(t *testing.T) {
+	out := &syncBuffer{}
+	l := New(out, Options{Format: FormatJSON, Async: true})
+	handler := testLoggerStation(l)
+	for i := 0; i < 50; i++ {
+		testLoggerRequest(handler, "/users/1")
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := out.lines(); len(lines) != 50 || l.Dropped() != 0 {
+		t.Fatalf("expected 50 lines after the Close but got %d, %d dropped", len(lines), l.Dropped())
+	}
+
+	blocking := &blockingWriter{release: make(chan struct{})}
+	l = New(blocking, Options{Format: FormatJSON, Async: true, BufferSize: 1})
+	handler = testLoggerStation(l)
+	for i := 0; i < 10; i++ {
+		testLoggerRequest(handler, "/users/1")
+	}
+	close(blocking.release)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	written := uint64(len(blocking.lines()))
+	if l.Dropped() == 0 || written+l.Dropped() != 10 {
+		t.Fatalf("expected the lines to be dropped when the buffer is full, got %d written and %d dropped", written, l.Dropped())
+	}
+}
+
+func TestLoggerTextOptions(t *testing.T) {
+	out := &syncBuffer{}
+	handler := testLoggerStation(CustomHandler(out, "", 0, Options{Method: true, Path: true}))
+	testLoggerRequest(handler, "/users/3")
+	lines := out.lines()
+	if len(lines) != 1 || !strings.HasSuffix(lines[0], "GET /users/3") || strings.Contains(lines[0], "200") || strings.Contains(lines[0], "10.0.0.1") {
+		t.Fatalf("expected only the method and the path but got %q", lines)
+	}
+}
@@ -80,10 +80,10 @@ import (
 func main() {
     iris.Use(pongo2.Pongo2())
 
-    iris.Get("/", func(ctx *iris.Context) {
+    iris.Get("/", iris.HandlerFunc(func(ctx *iris.Context) {
         ctx.Set("template", "index.html")
         ctx.Set("data", map[string]interface{}{"message": "Hello World!"})
-    })
+    }))
 
     iris.Listen(":8080")
 }
@@ -0,0 +1,81 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package recovery
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/kataras/iris"
+)
+
+var debugPage = template.Must(template.New("recovery").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Code}} panic: {{.Report.Value}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+h1 { color: #b00020; }
+th { text-align: left; padding-right: 1em; }
+pre { background: #f4f4f4; padding: 1em; overflow: auto; }
+</style>
+</head>
+<body>
+<h1>panic: {{.Report.Value}}</h1>
+<table>
+<tr><th>Status</th><td>{{.Code}} {{.Status}}</td></tr>
+<tr><th>Route</th><td>{{.Report.Method}} {{.Report.Path}}</td></tr>
+<tr><th>Handler</th><td>{{.Report.Handler}}</td></tr>
+{{range $key, $value := .Report.Params}}<tr><th>:{{$key}}</th><td>{{$value}}</td></tr>
+{{end}}{{if .Report.RequestID}}<tr><th>Request ID</th><td>{{.Report.RequestID}}</td></tr>
+{{end}}{{if .Report.TraceID}}<tr><th>Trace ID</th><td>{{.Report.TraceID}}</td></tr>
+{{end}}<tr><th>Time</th><td>{{.Report.Time}}</td></tr>
+</table>
+<h2>Stack</h2>
+<pre>{{.Report.Stack}}</pre>
+</body>
+</html>
+`))
+
+// renderDebugPage sends the debug page of the report, see Options.Debug
+func renderDebugPage(ctx *iris.Context, code int, report *Report) {
+	var b bytes.Buffer
+	err := debugPage.Execute(&b, struct {
+		Code   int
+		Status string
+		Report *Report
+	}{code, http.StatusText(code), report})
+	if err != nil {
+		ctx.HandleError(err)
+		return
+	}
+	// the handler may set its content type before the panic
+	ctx.ResponseWriter.Header().Set(iris.ContentType, iris.ContentHTML+"; charset="+iris.Charset)
+	ctx.WriteHTML(code, b.String())
+}
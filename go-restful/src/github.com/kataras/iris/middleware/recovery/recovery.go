@@ -27,35 +27,248 @@
 package recovery
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/kataras/iris"
 )
 
-type recovery struct {
-	//out optional output to log any panics
-	out io.Writer
+const (
+	// DefaultRequestIDHeader is the request header which the request id of a report is read from, if the requestid middleware is not used
+	DefaultRequestIDHeader = iris.RequestIDHeader
+
+	// maxSeen is the number of the distinct panics which the duplicates limiter keeps before it removes the expired ones
+	maxSeen = 1024
+)
+
+// Options are the options of the recovery middleware
+type Options struct {
+	// Reporters receive each report of a recovered panic, in order
+	// Default is a LogReporter to the os.Stderr
+	Reporters []Reporter
+	// DuplicatesWindow is the duration which a panic with the same value from the same handler is reported only one time,
+	// the next report has the number of the suppressed duplicates.
+	// The request is recovered either way, only the report is suppressed
+	// Default is 0, all panics are reported
+	DuplicatesWindow time.Duration
+	// Debug renders a debug page with the panic value, the route and the stack instead of sending the error to the station's error handler,
+	// enable it only in development, it exposes the source of your application
+	// Default is false
+	Debug bool
+	// RequestID returns the request id of the report
+	// Default is the ctx.RequestID, which is set by the requestid middleware or it's the DefaultRequestIDHeader of the request
+	RequestID func(*iris.Context) string
+	// ErrorOut is the writer which the errors of the reporters are written to
+	// Default is the os.Stderr
+	ErrorOut io.Writer
+}
+
+// Report is the information of a recovered panic which is passed to the Reporters
+type Report struct {
+	Time time.Time `json:"time"`
+	// Value is the panic value formatted with %v
+	Value string `json:"value"`
+	// Err is the panic value as error, it's the value itself if the panic was called with an error
+	Err   error  `json:"-"`
+	Stack string `json:"stack"`
+
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Handler string `json:"handler"`
+	// Params are the named parameters of the route
+	Params    map[string]string `json:"params,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	// TraceID is the W3C trace id of the request, see the requestid middleware
+	TraceID string `json:"trace_id,omitempty"`
+	// Suppressed is the number of the same panics which were not reported since the previous report of this panic, see Options.DuplicatesWindow
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// key returns the key which the duplicates of this report have the same
+func (r *Report) key() string {
+	return r.Handler + "\x00" + r.Value
+}
+
+// seenPanic is the last report of a panic, used to limit the duplicates
+type seenPanic struct {
+	reported   time.Time
+	suppressed int
+}
+
+// Recoverer is the recovery middleware, it recovers the panics of the next handlers,
+// reports them and sends them as errors to the station's error handler (see iris.SetErrorHandler).
+//
+// It's also a plugin, when it's registed with iris.Plugin it becomes the first handler of each route which is registed after,
+// so it recovers the panics of the global and the party middleware too
+type Recoverer struct {
+	options Options
+
+	mu   sync.Mutex
+	seen map[string]*seenPanic
+}
+
+var (
+	_ iris.Handler          = &Recoverer{}
+	_ iris.IPlugin          = &Recoverer{}
+	_ iris.IPluginPreHandle = &Recoverer{}
+)
+
+// New creates and returns the recovery middleware, use it as the first handler (iris.Use) or as a plugin (iris.Plugin)
+func New(options ...Options) *Recoverer {
+	r := &Recoverer{seen: make(map[string]*seenPanic)}
+	if len(options) > 0 {
+		r.options = options[0]
+	}
+	if r.options.ErrorOut == nil {
+		r.options.ErrorOut = os.Stderr
+	}
+	if len(r.options.Reporters) == 0 {
+		r.options.Reporters = []Reporter{LogReporter(os.Stderr)}
+	}
+	if r.options.RequestID == nil {
+		r.options.RequestID = (*iris.Context).RequestID
+	}
+	return r
+}
+
+// Recovery restores the server on internal server errors (panics)
+// receives an optional writer, the default is the os.Stderr if no out writer given
+func Recovery(out ...io.Writer) iris.Handler {
+	w := io.Writer(os.Stderr)
+	if len(out) == 1 {
+		w = out[0]
+	}
+	return New(Options{Reporters: []Reporter{LogReporter(w)}})
 }
 
-func (r recovery) Serve(ctx *iris.Context) {
+// Serve implements the iris.Handler
+func (r *Recoverer) Serve(ctx *iris.Context) {
 	defer func() {
-		if err := recover(); err != nil {
-			r.out.Write([]byte("[" + time.Now().String() + "]Recovery from panic \n"))
-			//ctx.Panic just sends  http status 500 by default, but you can change it by: iris.OnPanic(func( c *iris.Context){})
-			ctx.Panic()
+		if rec := recover(); rec != nil {
+			if rec == http.ErrAbortHandler {
+				// the net/http aborts the response silently
+				panic(rec)
+			}
+			r.recovered(ctx, rec, debug.Stack())
 		}
 	}()
 	ctx.Next()
 }
 
-// Recovery restores the server on internal server errors (panics)
-// receives an optional writer, the default is the os.Stderr if no out writer given
-func Recovery(out ...io.Writer) iris.Handler {
-	r := recovery{os.Stderr}
-	if out != nil && len(out) == 1 {
-		r.out = out[0]
+// recovered reports the panic value and sends it to the client
+func (r *Recoverer) recovered(ctx *iris.Context, rec interface{}, stack []byte) {
+	err, ok := rec.(error)
+	if !ok {
+		err = fmt.Errorf("%v", rec)
 	}
-	return r
+
+	report := &Report{
+		Time:      time.Now(),
+		Value:     fmt.Sprintf("%v", rec),
+		Err:       err,
+		Stack:     string(stack),
+		Method:    ctx.Request.Method,
+		Path:      ctx.Request.URL.Path,
+		Handler:   ctx.GetHandlerName(),
+		RequestID: r.options.RequestID(ctx),
+	}
+	if trace := ctx.TraceContext(); trace.IsValid() {
+		report.TraceID = trace.TraceID.String()
+	}
+	if len(ctx.Params) > 0 {
+		report.Params = make(map[string]string, len(ctx.Params))
+		for _, p := range ctx.Params {
+			report.Params[p.Key] = p.Value
+		}
+	}
+
+	if r.allow(report) {
+		for _, reporter := range r.options.Reporters {
+			if rerr := reporter.Report(report); rerr != nil {
+				fmt.Fprintf(r.options.ErrorOut, "[Iris] Error on recovery reporter: %s\n", rerr.Error())
+			}
+		}
+	}
+
+	if r.options.Debug {
+		ctx.StopExecution()
+		code := http.StatusInternalServerError
+		var httpErr *iris.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Code >= 400 {
+			code = httpErr.Code
+		}
+		renderDebugPage(ctx, code, report)
+		return
+	}
+	// the panic goes to the station's error handler as an error, which by default sends 500 by the iris.OnPanic(func( c *iris.Context){}) handler
+	// or the status code of the error if it's an *iris.HTTPError or mapped by the iris.MapError
+	ctx.HandleError(err)
+}
+
+// allow returns true if the report should be reported, it sets the report's Suppressed
+// returns false if the same panic was reported inside the DuplicatesWindow
+func (r *Recoverer) allow(report *Report) bool {
+	window := r.options.DuplicatesWindow
+	if window <= 0 {
+		return true
+	}
+	key := report.key()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s := r.seen[key]; s != nil {
+		if report.Time.Sub(s.reported) < window {
+			s.suppressed++
+			return false
+		}
+		report.Suppressed = s.suppressed
+		s.reported = report.Time
+		s.suppressed = 0
+		return true
+	}
+
+	if len(r.seen) >= maxSeen {
+		for k, s := range r.seen {
+			if report.Time.Sub(s.reported) >= window {
+				delete(r.seen, k)
+			}
+		}
+	}
+	r.seen[key] = &seenPanic{reported: report.Time}
+	return true
+}
+
+// GetName implements the iris.IPlugin
+func (r *Recoverer) GetName() string {
+	return "recovery"
+}
+
+// GetDescription implements the iris.IPlugin
+func (r *Recoverer) GetDescription() string {
+	return "Recovers and reports the panics of the whole route's middleware"
+}
+
+// Activate implements the iris.IPlugin
+func (r *Recoverer) Activate(iris.IPluginContainer) error {
+	return nil
+}
+
+// PreHandle makes the recoverer the first handler of the route, implements the iris.IPluginPreHandle
+func (r *Recoverer) PreHandle(route iris.IRoute) {
+	middleware := route.GetMiddleware()
+	handlers := make(iris.Middleware, 1, len(middleware)+1)
+	handlers[0] = r
+	for _, h := range middleware {
+		if h != iris.Handler(r) {
+			handlers = append(handlers, h)
+		}
+	}
+	route.SetMiddleware(handlers)
 }
@@ -0,0 +1,297 @@
+package recovery
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kataras/iris"
+)
+
+// memoryReporter keeps the reports
+type memoryReporter struct {
+	mu      sync.Mutex
+	reports []*Report
+}
+
+func (m *memoryReporter) Report(r *Report) error {
+	m.mu.Lock()
+	m.reports = append(m.reports, r)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *memoryReporter) len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.reports)
+}
+
+func testRecoveryRequest(handler http.Handler, method, path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for i := 0; i < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	return res
+}
+
+func TestRecoveryPlugin(t *testing.T) {
+	reporter := &memoryReporter{}
+	s := iris.New()
+	// the global middleware is registed before the recoverer but it's recovered too
+	s.UseFunc(func(c *iris.Context) {
+		if c.URLParam("global") == "1" {
+			panic("global middleware")
+		}
+		c.Next()
+	})
+	if err := s.Plugin(New(Options{Reporters: []Reporter{reporter}})); err != nil {
+		t.Fatal(err)
+	}
+	s.Get("/users/:id", iris.HandlerFunc(func(c *iris.Context) {
+		panic("user " + c.Param("id"))
+	}))
+	s.Get("/teapot", iris.HandlerFunc(func(c *iris.Context) {
+		panic(iris.NewHTTPError(http.StatusTeapot, "short and stout"))
+	}))
+	s.Get("/ok", iris.HandlerFunc(func(c *iris.Context) {
+		c.Write("ok")
+	}))
+	handler := s.Serve()
+
+	res := testRecoveryRequest(handler, "GET", "/users/42", DefaultRequestIDHeader, "req-1")
+	if res.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500 but got %d", res.Code)
+	}
+	if reporter.len() != 1 {
+		t.Fatalf("expected 1 report but got %d", reporter.len())
+	}
+	r := reporter.reports[0]
+	if r.Value != "user 42" || r.Method != "GET" || r.Path != "/users/42" || r.RequestID != "req-1" {
+		t.Fatalf("unexpected report %#v", r)
+	}
+	if r.Params["id"] != "42" {
+		t.Fatalf("expected the param id=42 but got %v", r.Params)
+	}
+	if !strings.Contains(r.Handler, "TestRecoveryPlugin") {
+		t.Fatalf("expected the handler name of the route but got %q", r.Handler)
+	}
+	if !strings.Contains(r.Stack, "recovery_test.go") {
+		t.Fatalf("expected the stack of the panic but got:\n%s", r.Stack)
+	}
+
+	res = testRecoveryRequest(handler, "GET", "/ok?global=1")
+	if res.Code != http.StatusInternalServerError || reporter.len() != 2 || reporter.reports[1].Value != "global middleware" {
+		t.Fatalf("expected the panic of the global middleware to be recovered, got status %d and %d reports", res.Code, reporter.len())
+	}
+
+	res = testRecoveryRequest(handler, "GET", "/teapot")
+	if res.Code != http.StatusTeapot {
+		t.Fatalf("expected the status of the *iris.HTTPError but got %d", res.Code)
+	}
+	var httpErr *iris.HTTPError
+	if !errors.As(reporter.reports[2].Err, &httpErr) {
+		t.Fatalf("expected the panic error to be kept but got %v", reporter.reports[2].Err)
+	}
+
+	res = testRecoveryRequest(handler, "GET", "/ok")
+	if res.Code != http.StatusOK || res.Body.String() != "ok" || reporter.len() != 3 {
+		t.Fatalf("expected no panic, got status %d, body %q and %d reports", res.Code, res.Body.String(), reporter.len())
+	}
+}
+
+func TestRecoveryWriter(t *testing.T) {
+	var out bytes.Buffer
+	s := iris.New()
+	s.Use(Recovery(&out))
+	s.Get("/products/:name", iris.HandlerFunc(func(c *iris.Context) {
+		panic(errors.New("out of stock"))
+	}))
+	res := testRecoveryRequest(s.Serve(), "GET", "/products/tea")
+	if res.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500 but got %d", res.Code)
+	}
+	log := out.String()
+	for _, expected := range []string{"Recovery from panic: out of stock", "GET /products/tea", "name:tea", "goroutine"} {
+		if !strings.Contains(log, expected) {
+			t.Fatalf("expected the log to contain %q:\n%s", expected, log)
+		}
+	}
+}
+
+func TestRecoveryDuplicates(t *testing.T) {
+	reporter := &memoryReporter{}
+	s := iris.New()
+	s.Use(New(Options{Reporters: []Reporter{reporter}, DuplicatesWindow: 100 * time.Millisecond}))
+	s.Get("/a", iris.HandlerFunc(func(c *iris.Context) {
+		panic("a")
+	}))
+	s.Get("/b", iris.HandlerFunc(func(c *iris.Context) {
+		panic("b")
+	}))
+	handler := s.Serve()
+
+	for i := 0; i < 3; i++ {
+		if res := testRecoveryRequest(handler, "GET", "/a"); res.Code != http.StatusInternalServerError {
+			t.Fatalf("expected each duplicate to be recovered but got status %d", res.Code)
+		}
+	}
+	testRecoveryRequest(handler, "GET", "/b")
+	if reporter.len() != 2 {
+		t.Fatalf("expected 2 reports but got %d", reporter.len())
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	testRecoveryRequest(handler, "GET", "/a")
+	if reporter.len() != 3 {
+		t.Fatalf("expected 3 reports but got %d", reporter.len())
+	}
+	if suppressed := reporter.reports[2].Suppressed; suppressed != 2 {
+		t.Fatalf("expected 2 suppressed duplicates but got %d", suppressed)
+	}
+}
+
+func TestRecoveryReporters(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "panics.json")
+	fileReporter, err := JSONFileReporter(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var received []Report
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var r Report
+		if err := json.NewDecoder(req.Body).Decode(&r); err != nil || req.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, r)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	var errorOut, webhookErrorOut bytes.Buffer
+	webhook := WebhookReporter(hook.URL)
+	failingWebhook := NewWebhook(WebhookOptions{URL: failing.URL, ErrorOut: &webhookErrorOut})
+	s := iris.New()
+	s.Use(New(Options{
+		Reporters: []Reporter{fileReporter, webhook, failingWebhook},
+		ErrorOut:  &errorOut,
+	}))
+	s.Get("/orders/:id", iris.HandlerFunc(func(c *iris.Context) {
+		panic("order " + c.Param("id"))
+	}))
+	handler := s.Serve()
+	testRecoveryRequest(handler, "GET", "/orders/1", DefaultRequestIDHeader, "req-1")
+	testRecoveryRequest(handler, "GET", "/orders/2", DefaultRequestIDHeader, "req-2")
+	// the queued reports are sent on close
+	webhook.Close()
+	failingWebhook.Close()
+
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines []Report
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var r Report
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("expected a JSON report per line but got %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, r)
+	}
+	if len(lines) != 2 || lines[0].Value != "order 1" || lines[1].RequestID != "req-2" || lines[1].Params["id"] != "2" || lines[0].Stack == "" {
+		t.Fatalf("unexpected reports in the file %#v", lines)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || received[0].Value != "order 1" || received[1].Path != "/orders/2" {
+		t.Fatalf("unexpected reports of the webhook %#v", received)
+	}
+	if errorOut.Len() != 0 {
+		t.Fatalf("expected no error of the reporters but got %q", errorOut.String())
+	}
+	if !strings.Contains(webhookErrorOut.String(), "503 Service Unavailable") {
+		t.Fatalf("expected the error of the failing webhook but got %q", webhookErrorOut.String())
+	}
+}
+
+func TestWebhookQueue(t *testing.T) {
+	arrived := make(chan struct{}, 3)
+	release := make(chan struct{})
+	var received int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		atomic.AddInt32(&received, 1)
+	}))
+	defer hook.Close()
+
+	webhook := NewWebhook(WebhookOptions{URL: hook.URL, QueueSize: 1})
+	// the first report is sent, the second waits in the queue and the third is dropped without waiting for the webhook
+	if err := webhook.Report(&Report{Value: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	<-arrived
+	tests := []error{nil, ErrWebhookQueueFull}
+	for i, expected := range tests {
+		if err := webhook.Report(&Report{Value: strconv.Itoa(i + 2)}); err != expected {
+			t.Fatalf("[%d] expected %v but got %v", i, expected, err)
+		}
+	}
+
+	close(release)
+	webhook.Close()
+	if n := atomic.LoadInt32(&received); n != 2 {
+		t.Fatalf("expected the queued report to be sent on close but got %d reports", n)
+	}
+	if err := webhook.Report(&Report{Value: "4"}); err != ErrWebhookClosed {
+		t.Fatalf("expected %v after the close but got %v", ErrWebhookClosed, err)
+	}
+}
+
+func TestRecoveryDebug(t *testing.T) {
+	s := iris.New()
+	s.Use(New(Options{Reporters: []Reporter{&memoryReporter{}}, Debug: true}))
+	s.Get("/search", iris.HandlerFunc(func(c *iris.Context) {
+		c.SetContentType([]string{"application/json"})
+		panic("<script>alert(1)</script>")
+	}))
+	res := testRecoveryRequest(s.Serve(), "GET", "/search")
+	if res.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500 but got %d", res.Code)
+	}
+	if ct := res.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("expected an html page but got %q", ct)
+	}
+	body := res.Body.String()
+	if strings.Contains(body, "<script>") || !strings.Contains(body, "&lt;script&gt;") {
+		t.Fatalf("expected the panic value to be escaped:\n%s", body)
+	}
+	if !strings.Contains(body, "GET /search") || !strings.Contains(body, "recovery_test.go") {
+		t.Fatalf("expected the route and the stack in the page:\n%s", body)
+	}
+}
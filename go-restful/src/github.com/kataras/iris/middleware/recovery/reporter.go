@@ -0,0 +1,236 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package recovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWebhookTimeout is the timeout of the webhook's requests
+	DefaultWebhookTimeout = 5 * time.Second
+	// DefaultWebhookQueueSize is the number of the reports which wait to be sent by a Webhook
+	DefaultWebhookQueueSize = 100
+)
+
+var (
+	// ErrWebhookQueueFull is returned by the Webhook's Report when the queue is full, the report is dropped
+	ErrWebhookQueueFull = errors.New("webhook queue is full, the report is dropped")
+	// ErrWebhookClosed is returned by the Webhook's Report after the Close, the report is dropped
+	ErrWebhookClosed = errors.New("webhook is closed, the report is dropped")
+)
+
+// Reporter receives the reports of the recovered panics
+type Reporter interface {
+	Report(*Report) error
+}
+
+// ReporterFunc is the func which implements the Reporter
+type ReporterFunc func(*Report) error
+
+// Report implements the Reporter
+func (f ReporterFunc) Report(r *Report) error {
+	return f(r)
+}
+
+// writerReporter writes the reports to a writer, the writes are serialized
+type writerReporter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	encode func(io.Writer, *Report) error
+}
+
+func (w *writerReporter) Report(r *Report) error {
+	w.mu.Lock()
+	err := w.encode(w.out, r)
+	w.mu.Unlock()
+	return err
+}
+
+func writeText(out io.Writer, r *Report) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "[%s] Recovery from panic: %s\n", r.Time.Format(time.RFC3339), r.Value)
+	fmt.Fprintf(&b, "%s %s handler: %s", r.Method, r.Path, r.Handler)
+	if len(r.Params) > 0 {
+		fmt.Fprintf(&b, " params: %v", r.Params)
+	}
+	if r.RequestID != "" {
+		fmt.Fprintf(&b, " request id: %s", r.RequestID)
+	}
+	if r.TraceID != "" {
+		fmt.Fprintf(&b, " trace id: %s", r.TraceID)
+	}
+	if r.Suppressed > 0 {
+		fmt.Fprintf(&b, " (%d duplicates suppressed)", r.Suppressed)
+	}
+	b.WriteString("\n")
+	b.WriteString(r.Stack)
+	if len(r.Stack) > 0 && r.Stack[len(r.Stack)-1] != '\n' {
+		b.WriteString("\n")
+	}
+	_, err := out.Write(b.Bytes())
+	return err
+}
+
+func writeJSON(out io.Writer, r *Report) error {
+	return json.NewEncoder(out).Encode(r)
+}
+
+// LogReporter returns a Reporter which writes each report as text, the panic value, the route and the stack, to the out writer
+func LogReporter(out io.Writer) Reporter {
+	return &writerReporter{out: out, encode: writeText}
+}
+
+// JSONReporter returns a Reporter which writes each report as a JSON object in its own line to the out writer
+func JSONReporter(out io.Writer) Reporter {
+	return &writerReporter{out: out, encode: writeJSON}
+}
+
+// JSONFileReporter returns a JSONReporter which appends the reports to the file, the file is created if it doesn't exist
+func JSONFileReporter(filename string) (Reporter, error) {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("[Iris] Error on recovery: can't open the report file %s: %s", filename, err.Error())
+	}
+	return JSONReporter(f), nil
+}
+
+// WebhookOptions are the options of the NewWebhook
+type WebhookOptions struct {
+	// URL is the url which each report is POSTed to as JSON
+	URL string
+	// Client is the http client of the requests
+	// Default has a timeout of DefaultWebhookTimeout
+	Client *http.Client
+	// QueueSize is the number of the reports which wait to be sent, the next reports are dropped until there is room
+	// Default is the DefaultWebhookQueueSize
+	QueueSize int
+	// ErrorOut is the writer which the errors of the requests are written to, the reports are not sent again
+	// Default is the os.Stderr
+	ErrorOut io.Writer
+}
+
+// Webhook is a Reporter which POSTs the reports as JSON to a url from its own goroutine,
+// so the response of the panic doesn't wait for the webhook, a status code other than 2xx is an error
+//
+// Close it on shutdown in order to send the queued reports
+type Webhook struct {
+	options WebhookOptions
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan []byte
+	done    chan struct{}
+}
+
+var _ Reporter = &Webhook{}
+
+// NewWebhook returns a Webhook which sends the reports with these options
+func NewWebhook(options WebhookOptions) *Webhook {
+	if options.Client == nil {
+		options.Client = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = DefaultWebhookQueueSize
+	}
+	if options.ErrorOut == nil {
+		options.ErrorOut = os.Stderr
+	}
+	w := &Webhook{options: options, queue: make(chan []byte, options.QueueSize), done: make(chan struct{})}
+	go w.send()
+	return w
+}
+
+// WebhookReporter returns a Webhook which POSTs each report as JSON to the url,
+// the client is optional, the default has a timeout of DefaultWebhookTimeout, see NewWebhook for the rest options
+func WebhookReporter(url string, client ...*http.Client) *Webhook {
+	options := WebhookOptions{URL: url}
+	if len(client) > 0 {
+		options.Client = client[0]
+	}
+	return NewWebhook(options)
+}
+
+// Report queues the report, it returns ErrWebhookQueueFull if the report is dropped
+func (w *Webhook) Report(r *Report) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return ErrWebhookClosed
+	}
+	select {
+	case w.queue <- body:
+		return nil
+	default:
+		return ErrWebhookQueueFull
+	}
+}
+
+// Close sends the queued reports and stops the webhook, the next reports are dropped
+func (w *Webhook) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+	<-w.done
+	return nil
+}
+
+func (w *Webhook) send() {
+	defer close(w.done)
+	for body := range w.queue {
+		if err := w.post(body); err != nil {
+			fmt.Fprintf(w.options.ErrorOut, "[Iris] Error on recovery webhook: %s\n", err.Error())
+		}
+	}
+}
+
+func (w *Webhook) post(body []byte) error {
+	res, err := w.options.Client.Post(w.options.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook %s responded with %s", w.options.URL, res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/kataras/iris"
+)
+
+const (
+	// MaxLength is the maximum length of an accepted request id, the longer ids are replaced by a generated
+	MaxLength = 128
+)
+
+// Options are the options of the requestid middleware
+type Options struct {
+	// Generator returns the id of the requests which have no valid X-Request-Id header
+	// Default is a random UUID, ex: 8b7c2f0e-8e4a-4c3a-9a8f-0d2b1e6f5a41
+	Generator func() string
+	// IgnoreIncoming generates the id of each request, even if it has an X-Request-Id header,
+	// enable it when the clients are not trusted to choose the ids
+	// Default is false
+	IgnoreIncoming bool
+	// Sampled sets the sampled flag of the traces which start here, the requests which have no valid traceparent header,
+	// it's not used if the station traces the requests, the middleware keeps the trace context of the server span (see iris.Station.Tracing)
+	// Default is false
+	Sampled bool
+}
+
+// NewID returns a random UUID (version 4)
+func NewID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+// validID returns true if the id is not empty, not longer than the MaxLength and it has only visible ASCII characters, except the quotes and the backslash,
+// so it's safe to be written to the logs
+func validID(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if c := id[i]; c <= ' ' || c >= 0x7f || c == '"' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+type requestIDMiddleware struct {
+	options Options
+}
+
+// New returns the requestid middleware, use it as the first middleware (iris.Use)
+//
+// It accepts the X-Request-Id header of the request or it generates an id, it parses the W3C traceparent and tracestate headers
+// or it starts a new trace, and it sets them to the Context (see ctx.RequestID and ctx.TraceContext) and to the request's context.Context.
+// The X-Request-Id and the traceparent (with the span of this request) are sent with the response.
+//
+// The Transport sends them to the next services, see NewRequest
+func New(options ...Options) iris.Handler {
+	m := &requestIDMiddleware{}
+	if len(options) > 0 {
+		m.options = options[0]
+	}
+	if m.options.Generator == nil {
+		m.options.Generator = NewID
+	}
+	return m
+}
+
+func (m *requestIDMiddleware) Serve(ctx *iris.Context) {
+	id := ctx.Request.Header.Get(iris.RequestIDHeader)
+	if m.options.IgnoreIncoming || !validID(id) {
+		id = m.options.Generator()
+	}
+
+	// the trace context of the server span, if the station traces the requests (see iris.Station.Tracing)
+	trace := ctx.TraceContext()
+	if !trace.IsValid() {
+		var err error
+		if trace, err = iris.ContinueTrace(ctx.Request.Header); err != nil {
+			trace = iris.TraceContext{TraceID: iris.NewTraceID(), SpanID: iris.NewSpanID()}
+			if m.options.Sampled {
+				trace.Flags = iris.TraceFlagSampled
+			}
+		}
+	}
+
+	ctx.SetRequestID(id)
+	ctx.SetTraceContext(trace)
+	h := ctx.ResponseWriter.Header()
+	h.Set(iris.RequestIDHeader, id)
+	h.Set(iris.TraceparentHeader, trace.Traceparent())
+	ctx.Request = ctx.Request.WithContext(NewContext(ctx.Request.Context(), id, trace))
+	ctx.Next()
+}
+
+type contextKey struct{}
+
+type contextValues struct {
+	requestID string
+	trace     iris.TraceContext
+}
+
+// NewContext returns a copy of the parent which has the request id and the trace context, the Transport sends them to the next services
+func NewContext(parent context.Context, requestID string, trace iris.TraceContext) context.Context {
+	return context.WithValue(parent, contextKey{}, contextValues{requestID, trace})
+}
+
+// FromContext returns the request id and the trace context of the context.Context, ok is false if they are not set by the middleware or the NewContext
+func FromContext(c context.Context) (requestID string, trace iris.TraceContext, ok bool) {
+	v, ok := c.Value(contextKey{}).(contextValues)
+	return v.requestID, v.trace, ok
+}
+
+// NewRequest returns an outgoing request which has the context.Context of the iris' request,
+// send it with a client which uses the Transport (ex: the DefaultClient) to propagate the request id and the trace context
+// ex: req, err := requestid.NewRequest(c, "GET", "http://users-service/users/42", nil)
+// res, err := requestid.DefaultClient.Do(req)
+func NewRequest(ctx *iris.Context, method, url string, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx.Request.Context(), method, url, body)
+}
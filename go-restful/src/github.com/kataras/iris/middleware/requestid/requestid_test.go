@@ -0,0 +1,193 @@
+package requestid
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/middleware/logger"
+	"github.com/kataras/iris/middleware/recovery"
+)
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func testRequest(handler http.Handler, path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for i := 0; i < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	return res
+}
+
+func TestRequestID(t *testing.T) {
+	var id string
+	var trace iris.TraceContext
+	record := iris.HandlerFunc(func(c *iris.Context) {
+		id = c.RequestID()
+		trace = c.TraceContext()
+		ctxID, ctxTrace, ok := FromContext(c.Request.Context())
+		if !ok || ctxID != id || ctxTrace != trace {
+			t.Fatalf("expected the request's context.Context to have the id and the trace")
+		}
+	})
+	s := iris.New()
+	s.Use(New())
+	s.Get("/", record)
+	handler := s.Serve()
+
+	res := testRequest(handler, "/")
+	if !uuidRegexp.MatchString(id) || res.Header().Get(iris.RequestIDHeader) != id {
+		t.Fatalf("expected a generated id to be sent but got %q and %q", id, res.Header().Get(iris.RequestIDHeader))
+	}
+	if !trace.IsValid() || trace.ParentID.IsValid() || trace.Sampled() {
+		t.Fatalf("expected a new not sampled trace but got %#v", trace)
+	}
+	if res.Header().Get(iris.TraceparentHeader) != trace.Traceparent() {
+		t.Fatalf("expected the traceparent %s but got %s", trace.Traceparent(), res.Header().Get(iris.TraceparentHeader))
+	}
+
+	testRequest(handler, "/", "X-Request-ID", "abc-123")
+	if id != "abc-123" {
+		t.Fatalf("expected the incoming id but got %q", id)
+	}
+	for _, invalid := range []string{"bad id", "bad\nid", strings.Repeat("a", MaxLength+1), `"quoted"`} {
+		testRequest(handler, "/", iris.RequestIDHeader, invalid)
+		if !uuidRegexp.MatchString(id) {
+			t.Fatalf("expected the invalid id %q to be replaced but got %q", invalid, id)
+		}
+	}
+
+	incoming := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	res = testRequest(handler, "/", iris.TraceparentHeader, incoming, iris.TracestateHeader, "congo=t61rcWkgMzE")
+	if trace.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || trace.ParentID.String() != "00f067aa0ba902b7" ||
+		trace.SpanID == trace.ParentID || !trace.SpanID.IsValid() || !trace.Sampled() || trace.State != "congo=t61rcWkgMzE" {
+		t.Fatalf("expected the trace to continue but got %#v", trace)
+	}
+	if sent := res.Header().Get(iris.TraceparentHeader); sent != "00-4bf92f3577b34da6a3ce929d0e0e4736-"+trace.SpanID.String()+"-01" {
+		t.Fatalf("expected the traceparent of this request's span but got %s", sent)
+	}
+
+	testRequest(handler, "/", iris.TraceparentHeader, "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	if trace.TraceID.String() == "00000000000000000000000000000000" || trace.ParentID.IsValid() {
+		t.Fatalf("expected a new trace for the invalid traceparent but got %#v", trace)
+	}
+
+	s = iris.New()
+	s.Use(New(Options{IgnoreIncoming: true, Sampled: true, Generator: func() string { return "generated" }}))
+	s.Get("/", record)
+	testRequest(s.Serve(), "/", iris.RequestIDHeader, "abc-123")
+	if id != "generated" || !trace.Sampled() {
+		t.Fatalf("expected the generated id and a sampled trace but got %q %#v", id, trace)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var received http.Header
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received = req.Header.Clone()
+	}))
+	defer downstream.Close()
+
+	var trace iris.TraceContext
+	s := iris.New()
+	s.Use(New())
+	s.Get("/", iris.HandlerFunc(func(c *iris.Context) {
+		trace = c.TraceContext()
+		req, err := NewRequest(c, "GET", downstream.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.URLParam("explicit") != "" {
+			req.Header.Set(iris.RequestIDHeader, "explicit")
+		}
+		res, err := DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if req.Header.Get(iris.TraceparentHeader) != "" {
+			t.Fatalf("expected the outgoing request not to be modified")
+		}
+	}))
+	handler := s.Serve()
+
+	testRequest(handler, "/", iris.RequestIDHeader, "req-1", iris.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", iris.TracestateHeader, "congo=t61rcWkgMzE")
+	if received.Get(iris.RequestIDHeader) != "req-1" {
+		t.Fatalf("expected the request id to be propagated but got %q", received.Get(iris.RequestIDHeader))
+	}
+	// the downstream's parent is this request's span
+	if received.Get(iris.TraceparentHeader) != "00-4bf92f3577b34da6a3ce929d0e0e4736-"+trace.SpanID.String()+"-01" {
+		t.Fatalf("unexpected propagated traceparent %q", received.Get(iris.TraceparentHeader))
+	}
+	if received.Get(iris.TracestateHeader) != "congo=t61rcWkgMzE" {
+		t.Fatalf("expected the tracestate to be propagated but got %q", received.Get(iris.TracestateHeader))
+	}
+
+	testRequest(handler, "/?explicit=1", iris.RequestIDHeader, "req-2")
+	if received.Get(iris.RequestIDHeader) != "explicit" {
+		t.Fatalf("expected the header of the request not to be changed but got %q", received.Get(iris.RequestIDHeader))
+	}
+
+	// a request without the middleware's context is sent as it's
+	req, _ := http.NewRequest("GET", downstream.URL, nil)
+	res, err := DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if received.Get(iris.RequestIDHeader) != "" || received.Get(iris.TraceparentHeader) != "" {
+		t.Fatalf("expected no propagated headers but got %v", received)
+	}
+}
+
+// the logger and the recovery middleware read the id and the trace of the Context
+func TestRequestIDLoggerAndRecovery(t *testing.T) {
+	var access bytes.Buffer
+	var reports []*recovery.Report
+	ring := iris.NewRingSink(10)
+
+	s := iris.New()
+	s.GetLogger().SetSinks(ring)
+	s.Use(New())
+	s.Use(logger.New(&access, logger.Options{Format: logger.FormatJSON}))
+	s.Use(recovery.New(recovery.Options{Reporters: []recovery.Reporter{recovery.ReporterFunc(func(r *recovery.Report) error {
+		reports = append(reports, r)
+		return nil
+	})}}))
+	var trace iris.TraceContext
+	s.Get("/panic", iris.HandlerFunc(func(c *iris.Context) {
+		trace = c.TraceContext()
+		c.Logger().Info("about to panic")
+		panic("boom")
+	}))
+	testRequest(s.Serve(), "/panic", iris.RequestIDHeader, "req-1")
+
+	if len(reports) != 1 || reports[0].RequestID != "req-1" || reports[0].TraceID != trace.TraceID.String() {
+		t.Fatalf("expected the report to have the request id and the trace id but got %#v", reports)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(access.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["request_id"] != "req-1" || entry["trace_id"] != trace.TraceID.String() || entry["span_id"] != trace.SpanID.String() {
+		t.Fatalf("expected the access log to have the request id and the trace but got %s", access.String())
+	}
+	records := ring.Records()
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record but got %v", records)
+	}
+	fields := map[string]interface{}{}
+	for _, f := range records[0].Fields {
+		fields[f.Key] = f.Value
+	}
+	if fields["request_id"] != "req-1" || fields["trace_id"] != trace.TraceID.String() {
+		t.Fatalf("expected the request's logger to have the request id and the trace id but got %v", records[0].Fields)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package requestid
+
+import (
+	"net/http"
+
+	"github.com/kataras/iris"
+)
+
+// DefaultClient is an http.Client which propagates the request id and the trace context of the requests' context.Context
+var DefaultClient = &http.Client{Transport: NewTransport(nil)}
+
+// Transport is an http.RoundTripper which sends the request id and the trace context of the request's context.Context (see FromContext)
+// as X-Request-Id, traceparent and tracestate headers, the headers which are already set are not changed
+type Transport struct {
+	// Base is the RoundTripper which sends the requests, the http.DefaultTransport if nil
+	Base http.RoundTripper
+}
+
+var _ http.RoundTripper = &Transport{}
+
+// NewTransport returns a Transport which sends the requests with the base RoundTripper, the http.DefaultTransport if nil
+// ex: client := &http.Client{Transport: requestid.NewTransport(nil), Timeout: 5 * time.Second}
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base}
+}
+
+// RoundTrip implements the http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	id, trace, ok := FromContext(req.Context())
+	if !ok {
+		return base.RoundTrip(req)
+	}
+	// a RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	if id != "" && req.Header.Get(iris.RequestIDHeader) == "" {
+		req.Header.Set(iris.RequestIDHeader, id)
+	}
+	if trace.IsValid() && req.Header.Get(iris.TraceparentHeader) == "" {
+		req.Header.Set(iris.TraceparentHeader, trace.Traceparent())
+		if trace.State != "" {
+			req.Header.Set(iris.TracestateHeader, trace.State)
+		}
+	}
+	return base.RoundTrip(req)
+}
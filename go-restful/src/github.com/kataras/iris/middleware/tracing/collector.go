@@ -0,0 +1,147 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package tracing
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CollectedSpan is a span which the Collector received
+type CollectedSpan struct {
+	TraceID string
+	SpanID  string
+	// ParentSpanID is empty for the root spans
+	ParentSpanID  string
+	TraceState    string
+	Name          string
+	Kind          int
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    map[string]interface{}
+	Status        int
+	StatusMessage string
+	// Resource are the attributes of the resource which sent the span, ex: "service.name"
+	Resource map[string]interface{}
+	// Scope is the name of the instrumentation scope
+	Scope string
+}
+
+// Collector is an in-process OTLP/HTTP collector which keeps the received spans in memory,
+// it accepts the JSON encoding only, use it to test the tracing or to see the spans of a development server
+//
+// Usage:
+// collector := &tracing.Collector{}
+// server := httptest.NewServer(collector)
+// exporter := tracing.NewExporter(tracing.Options{Endpoint: server.URL + "/v1/traces"})
+// ... exporter.Flush(); spans := collector.Spans()
+type Collector struct {
+	mu    sync.Mutex
+	spans []CollectedSpan
+}
+
+var _ http.Handler = &Collector{}
+
+func (c *Collector) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.Header().Set("Allow", http.MethodPost)
+		http.Error(res, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if contentType := req.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		http.Error(res, "only the application/json is supported", http.StatusUnsupportedMediaType)
+		return
+	}
+	var r otlpRequest
+	if err := json.NewDecoder(req.Body).Decode(&r); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var spans []CollectedSpan
+	for _, rs := range r.ResourceSpans {
+		resource := collectAttributes(rs.Resource.Attributes)
+		for _, ss := range rs.ScopeSpans {
+			for _, s := range ss.Spans {
+				spans = append(spans, CollectedSpan{
+					TraceID:       s.TraceID,
+					SpanID:        s.SpanID,
+					ParentSpanID:  s.ParentSpanID,
+					TraceState:    s.TraceState,
+					Name:          s.Name,
+					Kind:          s.Kind,
+					StartTime:     parseUnixNano(s.StartTimeUnixNano),
+					EndTime:       parseUnixNano(s.EndTimeUnixNano),
+					Attributes:    collectAttributes(s.Attributes),
+					Status:        s.Status.Code,
+					StatusMessage: s.Status.Message,
+					Resource:      resource,
+					Scope:         ss.Scope.Name,
+				})
+			}
+		}
+	}
+	c.mu.Lock()
+	c.spans = append(c.spans, spans...)
+	c.mu.Unlock()
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Write([]byte("{}"))
+}
+
+// Spans returns a copy of the received spans, by the order they received
+func (c *Collector) Spans() []CollectedSpan {
+	c.mu.Lock()
+	spans := make([]CollectedSpan, len(c.spans))
+	copy(spans, c.spans)
+	c.mu.Unlock()
+	return spans
+}
+
+// Reset removes the received spans
+func (c *Collector) Reset() {
+	c.mu.Lock()
+	c.spans = nil
+	c.mu.Unlock()
+}
+
+func collectAttributes(attributes []otlpKeyValue) map[string]interface{} {
+	m := make(map[string]interface{}, len(attributes))
+	for _, kv := range attributes {
+		m[kv.Key] = kv.Value.value()
+	}
+	return m
+}
+
+func parseUnixNano(s string) time.Time {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return time.Unix(0, n)
+}
@@ -0,0 +1,286 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kataras/iris"
+)
+
+const (
+	// DefaultEndpoint is the default url which the spans are sent to, the OTLP/HTTP traces endpoint of a local collector
+	DefaultEndpoint = "http://localhost:4318/v1/traces"
+	// DefaultServiceName is the default service.name of the spans' resource
+	DefaultServiceName = "iris"
+	// DefaultBatchSize is the default maximum number of the spans which are sent by one request
+	DefaultBatchSize = 512
+	// DefaultQueueSize is the default number of the spans which wait to be sent, the next spans are dropped
+	DefaultQueueSize = 2048
+	// DefaultFlushInterval is the default interval which the queued spans are sent, even if they are less than a batch
+	DefaultFlushInterval = 5 * time.Second
+	// DefaultTimeout is the default timeout of the requests to the collector
+	DefaultTimeout = 10 * time.Second
+)
+
+var errExporterClosed = errors.New("the exporter is closed")
+
+// Options are the options of the Exporter
+type Options struct {
+	// Endpoint is the url of the collector's OTLP/HTTP traces endpoint, the spans are POSTed as JSON
+	// Default is the DefaultEndpoint
+	Endpoint string
+	// Headers are sent with each request, ex: an authorization header of the collector
+	Headers map[string]string
+	// ServiceName is the service.name attribute of the resource
+	// Default is the DefaultServiceName
+	ServiceName string
+	// ResourceAttributes are the other attributes of the resource, ex: "deployment.environment": "production"
+	ResourceAttributes map[string]interface{}
+	// BatchSize is the maximum number of the spans which are sent by one request
+	// Default is the DefaultBatchSize
+	BatchSize int
+	// QueueSize is the number of the spans which wait to be sent, the spans which end when the queue is full are dropped
+	// Default is the DefaultQueueSize
+	QueueSize int
+	// FlushInterval is the interval which the queued spans are sent, even if they are less than a batch
+	// Default is the DefaultFlushInterval
+	FlushInterval time.Duration
+	// Client sends the requests to the collector
+	// Default is a client with the DefaultTimeout
+	Client *http.Client
+	// ErrorOut is the writer which the errors of the requests to the collector are written to
+	// Default is the os.Stderr
+	ErrorOut io.Writer
+}
+
+// Exporter is the iris.SpanProcessor which sends the spans to a collector with the OTLP/HTTP,
+// the spans are queued and sent by batches from a goroutine, so the requests don't wait for the collector
+//
+// Usage:
+// exporter := tracing.NewExporter(tracing.Options{Endpoint: "http://collector:4318/v1/traces", ServiceName: "api"})
+// defer exporter.Shutdown(context.Background())
+// iris.Tracing(iris.TracingOptions{Processor: exporter, MiddlewareSpans: true})
+type Exporter struct {
+	options  Options
+	resource []otlpKeyValue
+	spans    chan *iris.Span
+	flush    chan chan error
+	done     chan struct{}
+	dropped  uint64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+var _ iris.SpanProcessor = &Exporter{}
+
+// NewExporter returns a new Exporter and starts its goroutine, call its Shutdown to send the queued spans and stop it
+func NewExporter(options ...Options) *Exporter {
+	e := &Exporter{}
+	if len(options) > 0 {
+		e.options = options[0]
+	}
+	if e.options.Endpoint == "" {
+		e.options.Endpoint = DefaultEndpoint
+	}
+	if e.options.ServiceName == "" {
+		e.options.ServiceName = DefaultServiceName
+	}
+	if e.options.BatchSize <= 0 {
+		e.options.BatchSize = DefaultBatchSize
+	}
+	if e.options.QueueSize <= 0 {
+		e.options.QueueSize = DefaultQueueSize
+	}
+	if e.options.FlushInterval <= 0 {
+		e.options.FlushInterval = DefaultFlushInterval
+	}
+	if e.options.Client == nil {
+		e.options.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+	if e.options.ErrorOut == nil {
+		e.options.ErrorOut = os.Stderr
+	}
+
+	e.resource = append(e.resource, otlpKeyValue{Key: "service.name", Value: otlpValueOf(e.options.ServiceName)})
+	for k, v := range e.options.ResourceAttributes {
+		e.resource = append(e.resource, otlpKeyValue{Key: k, Value: otlpValueOf(v)})
+	}
+	e.spans = make(chan *iris.Span, e.options.QueueSize)
+	e.flush = make(chan chan error)
+	e.done = make(chan struct{})
+	go e.run()
+	return e
+}
+
+func (e *Exporter) run() {
+	ticker := time.NewTicker(e.options.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]*iris.Span, 0, e.options.BatchSize)
+	send := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := e.export(batch)
+		if err != nil {
+			fmt.Fprintf(e.options.ErrorOut, "[Iris] Error on tracing exporter: %s\n", err.Error())
+		}
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		select {
+		case span, ok := <-e.spans:
+			if !ok {
+				send()
+				close(e.done)
+				return
+			}
+			if batch = append(batch, span); len(batch) >= e.options.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case result := <-e.flush:
+			var err error
+			for n := len(e.spans); n > 0; n-- {
+				if batch = append(batch, <-e.spans); len(batch) >= e.options.BatchSize {
+					if serr := send(); serr != nil {
+						err = serr
+					}
+				}
+			}
+			if serr := send(); serr != nil {
+				err = serr
+			}
+			result <- err
+		}
+	}
+}
+
+// export sends the spans with one request, a status code other than 2xx is an error
+func (e *Exporter) export(spans []*iris.Span) error {
+	body, err := json.Marshal(newOTLPRequest(e.resource, spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.options.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.options.Headers {
+		req.Header.Set(k, v)
+	}
+	res, err := e.options.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("collector %s responded with %s for %d spans", e.options.Endpoint, res.Status, len(spans))
+	}
+	return nil
+}
+
+// OnEnd queues the span, the span is dropped if the queue is full or the exporter is closed
+func (e *Exporter) OnEnd(span *iris.Span) {
+	e.mu.RLock()
+	if e.closed {
+		e.mu.RUnlock()
+		atomic.AddUint64(&e.dropped, 1)
+		return
+	}
+	select {
+	case e.spans <- span:
+	default:
+		atomic.AddUint64(&e.dropped, 1)
+	}
+	e.mu.RUnlock()
+}
+
+// Dropped returns the number of the dropped spans
+func (e *Exporter) Dropped() uint64 {
+	return atomic.LoadUint64(&e.dropped)
+}
+
+// Flush sends the queued spans now and returns the error of the last failed request, if any
+func (e *Exporter) Flush() error {
+	result := make(chan error, 1)
+	select {
+	case e.flush <- result:
+		return <-result
+	case <-e.done:
+		return errExporterClosed
+	}
+}
+
+// Shutdown sends the queued spans and stops the goroutine, the next spans are dropped
+// it returns the context's error if the context is done before the spans are sent
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.spans)
+	}
+	e.mu.Unlock()
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RatioSampler returns a TracingOptions.Sampler which records the ratio of the new traces, ex: 0.1 records the 10%,
+// the decision depends only on the trace id, as the OpenTelemetry's TraceIdRatioBased sampler
+func RatioSampler(ratio float64) func(iris.TraceID) bool {
+	if ratio >= 1 {
+		return func(iris.TraceID) bool { return true }
+	}
+	if ratio <= 0 {
+		return func(iris.TraceID) bool { return false }
+	}
+	bound := uint64(ratio * (1 << 63))
+	return func(id iris.TraceID) bool {
+		return binary.BigEndian.Uint64(id[8:])>>1 < bound
+	}
+}
@@ -0,0 +1,205 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/middleware/requestid"
+)
+
+func findSpan(spans []CollectedSpan, name string) *CollectedSpan {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+func auth(c *iris.Context) {
+	c.Next()
+}
+
+func TestExporter(t *testing.T) {
+	collector := &Collector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	exporter := NewExporter(Options{
+		Endpoint:           server.URL + "/v1/traces",
+		ServiceName:        "users-api",
+		ResourceAttributes: map[string]interface{}{"deployment.environment": "test"},
+		FlushInterval:      time.Hour,
+	})
+	defer exporter.Shutdown(context.Background())
+
+	s := iris.New()
+	s.Tracing(iris.TracingOptions{Processor: exporter, MiddlewareSpans: true})
+	s.Use(iris.HandlerFunc(auth))
+	s.Get("/users/:id", iris.HandlerFunc(func(c *iris.Context) {
+		span := c.StartSpan("load user")
+		span.SetAttribute("user.id", 42)
+		span.SetAttribute("user.admin", true)
+		span.End()
+		c.Write("user")
+	}))
+	handler := s.Serve()
+
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	req.Header.Set(iris.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if err := exporter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := collector.Spans()
+	root := findSpan(spans, "GET /users/:id")
+	if root == nil {
+		t.Fatalf("Expected the server span named after the route but got %d spans", len(spans))
+	}
+	if root.Kind != int(iris.SpanKindServer) || root.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || root.ParentSpanID != "00f067aa0ba902b7" {
+		t.Fatalf("Expected a server span which continues the caller's trace but got %#v", root)
+	}
+	if root.Attributes["http.route"] != "/users/:id" || root.Attributes["http.response.status_code"] != int64(200) {
+		t.Fatalf("Expected the http attributes but got %v", root.Attributes)
+	}
+	if root.Resource["service.name"] != "users-api" || root.Resource["deployment.environment"] != "test" || root.Scope != scopeName {
+		t.Fatalf("Expected the resource attributes but got %v", root.Resource)
+	}
+	if root.EndTime.Before(root.StartTime) {
+		t.Fatalf("Expected the end time after the start time")
+	}
+
+	for _, name := range []string{"iris.cache", "iris.route", "github.com/kataras/iris/middleware/tracing.auth"} {
+		if span := findSpan(spans, name); span == nil || span.ParentSpanID != root.SpanID || span.TraceID != root.TraceID {
+			t.Fatalf("Expected the %s span to be a child of the server span", name)
+		}
+	}
+	middleware := findSpan(spans, "github.com/kataras/iris/middleware/tracing.auth")
+	var handlerSpan *CollectedSpan
+	for i := range spans {
+		if strings.HasPrefix(spans[i].Name, "github.com/kataras/iris/middleware/tracing.TestExporter") {
+			handlerSpan = &spans[i]
+		}
+	}
+	if handlerSpan == nil || handlerSpan.ParentSpanID != middleware.SpanID {
+		t.Fatalf("Expected the span of the route's handler to be a child of the middleware's span")
+	}
+	custom := findSpan(spans, "load user")
+	if custom == nil || custom.ParentSpanID != handlerSpan.SpanID || custom.Attributes["user.id"] != int64(42) || custom.Attributes["user.admin"] != true {
+		t.Fatalf("Expected the custom span with its attributes but got %#v", custom)
+	}
+
+	collector.Reset()
+	if err := exporter.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+	if exporter.Dropped() == 0 || len(collector.Spans()) != 0 {
+		t.Fatalf("Expected the spans to be dropped after the shutdown")
+	}
+	if err := exporter.Flush(); err == nil {
+		t.Fatalf("Expected an error on flush after the shutdown")
+	}
+}
+
+func TestExporterBatches(t *testing.T) {
+	requests := 0
+	collector := &Collector{}
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		requests++
+		if req.Header.Get("Authorization") != "Bearer token" {
+			http.Error(res, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		collector.ServeHTTP(res, req)
+	}))
+	defer server.Close()
+
+	errorOut := &bytes.Buffer{}
+	exporter := NewExporter(Options{Endpoint: server.URL, BatchSize: 2, FlushInterval: time.Hour, ErrorOut: errorOut})
+	span := &iris.Span{Trace: iris.TraceContext{TraceID: iris.NewTraceID(), SpanID: iris.NewSpanID()}, Name: "op"}
+	exporter.OnEnd(span)
+	if err := exporter.Flush(); err == nil || !strings.Contains(errorOut.String(), "401 Unauthorized") {
+		t.Fatalf("Expected the error of the collector but got %v and %q", err, errorOut.String())
+	}
+	exporter.Shutdown(context.Background())
+
+	requests = 0
+	exporter = NewExporter(Options{Endpoint: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}, BatchSize: 2, FlushInterval: time.Hour})
+	for i := 0; i < 5; i++ {
+		exporter.OnEnd(span)
+	}
+	if err := exporter.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if spans := collector.Spans(); len(spans) != 5 || requests != 3 || spans[0].Name != "op" || spans[0].ParentSpanID != "" {
+		t.Fatalf("Expected 5 spans by 3 requests but got %d spans by %d requests", len(spans), requests)
+	}
+}
+
+func TestCollectorRejects(t *testing.T) {
+	collector := &Collector{}
+	w := httptest.NewRecorder()
+	collector.ServeHTTP(w, httptest.NewRequest("GET", "/v1/traces", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected 405 but got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/v1/traces", strings.NewReader("spans"))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	collector.ServeHTTP(w, req)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("Expected 415 but got %d", w.Code)
+	}
+}
+
+func TestRatioSampler(t *testing.T) {
+	if !RatioSampler(1)(iris.NewTraceID()) || RatioSampler(0)(iris.NewTraceID()) {
+		t.Fatalf("Expected the ratios 1 and 0 to record all and none")
+	}
+	sampler, sampled := RatioSampler(0.25), 0
+	for i := 0; i < 4000; i++ {
+		if sampler(iris.NewTraceID()) {
+			sampled++
+		}
+	}
+	if sampled < 800 || sampled > 1200 {
+		t.Fatalf("Expected about 1000 sampled traces but got %d", sampled)
+	}
+	id := iris.NewTraceID()
+	if sampler(id) != sampler(id) {
+		t.Fatalf("Expected the same decision for the same trace id")
+	}
+}
+
+func TestRequestIDKeepsServerSpan(t *testing.T) {
+	collector := &Collector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+	exporter := NewExporter(Options{Endpoint: server.URL, FlushInterval: time.Hour})
+	defer exporter.Shutdown(context.Background())
+
+	s := iris.New()
+	s.Tracing(iris.TracingOptions{Processor: exporter})
+	s.Use(requestid.New())
+	s.Get("/", iris.HandlerFunc(func(c *iris.Context) {}))
+	w := httptest.NewRecorder()
+	s.Serve().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	exporter.Flush()
+
+	root := findSpan(collector.Spans(), "GET /")
+	if root == nil {
+		t.Fatalf("Expected the server span")
+	}
+	trace, err := iris.ParseTraceparent(w.Header().Get(iris.TraceparentHeader))
+	if err != nil || trace.TraceID.String() != root.TraceID || trace.SpanID.String() != root.SpanID {
+		t.Fatalf("Expected the traceparent of the response to be the server span but got %s", w.Header().Get(iris.TraceparentHeader))
+	}
+}
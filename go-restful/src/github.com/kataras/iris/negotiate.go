@@ -0,0 +1,314 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package iris
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// AcceptHeader is the request's header key which the client uses to declare the media types it accepts
+	AcceptHeader = "Accept"
+	// VaryHeader is the response's header key which lists the request headers which the response depends on
+	VaryHeader = "Vary"
+)
+
+// ErrNotAcceptable is returned from the Context.Negotiate and Context.Respond when none of the offered media types is accepted by the client,
+// the 406 status has been already sent
+var ErrNotAcceptable = errors.New("[Iris] Error on Negotiate: none of the offered media types is acceptable")
+
+// AcceptSpec is a media range of the Accept header with its quality value, ex: text/html;q=0.8
+type AcceptSpec struct {
+	// Type is the type of the media range, ex: text or *
+	Type string
+	// Subtype is the subtype of the media range, ex: html or *
+	Subtype string
+	// Q is the quality value, from 0 to 1, defaults to 1
+	Q float64
+}
+
+// specificity returns 0 for */*, 1 for type/* and 2 for type/subtype
+func (a AcceptSpec) specificity() int {
+	if a.Type == "*" {
+		return 0
+	}
+	if a.Subtype == "*" {
+		return 1
+	}
+	return 2
+}
+
+// Match returns true if the media type, ex: application/json, is inside this media range
+func (a AcceptSpec) Match(mediaType string) bool {
+	typ, subtype := splitMediaType(mediaType)
+	return (a.Type == "*" || a.Type == typ) && (a.Subtype == "*" || a.Subtype == subtype)
+}
+
+func splitMediaType(mediaType string) (string, string) {
+	if idx := strings.IndexByte(mediaType, ';'); idx != -1 {
+		mediaType = mediaType[:idx]
+	}
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if idx := strings.IndexByte(mediaType, '/'); idx != -1 {
+		return mediaType[:idx], mediaType[idx+1:]
+	}
+	return mediaType, ""
+}
+
+// ParseAccept parses an Accept header's value and returns its media ranges sorted by their preference,
+// the quality value first and the more specific range when they have the same quality.
+// Invalid media ranges are skipped, an empty header returns an empty slice
+func ParseAccept(header string) []AcceptSpec {
+	specs := make([]AcceptSpec, 0, 4)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		typ, subtype := splitMediaType(params[0])
+		if typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+			continue
+		}
+		spec := AcceptSpec{Type: typ, Subtype: subtype, Q: 1}
+		for _, param := range params[1:] {
+			if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+				q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil || q < 0 || q > 1 {
+					q = 0
+				}
+				spec.Q = q
+				// the parameters after the q are accept-extensions
+				break
+			}
+		}
+		specs = append(specs, spec)
+	}
+
+	sort.SliceStable(specs, func(i, j int) bool {
+		if specs[i].Q != specs[j].Q {
+			return specs[i].Q > specs[j].Q
+		}
+		return specs[i].specificity() > specs[j].specificity()
+	})
+	return specs
+}
+
+// quality returns the quality of the media type by the most specific media range which matches it and its specificity,
+// -1 if none matches
+func quality(specs []AcceptSpec, mediaType string) (float64, int) {
+	q, specificity := -1.0, -1
+	for _, spec := range specs {
+		if spec.Match(mediaType) && spec.specificity() > specificity {
+			q, specificity = spec.Q, spec.specificity()
+		}
+	}
+	return q, specificity
+}
+
+// ResponseEncoder encodes a value to the response's body, it's used by the Context.Respond for a media type
+type ResponseEncoder func(w io.Writer, v interface{}) error
+
+type responseEncoder struct {
+	mediaType string
+	encode    ResponseEncoder
+}
+
+// responseEncoders are the encoders of the Context.Respond, by the order of the server's preference
+var responseEncoders = []responseEncoder{
+	{ContentJSON, encodeJSON},
+	{ContentXML, encodeXML},
+	{ContentHTML, encodeHTML},
+	{ContentTEXT, encodeText},
+	{ContentXMLText, encodeXML},
+}
+
+// RegisterEncoder registers an encoder for a media type, ex: application/msgpack or text/csv, which the Context.Respond uses
+// it replaces the previous encoder of this media type, if any, otherwise the new one has the lowest preference
+func RegisterEncoder(mediaType string, encoder ResponseEncoder) {
+	mediaType = strings.ToLower(mediaType)
+	for i := range responseEncoders {
+		if responseEncoders[i].mediaType == mediaType {
+			responseEncoders[i].encode = encoder
+			return
+		}
+	}
+	responseEncoders = append(responseEncoders, responseEncoder{mediaType, encoder})
+}
+
+func encodeJSON(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func encodeXML(w io.Writer, v interface{}) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// encodeText writes the string form of the value, []byte and strings are written as they are
+func encodeText(w io.Writer, v interface{}) error {
+	var err error
+	if b, ok := v.([]byte); ok {
+		_, err = w.Write(b)
+	} else {
+		_, err = fmt.Fprint(w, v)
+	}
+	return err
+}
+
+// encodeHTML writes the escaped string form of the value, strings too because the value may come from the client,
+// send raw html with the Context.HTML or with a text/html func of the Context.Negotiate
+func encodeHTML(w io.Writer, v interface{}) error {
+	_, err := io.WriteString(w, html.EscapeString(fmt.Sprint(v)))
+	return err
+}
+
+// preference returns the server's preference of a media type, lower is better
+func preference(mediaType string) int {
+	for i := range responseEncoders {
+		if responseEncoders[i].mediaType == mediaType {
+			return i
+		}
+	}
+	return len(responseEncoders)
+}
+
+// negotiate returns the offered media type which the client prefers,
+// the higher quality first, then the more specific media range and then the server's preference.
+// Returns an empty string if none is acceptable
+func negotiate(accept string, offers []string) string {
+	specs := ParseAccept(accept)
+	if strings.TrimSpace(accept) == "" {
+		specs = []AcceptSpec{{Type: "*", Subtype: "*", Q: 1}}
+	}
+
+	best, bestQ, bestSpecificity := "", 0.0, -1
+	for _, offer := range offers {
+		q, specificity := quality(specs, offer)
+		if q <= 0 {
+			continue
+		}
+		if best == "" || q > bestQ || (q == bestQ && (specificity > bestSpecificity ||
+			(specificity == bestSpecificity && preferred(offer, best)))) {
+			best, bestQ, bestSpecificity = offer, q, specificity
+		}
+	}
+	return best
+}
+
+// preferred returns true if the server prefers the media type a than the b, by the registered encoders and then alphabetically
+func preferred(a, b string) bool {
+	if pa, pb := preference(a), preference(b); pa != pb {
+		return pa < pb
+	}
+	return a < b
+}
+
+// contentTypeOf returns the Content-Type header's value of a media type, textual types have the charset
+func contentTypeOf(mediaType string) string {
+	if strings.HasPrefix(mediaType, "text/") || mediaType == ContentJSON || mediaType == ContentXML || mediaType == ContentJSONP {
+		return mediaType + " ;charset=" + Charset
+	}
+	return mediaType
+}
+
+// notAcceptable sends the 406 status, by the error handler which can be changed with the iris.OnError(406,...)
+func (ctx *Context) notAcceptable() error {
+	ctx.EmitError(http.StatusNotAcceptable)
+	return ErrNotAcceptable
+}
+
+// Negotiate chooses one of the offers, by media type, from the request's Accept header, sets the Content-Type, writes the http status and executes the offer.
+// If none of them is acceptable it sends 406 and returns ErrNotAcceptable.
+//
+// Example:
+// ctx.Negotiate(200, map[string]func() error{
+//	"application/json": func() error { return ctx.RenderJSON(200, user) },
+//	"text/html": func() error { return ctx.RenderFile("user.html", user) },
+// })
+func (ctx *Context) Negotiate(httpStatus int, offers map[string]func() error) error {
+	mediaTypes := make([]string, 0, len(offers))
+	byMediaType := make(map[string]func() error, len(offers))
+	for mediaType, offer := range offers {
+		mediaType = strings.ToLower(mediaType)
+		mediaTypes = append(mediaTypes, mediaType)
+		byMediaType[mediaType] = offer
+	}
+
+	ctx.addVaryAccept()
+	mediaType := negotiate(ctx.Request.Header.Get(AcceptHeader), mediaTypes)
+	if mediaType == "" {
+		return ctx.notAcceptable()
+	}
+	ctx.SetContentType([]string{contentTypeOf(mediaType)})
+	ctx.WriteStatus(httpStatus)
+	return byMediaType[mediaType]()
+}
+
+// Respond encodes the value with the registered encoder (see iris.RegisterEncoder) of the media type which the client prefers,
+// by default JSON, XML, HTML and text, and writes it with the http status.
+// If none of them is acceptable it sends 406 and returns ErrNotAcceptable,
+// if the encoding fails nothing is written and the error is returned
+func (ctx *Context) Respond(httpStatus int, value interface{}) error {
+	mediaTypes := make([]string, len(responseEncoders))
+	for i := range responseEncoders {
+		mediaTypes[i] = responseEncoders[i].mediaType
+	}
+
+	ctx.addVaryAccept()
+	mediaType := negotiate(ctx.Request.Header.Get(AcceptHeader), mediaTypes)
+	if mediaType == "" {
+		return ctx.notAcceptable()
+	}
+
+	var body bytes.Buffer
+	if err := responseEncoders[preference(mediaType)].encode(&body, value); err != nil {
+		return err
+	}
+	ctx.SetContentType([]string{contentTypeOf(mediaType)})
+	ctx.WriteStatus(httpStatus)
+	_, err := ctx.ResponseWriter.Write(body.Bytes())
+	return err
+}
+
+// addVaryAccept adds the Accept to the response's Vary header, the response depends on it
+func (ctx *Context) addVaryAccept() {
+	h := ctx.ResponseWriter.Header()
+	for _, v := range h.Values(VaryHeader) {
+		for _, field := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), AcceptHeader) {
+				return
+			}
+		}
+	}
+	h.Add(VaryHeader, AcceptHeader)
+}
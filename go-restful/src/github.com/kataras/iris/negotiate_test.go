@@ -0,0 +1,170 @@
+package iris
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseAccept(t *testing.T) {
+	specs := ParseAccept("text/*;q=0.5, application/json;q=0.9, */*;q=0.1, text/html, invalid, application/xml;q=abc")
+	expected := []AcceptSpec{
+		{"text", "html", 1},
+		{"application", "json", 0.9},
+		{"text", "*", 0.5},
+		{"*", "*", 0.1},
+		{"application", "xml", 0},
+	}
+	if !reflect.DeepEqual(specs, expected) {
+		t.Fatalf("Expected %v but got %v", expected, specs)
+	}
+}
+
+type testNegotiateUser struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func (u testNegotiateUser) String() string {
+	return "user " + u.Name
+}
+
+func testRespond(accept string, value interface{}) *httptest.ResponseRecorder {
+	s := New()
+	s.Get("/", HandlerFunc(func(c *Context) { c.Respond(http.StatusCreated, value) }))
+	req := httptest.NewRequest("GET", "/", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	s.Serve().ServeHTTP(w, req)
+	return w
+}
+
+func TestContextRespond(t *testing.T) {
+	user := testNegotiateUser{"kataras"}
+	tests := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"", ContentJSON, `{"name":"kataras"}` + "\n"},
+		{"*/*", ContentJSON, `{"name":"kataras"}` + "\n"},
+		{"application/xml", ContentXML, "<testNegotiateUser><name>kataras</name></testNegotiateUser>"},
+		{"text/xml, application/json;q=0.5", ContentXMLText, "<testNegotiateUser><name>kataras</name></testNegotiateUser>"},
+		{"text/plain", ContentTEXT, "user kataras"},
+		{"text/*;q=0.8, text/html", ContentHTML, "user kataras"},
+		{"text/*, application/*;q=0.9", ContentHTML, "user kataras"},
+		{"application/json;q=0, */*;q=0.5", ContentXML, "<testNegotiateUser><name>kataras</name></testNegotiateUser>"},
+	}
+
+	for i, tt := range tests {
+		w := testRespond(tt.accept, user)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("[%d] Expected status 201 but got %d", i, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != contentTypeOf(tt.contentType) {
+			t.Fatalf("[%d] Expected Content-Type %s but got %s", i, contentTypeOf(tt.contentType), ct)
+		}
+		if w.Body.String() != tt.body {
+			t.Fatalf("[%d] Expected body %q but got %q", i, tt.body, w.Body.String())
+		}
+		if vary := w.Header().Get("Vary"); vary != "Accept" {
+			t.Fatalf("[%d] Expected Vary: Accept but got %q", i, vary)
+		}
+	}
+}
+
+func TestContextRespondEscapesHTML(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		body  string
+	}{
+		{"<script>alert(1)</script>", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{[]string{"<script>"}, "[&lt;script&gt;]"},
+	}
+
+	for i, tt := range tests {
+		w := testRespond("text/html", tt.value)
+		if ct := w.Header().Get("Content-Type"); ct != contentTypeOf(ContentHTML) {
+			t.Fatalf("[%d] Expected Content-Type %s but got %s", i, contentTypeOf(ContentHTML), ct)
+		}
+		if w.Body.String() != tt.body {
+			t.Fatalf("[%d] Expected body %q but got %q", i, tt.body, w.Body.String())
+		}
+	}
+}
+
+func TestContextRespondNotAcceptable(t *testing.T) {
+	w := testRespond("image/png", "x")
+	if w.Code != http.StatusNotAcceptable {
+		t.Fatalf("Expected status 406 but got %d", w.Code)
+	}
+
+	// custom 406 handler
+	s := New()
+	var err error
+	s.OnError(http.StatusNotAcceptable, func(c *Context) { c.Write("custom") })
+	s.Get("/", HandlerFunc(func(c *Context) { err = c.Respond(http.StatusOK, "x") }))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "image/png")
+	w = httptest.NewRecorder()
+	s.Serve().ServeHTTP(w, req)
+	if w.Code != http.StatusNotAcceptable || w.Body.String() != "custom" || err != ErrNotAcceptable {
+		t.Fatalf("Expected the custom 406 handler but got %d %q, error: %v", w.Code, w.Body.String(), err)
+	}
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	defer func(encoders []responseEncoder) { responseEncoders = encoders }(append([]responseEncoder(nil), responseEncoders...))
+
+	RegisterEncoder("text/CSV", func(w io.Writer, v interface{}) error {
+		u := v.(testNegotiateUser)
+		_, err := fmt.Fprintf(w, "name\n%s\n", u.Name)
+		return err
+	})
+
+	w := testRespond("text/csv", testNegotiateUser{"kataras"})
+	if ct := w.Header().Get("Content-Type"); ct != contentTypeOf("text/csv") || w.Body.String() != "name\nkataras\n" {
+		t.Fatalf("Unexpected response %s %q", ct, w.Body.String())
+	}
+	// the registered encoders have the lowest preference
+	if w = testRespond("", testNegotiateUser{"kataras"}); w.Header().Get("Content-Type") != contentTypeOf(ContentJSON) {
+		t.Fatalf("Expected JSON but got %s", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestContextNegotiate(t *testing.T) {
+	offers := func(c *Context) map[string]func() error {
+		return map[string]func() error{
+			ContentJSON: func() error { c.Write("json"); return nil },
+			ContentHTML: func() error { c.Write("html"); return nil },
+			"text/csv":  func() error { c.Write("csv"); return nil },
+		}
+	}
+	s := New()
+	s.Get("/", HandlerFunc(func(c *Context) { c.Negotiate(http.StatusOK, offers(c)) }))
+
+	tests := []struct {
+		accept string
+		status int
+		body   string
+	}{
+		{"", http.StatusOK, "json"},
+		{"text/*", http.StatusOK, "html"},
+		{"text/csv, text/html;q=0.9", http.StatusOK, "csv"},
+		{"text/html;q=0.2, application/json;q=0.4", http.StatusOK, "json"},
+		{"image/png", http.StatusNotAcceptable, "406 not acceptable"},
+	}
+	for i, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		s.Serve().ServeHTTP(w, req)
+		if w.Code != tt.status || w.Body.String() != tt.body {
+			t.Fatalf("[%d] Expected %d %q but got %d %q", i, tt.status, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
@@ -43,21 +43,25 @@ import (
 // IParty is the interface which implements the whole Party of routes
 type IParty interface {
 	IMiddlewareSupporter
-	Handle(method string, registedPath string, handlers ...Handler)
-	HandleFunc(method string, registedPath string, handlersFn ...HandlerFunc)
+	Handle(method string, registedPath string, handlers ...Handler) IRoute
+	HandleFunc(method string, registedPath string, handlersFn ...HandlerFunc) IRoute
 	HandleAnnotated(irisHandler Handler) error
-	Get(path string, handlersFn ...HandlerFunc)
-	Post(path string, handlersFn ...HandlerFunc)
-	Put(path string, handlersFn ...HandlerFunc)
-	Delete(path string, handlersFn ...HandlerFunc)
-	Connect(path string, handlersFn ...HandlerFunc)
-	Head(path string, handlersFn ...HandlerFunc)
-	Options(path string, handlersFn ...HandlerFunc)
-	Patch(path string, handlersFn ...HandlerFunc)
-	Trace(path string, handlersFn ...HandlerFunc)
-	Any(path string, handlersFn ...HandlerFunc)
-	Ws(path string, handler Handler)
+	Get(path string, handlers ...Handler) IRoute
+	Post(path string, handlers ...Handler) IRoute
+	Put(path string, handlers ...Handler) IRoute
+	Delete(path string, handlers ...Handler) IRoute
+	Connect(path string, handlers ...Handler) IRoute
+	Head(path string, handlers ...Handler) IRoute
+	Options(path string, handlers ...Handler) IRoute
+	Patch(path string, handlers ...Handler) IRoute
+	Trace(path string, handlers ...Handler) IRoute
+	Any(path string, handlers ...Handler) IRoute
+	Ws(path string, handler Handler) IRoute
 	Party(path string) IParty // Each party can have a party too
+	// OnError registers a handler for an http error status of the requests which their path starts with the party's path
+	OnError(statusCode int, handlerFunc HandlerFunc)
+	// Logger returns a child of the station's logger which writes the party's path with each message
+	Logger() *Logger
 	getRoot() IParty
 	getPath() string
 	isTheRoot() bool
@@ -82,8 +86,9 @@ func NewParty(path string, station *Station, hoster *GardenParty) IParty {
 	//if this party is comes from other party
 	if hoster != nil {
 		p.hoster = hoster
-		path = p.hoster.rootPath + path
-		p.Middleware = p.hoster.Middleware
+		path = p.hoster.joinPath(path)
+		// the capacity is limited, so the Use of a party doesn't write to the spare capacity of the hoster's or of another party's middleware
+		p.Middleware = p.hoster.Middleware[:len(p.hoster.Middleware):len(p.hoster.Middleware)]
 		lastSlashIndex := strings.LastIndexByte(path, SlashByte)
 
 		if lastSlashIndex == len(path)-1 {
@@ -95,6 +100,25 @@ func NewParty(path string, station *Station, hoster *GardenParty) IParty {
 	return p
 }
 
+// isDomainPath returns true if the path starts with a domain, ex: admin.mydomain.com/users
+func isDomainPath(path string) bool {
+	if path == "" || path[0] == SlashByte {
+		return false
+	}
+	firstSlashIndex := strings.IndexByte(path, SlashByte)
+	dotIndex := strings.IndexByte(path, '.')
+	return dotIndex != -1 && (firstSlashIndex == -1 || dotIndex < firstSlashIndex)
+}
+
+// joinPath returns the party's path followed by the path,
+// the root party doesn't prepend its slash to a path which starts with a domain, the NewRoute finds the domain from it
+func (p *GardenParty) joinPath(path string) string {
+	if p.isTheRoot() && isDomainPath(path) {
+		return path
+	}
+	return p.rootPath + path
+}
+
 // fixPath fix the double slashes, (because of root,I just do that before the .Handle no need for anything else special)
 func fixPath(str string) string {
 	return strings.Replace(str, "//", Slash, -1)
@@ -121,9 +145,10 @@ func (p GardenParty) getPath() string {
 	return p.rootPath
 }
 
-// Handle registers a route to the server's router
-func (p *GardenParty) Handle(method string, registedPath string, handlers ...Handler) {
-	registedPath = p.rootPath + registedPath
+// Handle registers a route to the server's router and returns it, the route can be named for the reverse routing, ex: .Name("user.edit")
+// it panics with a *RouteConflictError if the route collides with an already registed route
+func (p *GardenParty) Handle(method string, registedPath string, handlers ...Handler) IRoute {
+	registedPath = p.joinPath(registedPath)
 	if registedPath == "" {
 		registedPath = Slash
 	}
@@ -149,15 +174,24 @@ func (p *GardenParty) Handle(method string, registedPath string, handlers ...Han
 	//the party's middleware were setted on NewParty already, no need to check them.
 
 	if len(tempHandlers) > 0 {
-		handlers = append(tempHandlers, handlers...)
+		// each route has its own slice, the party's middleware may have capacity for more handlers which the routes would share
+		handlers = append(append(make(Middleware, 0, len(tempHandlers)+len(handlers)), tempHandlers...), handlers...)
 	}
 
 	//println(" so the len of registed ", registedPath, " of handlers is: ", len(handlers))
 	route := NewRoute(method, registedPath, handlers)
+	route.station = p.station
+	route.source = registrationSource()
+	if err := p.station.checkConflict(route); err != nil {
+		panic(err)
+	}
 
 	p.station.GetPluginContainer().DoPreHandle(route)
 
 	p.station.IRouter.setGarden(p.station.getGarden().Plant(route))
+	p.station.routes = append(p.station.routes, route)
+	// each route has its own middleware slice
+	p.station.routePaths[&route.middleware[0]] = route.fullpath
 
 	p.station.GetPluginContainer().DoPostHandle(route)
 
@@ -166,13 +200,14 @@ func (p *GardenParty) Handle(method string, registedPath string, handlers ...Han
 	//so
 	p.station.forceOptimusPrime()
 
+	return route
 }
 
 // HandleFunc registers and returns a route with a method string, path string and a handler
 // registedPath is the relative url path
 // handler is the iris.Handler which you can pass anything you want via iris.ToHandlerFunc(func(res,req){})... or just use func(c *iris.Context)
-func (p *GardenParty) HandleFunc(method string, registedPath string, handlersFn ...HandlerFunc) {
-	p.Handle(method, registedPath, ConvertToHandlers(handlersFn)...)
+func (p *GardenParty) HandleFunc(method string, registedPath string, handlersFn ...HandlerFunc) IRoute {
+	return p.Handle(method, registedPath, ConvertToHandlers(handlersFn)...)
 }
 
 // HandleAnnotated registers a route handler using a Struct implements iris.Handler (as anonymous property)
@@ -241,63 +276,85 @@ func (p *GardenParty) Party(path string) IParty {
 	return NewParty(path, p.station, p)
 }
 
+// OnError registers a handler for an http error status of the requests which their path starts with the party's path,
+// the handler of the party with the longest path is used, ex: the /api party sends JSON errors and the /web party sends HTML pages
+// the root party's handlers are the station's handlers, see Station.OnError
+func (p *GardenParty) OnError(statusCode int, handlerFunc HandlerFunc) {
+	if p.isTheRoot() {
+		p.station.OnError(statusCode, handlerFunc)
+		return
+	}
+	p.station.onPartyError(fixPath(p.rootPath), statusCode, handlerFunc)
+}
+
+// Logger returns a child of the station's logger which writes the party's path with each message, ex: party=/api
+// the root party's logger is the station's logger
+func (p *GardenParty) Logger() *Logger {
+	if p.isTheRoot() {
+		return p.station.GetLogger()
+	}
+	return p.station.GetLogger().With("party", fixPath(p.rootPath))
+}
+
 ///////////////////////////////
 //expose some methods as public
 ///////////////////////////////
 
+// the handlers of the verbs are HandlerFunc, HandlerFuncErr or any other Handler, ex: p.Get("/users/:id", HandlerFuncErr(getUser))
+
 // Get registers a route for the Get http method
-func (p *GardenParty) Get(path string, handlersFn ...HandlerFunc) {
-	p.HandleFunc(HTTPMethods.GET, path, handlersFn...)
+func (p *GardenParty) Get(path string, handlers ...Handler) IRoute {
+	return p.Handle(HTTPMethods.GET, path, handlers...)
 }
 
 // Post registers a route for the Post http method
-func (p *GardenParty) Post(path string, handlersFn ...HandlerFunc) {
-	p.HandleFunc(HTTPMethods.POST, path, handlersFn...)
+func (p *GardenParty) Post(path string, handlers ...Handler) IRoute {
+	return p.Handle(HTTPMethods.POST, path, handlers...)
 }
 
 // Put registers a route for the Put http method
-func (p *GardenParty) Put(path string, handlersFn ...HandlerFunc) {
-	p.HandleFunc(HTTPMethods.PUT, path, handlersFn...)
+func (p *GardenParty) Put(path string, handlers ...Handler) IRoute {
+	return p.Handle(HTTPMethods.PUT, path, handlers...)
 }
 
 // Delete registers a route for the Delete http method
-func (p *GardenParty) Delete(path string, handlersFn ...HandlerFunc) {
-	p.HandleFunc(HTTPMethods.DELETE, path, handlersFn...)
+func (p *GardenParty) Delete(path string, handlers ...Handler) IRoute {
+	return p.Handle(HTTPMethods.DELETE, path, handlers...)
 }
 
 // Connect registers a route for the Connect http method
-func (p *GardenParty) Connect(path string, handlersFn ...HandlerFunc) {
-	p.HandleFunc(HTTPMethods.CONNECT, path, handlersFn...)
+func (p *GardenParty) Connect(path string, handlers ...Handler) IRoute {
+	return p.Handle(HTTPMethods.CONNECT, path, handlers...)
 }
 
 // Head registers a route for the Head http method
-func (p *GardenParty) Head(path string, handlersFn ...HandlerFunc) {
-	p.HandleFunc(HTTPMethods.HEAD, path, handlersFn...)
+func (p *GardenParty) Head(path string, handlers ...Handler) IRoute {
+	return p.Handle(HTTPMethods.HEAD, path, handlers...)
 }
 
 // Options registers a route for the Options http method
-func (p *GardenParty) Options(path string, handlersFn ...HandlerFunc) {
-	p.HandleFunc(HTTPMethods.OPTIONS, path, handlersFn...)
+func (p *GardenParty) Options(path string, handlers ...Handler) IRoute {
+	return p.Handle(HTTPMethods.OPTIONS, path, handlers...)
 }
 
 // Patch registers a route for the Patch http method
-func (p *GardenParty) Patch(path string, handlersFn ...HandlerFunc) {
-	p.HandleFunc(HTTPMethods.PATCH, path, handlersFn...)
+func (p *GardenParty) Patch(path string, handlers ...Handler) IRoute {
+	return p.Handle(HTTPMethods.PATCH, path, handlers...)
 }
 
 // Trace registers a route for the Trace http method
-func (p *GardenParty) Trace(path string, handlersFn ...HandlerFunc) {
-	p.HandleFunc(HTTPMethods.TRACE, path, handlersFn...)
+func (p *GardenParty) Trace(path string, handlers ...Handler) IRoute {
+	return p.Handle(HTTPMethods.TRACE, path, handlers...)
 }
 
 // Any registers a route for ALL of the http methods (Get,Post,Put,Head,Patch,Options,Connect,Delete)
-func (p *GardenParty) Any(path string, handlersFn ...HandlerFunc) {
-	p.HandleFunc("", path, handlersFn...)
+func (p *GardenParty) Any(path string, handlers ...Handler) IRoute {
+	return p.Handle("", path, handlers...)
 }
 
 // Ws registers a websocket route
-func (p *GardenParty) Ws(path string, handler Handler) {
-	p.Handle("", path, handler)
+func (p *GardenParty) Ws(path string, handler Handler) IRoute {
+	return p.Handle("", path, handler)
 }
 
 // Use pass the middleware here
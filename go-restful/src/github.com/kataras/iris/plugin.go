@@ -87,6 +87,16 @@ type (
 		PreClose(*Station)
 	}
 
+	// IPluginServeError implements the ServeError(*Station, error) method
+	IPluginServeError interface {
+		// ServeError it's being called when the Server fails while it's running (if .Listen called)
+		// the Server is not running after this state, supervisors can restart it or alert
+		//
+		// first parameter is the station
+		// second parameter is the server's error
+		ServeError(*Station, error)
+	}
+
 	// IPluginPreDownload It's for the future, not being used, I need to create
 	// and return an ActivatedPlugin type which will have it's methods, and pass it on .Activate
 	// but now we return the whole pluginContainer, which I can't determinate which plugin tries to
@@ -106,11 +116,13 @@ type (
 		RemovePlugin(pluginName string)
 		GetByName(pluginName string) IPlugin
 		Printf(format string, a ...interface{})
+		GetLogger(plugin IPlugin) *Logger
 		DoPreHandle(route IRoute)
 		DoPostHandle(route IRoute)
 		DoPreListen(station *Station)
 		DoPostListen(station *Station)
 		DoPreClose(station *Station)
+		DoServeError(station *Station, err error)
 		DoPreDownload(pluginTryToDownload IPlugin, downloadURL string)
 		GetAll() []IPlugin
 		// GetDownloader is the only one module that is used and fire listeners at the same time in this file
@@ -169,6 +181,8 @@ func (d *DownloadManager) Install(remoteFileZip string, targetDirectory string)
 type PluginContainer struct {
 	activatedPlugins []IPlugin
 	downloader       *DownloadManager
+	// logger is the station's logger, nil if the container is not created by a station
+	logger *Logger
 }
 
 var _ IDownloadManager = &DownloadManager{}
@@ -245,10 +259,22 @@ func (p *PluginContainer) GetDownloader() IDownloadManager {
 	return p.downloader
 }
 
-// Printf sends plain text to any registed logger (future), some plugins maybe want use this method
-// maybe at the future I change it, instead of sync even-driven to async channels...
+// Printf sends plain text to the station's logger, with the LevelInfo
 func (p *PluginContainer) Printf(format string, a ...interface{}) {
-	fmt.Printf(format, a...) //for now just this.
+	if p.logger == nil {
+		fmt.Printf(format, a...)
+		return
+	}
+	p.logger.Printf(format, a...)
+}
+
+// GetLogger returns a child of the station's logger which writes the plugin's name with each message,
+// ex: plugin=iriscontrol
+func (p *PluginContainer) GetLogger(plugin IPlugin) *Logger {
+	if p.logger == nil {
+		p.logger = NewLogger(LoggerOutTerminal, "", 0)
+	}
+	return p.logger.With("plugin", plugin.GetName())
 }
 
 // DoPreHandle raise all plugins which has the PreHandle method
@@ -301,6 +327,16 @@ func (p *PluginContainer) DoPreClose(station *Station) {
 	}
 }
 
+// DoServeError raise all plugins which has the DoServeError method
+func (p *PluginContainer) DoServeError(station *Station, err error) {
+	for i := 0; i < len(p.activatedPlugins); i++ {
+		// check if this method exists on our plugin obj, these are optionaly and call it
+		if pluginObj, ok := p.activatedPlugins[i].(IPluginServeError); ok {
+			pluginObj.ServeError(station, err)
+		}
+	}
+}
+
 // DoPreDownload raise all plugins which has the DoPreDownload method
 func (p *PluginContainer) DoPreDownload(pluginTryToDownload IPlugin, downloadURL string) {
 	for i := 0; i < len(p.activatedPlugins); i++ {
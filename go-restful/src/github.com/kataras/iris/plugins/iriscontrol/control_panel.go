@@ -89,30 +89,30 @@ func (i *irisControlPlugin) setPanelRoutes() {
 
 	i.server.Get("/public/*assets", iris.Static(installationPath+"static"+pathSeperator, "/public/"))
 
-	i.server.Get("/login", func(ctx *iris.Context) {
+	i.server.Get("/login", iris.HandlerFunc(func(ctx *iris.Context) {
 		ctx.RenderFile("login.html", nil)
-	})
+	}))
 
-	i.server.Post("/login", func(ctx *iris.Context) {
+	i.server.Post("/login", iris.HandlerFunc(func(ctx *iris.Context) {
 		i.auth.login(ctx)
-	})
+	}))
 
 	i.server.Use(i.auth)
-	i.server.Get("/", func(ctx *iris.Context) {
+	i.server.Get("/", iris.HandlerFunc(func(ctx *iris.Context) {
 		ctx.RenderFile("index.html", DashboardPage{ServerIsRunning: i.station.Server.IsRunning, Routes: i.routes, Plugins: i.plugins})
-	})
+	}))
 
-	i.server.Post("/logout", func(ctx *iris.Context) {
+	i.server.Post("/logout", iris.HandlerFunc(func(ctx *iris.Context) {
 		i.auth.logout(ctx)
-	})
+	}))
 
 	//the controls
-	i.server.Post("/start_server", func(ctx *iris.Context) {
+	i.server.Post("/start_server", iris.HandlerFunc(func(ctx *iris.Context) {
 
-	})
+	}))
 
-	i.server.Post("/stop_server", func(ctx *iris.Context) {
+	i.server.Post("/stop_server", iris.HandlerFunc(func(ctx *iris.Context) {
 
-	})
+	}))
 
 }
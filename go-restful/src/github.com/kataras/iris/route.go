@@ -27,6 +27,8 @@
 package iris
 
 import (
+	"fmt"
+	"net/url"
 	"strings"
 )
 
@@ -40,6 +42,9 @@ type IRoute interface {
 	ProcessPath()
 	GetMiddleware() Middleware
 	SetMiddleware(m Middleware)
+	GetParamConstraints() ParamConstraints
+	Name(name string) IRoute
+	GetName() string
 }
 
 // Route contains basic and temporary info about the route, it is nil after iris.Listen called
@@ -51,6 +56,16 @@ type Route struct {
 	fullpath   string
 	PathPrefix string
 	middleware Middleware
+	// constraints of the named parameters, ex: /users/:id(int), parsed by the ProcessPath
+	constraints ParamConstraints
+	// name is the unique name of the route, for the reverse routing, see Station.URL
+	name string
+	// station is the station which the route is registed to, it's nil if the route is not registed via a Party
+	station *Station
+	// source is the file:line which the route registed from
+	source string
+	// host is the parsed domain, nil if the route has no domain
+	host *hostPattern
 }
 
 var _ IRoute = &Route{}
@@ -85,6 +100,13 @@ func NewRoute(method string, registedPath string, middleware Middleware) *Route
 
 	}
 	r := &Route{method: method, domain: domain, fullpath: registedPath, middleware: middleware}
+	if domain != "" {
+		host, err := parseHostPattern(domain)
+		if err != nil {
+			panic("Iris: Error on route " + method + ":" + domain + registedPath + " " + err.Error())
+		}
+		r.host = host
+	}
 	r.ProcessPath()
 	return r
 }
@@ -115,12 +137,133 @@ func (r Route) GetMiddleware() Middleware {
 }
 
 // SetMiddleware sets the middleware(s)
-func (r Route) SetMiddleware(m Middleware) {
+func (r *Route) SetMiddleware(m Middleware) {
 	r.middleware = m
 }
 
+// GetParamConstraints returns the constraints of the named parameters, if any
+func (r Route) GetParamConstraints() ParamConstraints {
+	return r.constraints
+}
+
+// Name sets the unique name of the route, in order to build its url by the name and the parameters, see Station.URL
+// it panics if the name is already used by an other route of the station
+func (r *Route) Name(name string) IRoute {
+	if r.station != nil {
+		r.station.nameRoute(name, r)
+	}
+	r.name = name
+	return r
+}
+
+// GetName returns the name of the route, if any
+func (r Route) GetName() string {
+	return r.name
+}
+
+// buildURL fills the parameters of the route's path with the values, by order, and returns it
+// an empty value (or nil) for an optional parameter removes its segment, ex: /docs/:version?/intro with "" returns /docs/intro
+// routes with domain are returned without scheme, ex: //admin.mydomain.com/users/42,
+// the values of the domain's parameters are first, ex: :tenant.mydomain.com/users/:id with "kataras", 42
+func (r Route) buildURL(values ...interface{}) (string, error) {
+	keys := paramKeys(r.fullpath)
+	if r.host != nil {
+		keys = append(r.host.keys[:len(r.host.keys):len(r.host.keys)], keys...)
+	}
+	if len(values) != len(keys) {
+		return "", fmt.Errorf("[Iris] Error on URL: route '%s' expects %d parameters %v but %d given", r.name, len(keys), keys, len(values))
+	}
+
+	domain := r.domain
+	if r.host != nil && len(r.host.keys) > 0 {
+		host, err := r.host.build(values[:len(r.host.keys)])
+		if err != nil {
+			return "", fmt.Errorf("[Iris] Error on URL: route '%s', %s", r.name, err.Error())
+		}
+		domain = host
+		keys, values = keys[len(r.host.keys):], values[len(r.host.keys):]
+	}
+
+	var buf strings.Builder
+	v := 0
+	for _, segment := range strings.Split(r.fullpath, Slash)[1:] {
+		optional := strings.HasSuffix(segment, "?")
+		if optional {
+			segment = segment[:len(segment)-1]
+		}
+
+		var seg strings.Builder
+		for i := 0; i < len(segment); i++ {
+			c := segment[i]
+			if c != ParameterStartByte && c != MatchEverythingByte {
+				seg.WriteByte(c)
+				continue
+			}
+			key := keys[v]
+			value := ""
+			if values[v] != nil {
+				value = fmt.Sprint(values[v])
+			}
+			v++
+			i += len(key)
+
+			if value == "" {
+				if optional {
+					break
+				}
+				if c == ParameterStartByte {
+					return "", fmt.Errorf("[Iris] Error on URL: route '%s', empty value for the parameter '%s'", r.name, key)
+				}
+			}
+			if constraint, ok := r.constraints[key]; ok {
+				if _, ok = constraint.Match(value); !ok {
+					return "", fmt.Errorf("[Iris] Error on URL: route '%s', the value '%s' is not valid for the parameter '%s'", r.name, value, key)
+				}
+			}
+
+			if c == MatchEverythingByte {
+				// the match everything parameter's value starts with a slash, ex: /static/*file with /css/main.css
+				parts := strings.Split(strings.TrimPrefix(value, Slash), Slash)
+				for j := range parts {
+					parts[j] = url.PathEscape(parts[j])
+				}
+				seg.WriteString(strings.Join(parts, Slash))
+			} else {
+				seg.WriteString(url.PathEscape(value))
+			}
+		}
+
+		if optional && seg.Len() == 0 {
+			continue
+		}
+		buf.WriteByte(SlashByte)
+		buf.WriteString(seg.String())
+	}
+	if buf.Len() == 0 {
+		buf.WriteByte(SlashByte)
+	}
+
+	if domain != "" {
+		return "//" + domain + buf.String(), nil
+	}
+	return buf.String(), nil
+}
+
 // ProcessPath modifie the path in order to set the path prefix of this Route
+// it removes also the parameters' constraints from the path, ex: /users/:id(int) becomes /users/:id
 func (r *Route) ProcessPath() {
+	cleanPath, constraints, err := stripParamConstraints(r.fullpath)
+	if err != nil {
+		panic("Iris: Error on route " + r.method + ":" + r.fullpath + " " + err.Error())
+	}
+	if constraints != nil {
+		r.fullpath = cleanPath
+		r.constraints = constraints
+	}
+	if err = validatePath(r.fullpath); err != nil {
+		panic("Iris: Error on route " + r.method + ":" + r.fullpath + " " + err.Error())
+	}
+
 	endPrefixIndex := strings.IndexByte(r.fullpath, ParameterStartByte)
 
 	if endPrefixIndex != -1 {
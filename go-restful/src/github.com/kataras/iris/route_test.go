@@ -0,0 +1,102 @@
+package iris
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStationURL(t *testing.T) {
+	s := New()
+	h := HandlerFunc(func(c *Context) {})
+	s.Get("/", h).Name("home")
+	s.Get("/users/:id/edit", h).Name("user.edit")
+	s.Get("/users/:id(int)/posts/:slug", h).Name("user.post")
+	s.Get("/static/*file", h).Name("static")
+	s.Party("/api").Post("/items/:name", h).Name("api.item")
+	s.Get("admin.mydomain.com/users/:id", h).Name("admin.user")
+
+	tests := []struct {
+		name     string
+		values   []interface{}
+		expected string
+		fail     bool
+	}{
+		{"home", nil, "/", false},
+		{"user.edit", []interface{}{42}, "/users/42/edit", false},
+		{"user.edit", []interface{}{"a b/c"}, "/users/a%20b%2Fc/edit", false},
+		{"user.post", []interface{}{7, "hello"}, "/users/7/posts/hello", false},
+		{"user.post", []interface{}{"x", "hello"}, "", true},
+		{"static", []interface{}{"/css/main file.css"}, "/static/css/main%20file.css", false},
+		{"static", []interface{}{"js/app.js"}, "/static/js/app.js", false},
+		{"api.item", []interface{}{"book"}, "/api/items/book", false},
+		{"admin.user", []interface{}{1}, "//admin.mydomain.com/users/1", false},
+		{"user.edit", nil, "", true},
+		{"user.edit", []interface{}{""}, "", true},
+		{"missing", nil, "", true},
+	}
+
+	for i, tt := range tests {
+		u, err := s.URL(tt.name, tt.values...)
+		if tt.fail {
+			if err == nil {
+				t.Fatalf("[%d] Expected an error but got %s", i, u)
+			}
+			continue
+		}
+		if err != nil || u != tt.expected {
+			t.Fatalf("[%d] Expected %s but got %s, error: %v", i, tt.expected, u, err)
+		}
+	}
+}
+
+func TestRouteNameDuplicate(t *testing.T) {
+	s := New()
+	s.Get("/a", HandlerFunc(func(c *Context) {})).Name("a")
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Expected a panic for a duplicate route name")
+		}
+	}()
+	s.Get("/b", HandlerFunc(func(c *Context) {})).Name("a")
+}
+
+func TestContextRedirectTo(t *testing.T) {
+	s := New()
+	s.Get("/users/:id", HandlerFunc(func(c *Context) {})).Name("user")
+	s.Get("admin.mydomain.com/", HandlerFunc(func(c *Context) {})).Name("admin")
+	s.Get("/old/:id", HandlerFunc(func(c *Context) { c.RedirectTo("user", c.Param("id")) }))
+	s.Get("/old-admin", HandlerFunc(func(c *Context) { c.RedirectTo("admin") }))
+	handler := s.Serve()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/old/42", nil))
+	if w.Code != 302 || w.Header().Get("Location") != "/users/42" {
+		t.Fatalf("Expected a redirect to /users/42 but got %d %s", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/old-admin", nil))
+	if w.Header().Get("Location") != "http://admin.mydomain.com/" {
+		t.Fatalf("Expected a redirect to http://admin.mydomain.com/ but got %s", w.Header().Get("Location"))
+	}
+}
+
+func TestTemplatesURL(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "user.html"), []byte(`<a href="{{ url "user.edit" .ID }}">edit</a>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New()
+	s.Templates(filepath.Join(dir, "*.html"))
+	s.Get("/users/:id/edit", HandlerFunc(func(c *Context) {})).Name("user.edit")
+	s.Get("/users/:id", HandlerFunc(func(c *Context) { c.RenderFile("user.html", map[string]string{"ID": c.Param("id")}) }))
+
+	w := httptest.NewRecorder()
+	s.Serve().ServeHTTP(w, httptest.NewRequest("GET", "/users/42", nil))
+	if expected := `<a href="/users/42/edit">edit</a>`; w.Body.String() != expected {
+		t.Fatalf("Expected %s but got %s", expected, w.Body.String())
+	}
+}
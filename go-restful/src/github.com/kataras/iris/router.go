@@ -26,7 +26,11 @@
 // SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 package iris
 
-import "net/http"
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
 
 const (
 	// ParameterStartByte is very used on the node, it's just contains the byte for the ':' rune/char
@@ -144,7 +148,19 @@ func (r *Router) OnError(statusCode int, handlerFunc HandlerFunc) {
 }
 
 // EmitError emits an error with it's http status code and the iris Context passed to the function
+// the handler of the party with the longest path which the request's path starts with is used, if any, see GardenParty.OnError
 func (r *Router) EmitError(statusCode int, ctx *Context) {
+	if h := r.station.partyErrorHandler(statusCode, ctx); h != nil {
+		ctx.WriteStatus(statusCode)
+		h.GetHandler().Serve(ctx)
+		return
+	}
+	if h := r.httpErrors.GetByCode(statusCode); h == nil && statusCode >= http.StatusBadRequest {
+		// an error without handler, ex: a code of the Station.MapError
+		ctx.WriteStatus(statusCode)
+		DefaultErrorHandler(statusCode, strconv.Itoa(statusCode)+" "+strings.ToLower(http.StatusText(statusCode))).Serve(ctx)
+		return
+	}
 	r.httpErrors.Emit(statusCode, ctx)
 }
 
@@ -163,7 +179,18 @@ func (r *Router) OnPanic(handlerFunc HandlerFunc) {
 //
 
 func (r *Router) find(_tree tree, reqPath string, ctx *Context) bool {
+	span := ctx.startSpan("iris.route", SpanKindInternal)
 	middleware, params, mustRedirect := _tree.rootBranch.GetBranch(reqPath, ctx.Params) // pass the parameters here for 0 allocation
+	if span != nil {
+		span.SetAttribute("iris.route.found", middleware != nil)
+		span.End()
+	}
+	return r.serve(_tree, middleware, params, mustRedirect, ctx)
+}
+
+// serve serves the middleware of the route which found for the request,
+// if not found then it redirects to the path with or without the trailing slash (StationOptions.PathCorrection) or it calls the methodFallback
+func (r *Router) serve(_tree tree, middleware Middleware, params PathParameters, mustRedirect bool, ctx *Context) bool {
 	if middleware != nil {
 		ctx.Params = params
 		ctx.middleware = middleware
@@ -171,7 +198,7 @@ func (r *Router) find(_tree tree, reqPath string, ctx *Context) bool {
 		ctx.memoryResponseWriter.ForceHeader()
 		return true
 	} else if mustRedirect && r.station.options.PathCorrection && ctx.Request.Method != HTTPMethods.CONNECT {
-		reqPath = ctx.Request.URL.Path // we re-assign it because reqPath maybe is with the domain/host prefix, with this we made the domain prefix routes works with path correction also
+		reqPath := ctx.Request.URL.Path
 		pathLen := len(reqPath)
 
 		//first of all checks if it's the index only slash /
@@ -199,11 +226,165 @@ func (r *Router) find(_tree tree, reqPath string, ctx *Context) bool {
 
 		return false
 	}
-	ctx.NotFound()
+	r.methodFallback(ctx)
 	return false
 
 }
 
+// lookupHost finds the route of the request, the trees of the request's host are checked first,
+// by the order: domains without parameters, domains with parameters, match everything subdomains, and then the trees without domain
+// the values of the domain's parameters are appended after the path's parameters
+// it returns the tree which found the route, or the tree which has the path with or without the trailing slash if mustRedirect is true
+func (r *Router) lookupHost(method string, methodMatch func(m1, m2 string) bool, ctx *Context, params PathParameters) (_tree tree, middleware Middleware, _ PathParameters, mustRedirect bool) {
+	host := r.station.requestHost(ctx.Request)
+	reqPath := ctx.Request.URL.Path
+	redirect := -1
+	for kind := hostLiteral; kind <= hostNone; kind++ {
+		for i := range r.garden {
+			t := r.garden[i]
+			if t.hostKind() != kind || !methodMatch(t.method, method) {
+				continue
+			}
+			m, p, redirectTo := t.rootBranch.GetBranch(reqPath, params)
+			if m == nil && (!redirectTo || redirect != -1) {
+				continue
+			}
+			if t.host != nil {
+				var ok bool
+				if p, ok = t.host.match(host, p); !ok {
+					continue
+				}
+			}
+			if m == nil {
+				redirect = i
+				continue
+			}
+			return t, m, p, false
+		}
+	}
+	if redirect != -1 {
+		return r.garden[redirect], nil, params, true
+	}
+	return tree{}, nil, params, false
+}
+
+// lookupTree returns the middleware and the parameters of the route which the method's tree has for this request, if any
+func (r *Router) lookupTree(method string, ctx *Context, params PathParameters) (Middleware, PathParameters) {
+	_, middleware, params, _ := r.lookupHost(method, MethodMatch, ctx, params)
+	return middleware, params
+}
+
+// allowedMethods returns the methods which have a route for the request's path, by the order of the HTTPMethods.ANY
+// the HEAD and the OPTIONS are included if they are answered automatically, see StationOptions.AutoHead and StationOptions.AutoOptions
+func (r *Router) allowedMethods(ctx *Context) []string {
+	var allowed []string
+	found, hasGet := false, false
+	for _, method := range HTTPMethods.ANY {
+		middleware, _ := r.lookupTree(method, ctx, nil)
+		registed := middleware != nil
+		found = found || registed
+		switch method {
+		case HTTPMethods.GET:
+			hasGet = registed
+		case HTTPMethods.HEAD:
+			// the GET is before the HEAD
+			registed = registed || (r.station.options.AutoHead && hasGet)
+		case HTTPMethods.OPTIONS:
+			registed = registed || r.station.options.AutoOptions
+		}
+		if registed {
+			allowed = append(allowed, method)
+		}
+	}
+	if !found {
+		return nil
+	}
+	return allowed
+}
+
+// methodFallback is called when the request's method has no route for the request's path
+// it serves a HEAD with the GET route (StationOptions.AutoHead), answers an OPTIONS with the Allow header (StationOptions.AutoOptions),
+// sends 405 with the Allow header if other methods have a route for this path (StationOptions.MethodNotAllowed), otherwise sends 404
+func (r *Router) methodFallback(ctx *Context) {
+	options := r.station.options
+	if !options.AutoHead && !options.AutoOptions && !options.MethodNotAllowed {
+		ctx.NotFound()
+		return
+	}
+
+	if ctx.Request.Method == HTTPMethods.HEAD && options.AutoHead {
+		if middleware, params := r.lookupTree(HTTPMethods.GET, ctx, ctx.Params); middleware != nil {
+			ctx.Params = params
+			ctx.middleware = middleware
+			ctx.ResponseWriter = &headResponseWriter{IMemoryWriter: ctx.ResponseWriter}
+			ctx.Do()
+			ctx.ResponseWriter.ForceHeader()
+			return
+		}
+	}
+
+	allowed := r.allowedMethods(ctx)
+	if len(allowed) == 0 {
+		ctx.NotFound()
+		return
+	}
+
+	if ctx.Request.Method == HTTPMethods.OPTIONS && options.AutoOptions {
+		ctx.ResponseWriter.Header().Set("Allow", strings.Join(allowed, ", "))
+		ctx.ResponseWriter.Header().Set(ContentLength, "0")
+		ctx.WriteStatus(http.StatusOK)
+		ctx.ResponseWriter.ForceHeader()
+		return
+	}
+
+	if options.MethodNotAllowed {
+		ctx.StopExecution()
+		ctx.ResponseWriter.Header().Set("Allow", strings.Join(allowed, ", "))
+		ctx.EmitError(http.StatusMethodNotAllowed)
+		return
+	}
+	ctx.NotFound()
+}
+
+// headResponseWriter discards the body of a GET route which serves a HEAD request
+type headResponseWriter struct {
+	IMemoryWriter
+	size int
+}
+
+func (w *headResponseWriter) Write(data []byte) (int, error) {
+	w.ForceHeader()
+	w.size += len(data)
+	return len(data), nil
+}
+
+func (w *headResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Size returns the size of the discarded body
+func (w *headResponseWriter) Size() int {
+	if !w.IsWritten() {
+		return -1
+	}
+	return w.size
+}
+
+// processDomainRequest is the processRequest of the routers which have routes with domains, see lookupHost
+func (r *Router) processDomainRequest(ctx *Context) bool {
+	span := ctx.startSpan("iris.route", SpanKindInternal)
+	_tree, middleware, params, mustRedirect := r.lookupHost(ctx.Request.Method, r.methodMatch, ctx, ctx.Params)
+	if span != nil {
+		span.SetAttribute("iris.route.found", middleware != nil)
+		span.End()
+	}
+	if middleware == nil && !mustRedirect {
+		r.methodFallback(ctx)
+		return false
+	}
+	return r.serve(_tree, middleware, params, mustRedirect, ctx)
+}
+
 //we use that to the router_memory also
 //returns true if it actually find serve something
 func (r *Router) processRequest(ctx *Context) bool {
@@ -215,7 +396,7 @@ func (r *Router) processRequest(ctx *Context) bool {
 			return r.find(r.garden[i], reqPath, ctx)
 		}
 	}
-	ctx.NotFound()
+	r.methodFallback(ctx)
 	return false
 }
 
@@ -228,11 +409,15 @@ func (r *Router) processRequest(ctx *Context) bool {
 func (r *Router) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	ctx := r.station.pool.Get().(*Context)
 	ctx.Reset(res, req)
+	if r.station.tracing != nil {
+		r.station.startServerSpan(ctx)
+	}
 
 	//defer r.station.pool.Put(ctx)
 	// defer is too slow it adds 10k nanoseconds to the benchmarks...so I will wrap the below to a function
 	r.processRequest(ctx)
 
+	r.station.endServerSpan(ctx)
 	r.station.pool.Put(ctx)
 
 }
@@ -254,34 +439,19 @@ func (r *RouterDomain) getType() RouterType {
 func (r *RouterDomain) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	ctx := r.station.pool.Get().(*Context)
 	ctx.Reset(res, req)
+	if r.station.tracing != nil {
+		r.station.startServerSpan(ctx)
+	}
 
 	//defer r.station.pool.Put(ctx)
 	// defer is too slow it adds 10k nanoseconds to the benchmarks...so I will wrap the below to a function
 	r.processRequest(ctx)
 
+	r.station.endServerSpan(ctx)
 	r.station.pool.Put(ctx)
 
 }
 
-// all these dublicates for this if: if r.garden[i].hosts { but it's 3k nanoseconds faster on non-domain routers, so I keep it FOR NOW I WILL FIND BETTER WAY
 func (r *RouterDomain) processRequest(ctx *Context) bool {
-	reqPath := ctx.Request.URL.Path
-	gLen := len(r.garden)
-	for i := 0; i < gLen; i++ {
-		if r.garden[i].hosts {
-			//it's expecting host
-			if r.garden[i].domain != ctx.Request.Host {
-				//but this is not the host we were expecting, so just continue to the next
-				continue
-			}
-			reqPath = ctx.Request.Host + reqPath
-		}
-
-		if r.methodMatch(r.garden[i].method, ctx.Request.Method) {
-			return r.find(r.garden[i], reqPath, ctx)
-		}
-
-	}
-	ctx.NotFound()
-	return false
+	return r.processDomainRequest(ctx)
 }
@@ -28,7 +28,6 @@ package iris
 
 import (
 	"net/http"
-	"sync"
 	"time"
 )
 
@@ -48,6 +47,8 @@ type MemoryRouter struct {
 	maxitems      int
 	resetDuration time.Duration
 	hasStarted    bool
+	// hosts is true when the MemoryRouterDomain uses this router, its routes have domains
+	hosts bool
 }
 
 // NewMemoryRouter returns a MemoryRouter
@@ -84,21 +85,59 @@ func (r MemoryRouter) getType() RouterType {
 
 // ServeWithPath serves a request
 // The only use of this is to no dublicate this particular code inside the other 2 memory routers.
+//
+// the cache keeps only the middleware and the parameters of the route, each request has its own Context from the pool
 func (r *MemoryRouter) ServeWithPath(path string, res http.ResponseWriter, req *http.Request) {
-	if ctx := r.cache.GetItem(req.Method, path); ctx != nil {
-		ctx.Redo(res, req)
-		return
+	station := r.getStation()
+	var lookupStart time.Time
+	if station.tracing != nil {
+		lookupStart = time.Now()
 	}
-
-	ctx := r.getStation().pool.Get().(*Context)
+	ctx := station.pool.Get().(*Context)
 	ctx.Reset(res, req)
 
-	if r.processRequest(ctx) {
-		//if something found and served then add it's clone to the cache
-		r.cache.AddItem(req.Method, path, ctx.Clone())
+	if route := r.cache.GetItem(req.Method, path); route != nil {
+		ctx.Params = append(ctx.Params, route.Params...)
+		ctx.middleware = route.Middleware
+		r.serveCached(ctx, lookupStart)
+	} else {
+		if station.tracing != nil {
+			station.startServerSpan(ctx)
+			ctx.traceCacheLookup(lookupStart, false)
+		}
+		if r.process(ctx) {
+			//if something found and served then add it's lookup result to the cache, the parameters are copied because the Context is reused
+			cached := &CachedRoute{Middleware: ctx.middleware}
+			if len(ctx.Params) > 0 {
+				cached.Params = make(PathParameters, len(ctx.Params))
+				copy(cached.Params, ctx.Params)
+			}
+			r.cache.AddItem(req.Method, path, cached)
+		}
+		station.endServerSpan(ctx)
 	}
+	station.pool.Put(ctx)
+}
+
+// serveCached serves the cached route of the request, the Context has the route's parameters and middleware
+func (r *MemoryRouter) serveCached(ctx *Context, lookupStart time.Time) {
+	station := r.getStation()
+	if station.tracing != nil {
+		station.startServerSpan(ctx)
+		ctx.traceCacheLookup(lookupStart, true)
+	}
+	ctx.Do()
+	ctx.memoryResponseWriter.ForceHeader()
+	station.endServerSpan(ctx)
+}
 
-	r.getStation().pool.Put(ctx)
+// process finds and serves the route of the request, it checks the domains of the routes if the router has domains
+// the MemoryRouterDomain's processRequest is not called by the ServeWithPath, which is of the underline MemoryRouter
+func (r *MemoryRouter) process(ctx *Context) bool {
+	if r.hosts {
+		return r.processDomainRequest(ctx)
+	}
+	return r.processRequest(ctx)
 }
 
 // ServeHTTP calls processRequest which finds and serves a route by it's request
@@ -114,6 +153,7 @@ type MemoryRouterDomain struct {
 
 // NewMemoryRouterDomain creates a MemoryRouterDomain and returns it
 func NewMemoryRouterDomain(underlineRouter *MemoryRouter) *MemoryRouterDomain {
+	underlineRouter.hosts = true
 	return &MemoryRouterDomain{underlineRouter}
 }
 
@@ -122,40 +162,23 @@ func (r MemoryRouterDomain) getType() RouterType {
 }
 
 func (r *MemoryRouterDomain) processRequest(ctx *Context) bool {
-	reqPath := ctx.Request.URL.Path
-	gLen := len(r.garden)
-	for i := 0; i < gLen; i++ {
-		if r.garden[i].hosts {
-			//it's expecting host
-			if r.garden[i].domain != ctx.Request.Host {
-				//but this is not the host we were expecting, so just continue to the next
-				continue
-			}
-			reqPath = ctx.Request.Host + reqPath
-		}
-		if r.garden[i].method == ctx.Request.Method {
-			return r.find(r.garden[i], reqPath, ctx)
-		}
-
-	}
-	ctx.NotFound()
-	return false
+	return r.processDomainRequest(ctx)
 }
 
 func (r *MemoryRouterDomain) ServeHTTP(res http.ResponseWriter, req *http.Request) {
-	path := req.URL.Path + req.Host
+	path := req.URL.Path + r.getStation().requestHost(req)
 	r.ServeWithPath(path, res, req)
 }
 
-// SyncMemoryRouter is the Router which is routine-thread-safe version of MemoryRouter, used only and only if running cores are > 1
+// SyncMemoryRouter was the routine-thread-safe version of MemoryRouter, used only and only if running cores are > 1
+// now the MemoryRouter and its caches are routine-thread-safe themselves, it's kept for compatibility
 type SyncMemoryRouter struct {
 	IMemoryRouter
-	mu sync.Mutex
 }
 
 // NewSyncRouter creates and returns a new SyncRouter object, from an underline IMemoryRouter
 func NewSyncRouter(underlineRouter IMemoryRouter) *SyncMemoryRouter {
-	return &SyncMemoryRouter{underlineRouter, sync.Mutex{}}
+	return &SyncMemoryRouter{underlineRouter}
 }
 
 func (r *SyncMemoryRouter) getType() RouterType {
@@ -163,14 +186,9 @@ func (r *SyncMemoryRouter) getType() RouterType {
 }
 
 func (r *SyncMemoryRouter) ServeHTTP(res http.ResponseWriter, req *http.Request) {
-	r.mu.Lock()
-
 	path := req.URL.Path
 	if r.IMemoryRouter.getType() == DomainMemory {
-		path += req.Host
+		path += r.getStation().requestHost(req)
 	}
 	r.ServeWithPath(path, res, req)
-
-	r.mu.Unlock()
-
 }
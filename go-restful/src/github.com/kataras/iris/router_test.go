@@ -0,0 +1,85 @@
+package iris
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testRouterMethods(s *Station) http.Handler {
+	s.Get("/users/:id", HandlerFunc(func(c *Context) {
+		c.SetHeader("X-User", []string{c.Param("id")})
+		c.Write("user %s", c.Param("id"))
+	}))
+	s.Post("/users/:id", HandlerFunc(func(c *Context) {}))
+	s.Options("/items", HandlerFunc(func(c *Context) { c.Write("custom options") }))
+	s.Put("/items", HandlerFunc(func(c *Context) {}))
+	return s.Serve()
+}
+
+func TestRouterMethodFallback(t *testing.T) {
+	for _, cache := range []bool{true, false} {
+		options := defaultOptions()
+		options.Cache = cache
+		handler := testRouterMethods(Custom(options))
+
+		tests := []struct {
+			method string
+			path   string
+			status int
+			allow  string
+			body   string
+		}{
+			{"DELETE", "/users/42", http.StatusMethodNotAllowed, "GET, POST, HEAD, OPTIONS", "405 method not allowed"},
+			// twice, the HEAD is not cached as a GET route
+			{"HEAD", "/users/42", http.StatusOK, "", ""},
+			{"HEAD", "/users/42", http.StatusOK, "", ""},
+			{"GET", "/users/42", http.StatusOK, "", "user 42"},
+			{"OPTIONS", "/users/42", http.StatusOK, "GET, POST, HEAD, OPTIONS", ""},
+			{"OPTIONS", "/items", http.StatusOK, "", "custom options"},
+			{"GET", "/items", http.StatusMethodNotAllowed, "PUT, OPTIONS", "405 method not allowed"},
+			{"DELETE", "/missing", http.StatusNotFound, "", "404 not found"},
+			{"OPTIONS", "/missing", http.StatusNotFound, "", "404 not found"},
+		}
+
+		for i, tt := range tests {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.status || w.Header().Get("Allow") != tt.allow || w.Body.String() != tt.body {
+				t.Fatalf("[cache: %v, %d] Expected %d, Allow: %q, body: %q but got %d, Allow: %q, body: %q",
+					cache, i, tt.status, tt.allow, tt.body, w.Code, w.Header().Get("Allow"), w.Body.String())
+			}
+			if tt.method == "HEAD" && w.Header().Get("X-User") != "42" {
+				t.Fatalf("[cache: %v, %d] Expected the headers of the GET route", cache, i)
+			}
+		}
+	}
+}
+
+func TestRouterMethodFallbackDisabled(t *testing.T) {
+	options := defaultOptions()
+	options.MethodNotAllowed = false
+	options.AutoHead = false
+	options.AutoOptions = false
+	handler := testRouterMethods(Custom(options))
+
+	for _, method := range []string{"DELETE", "HEAD", "OPTIONS"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, "/users/42", nil))
+		if w.Code != http.StatusNotFound || w.Header().Get("Allow") != "" {
+			t.Fatalf("[%s] Expected 404 without Allow but got %d %q", method, w.Code, w.Header().Get("Allow"))
+		}
+	}
+}
+
+func TestRouterMethodNotAllowedCustomHandler(t *testing.T) {
+	s := New()
+	s.OnError(http.StatusMethodNotAllowed, func(c *Context) { c.Write("not allowed, use %s", c.ResponseWriter.Header().Get("Allow")) })
+	handler := testRouterMethods(s)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("PATCH", "/items", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Body.String() != "not allowed, use PUT, OPTIONS" {
+		t.Fatalf("Unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
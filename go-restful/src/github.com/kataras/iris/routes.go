@@ -0,0 +1,185 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package iris
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteConflictError is the error which the Handle panics with when a route collides with an already registed route,
+// ex: /users/:id with /users/:name or /files/*path with /files/new
+type RouteConflictError struct {
+	// Route is the route which is being registed
+	Route RouteInfo
+	// Existing is the already registed route
+	Existing RouteInfo
+	// Reason describes the collision
+	Reason string
+}
+
+// Error returns the description of the collision with the routes and where they registed
+func (e *RouteConflictError) Error() string {
+	return fmt.Sprintf("[Iris] Error on Handle: route %s:%s%s (registed at %s) conflicts with the route %s:%s%s (registed at %s), %s",
+		e.Route.Method, e.Route.Domain, e.Route.Path, e.Route.Source,
+		e.Existing.Method, e.Existing.Domain, e.Existing.Path, e.Existing.Source, e.Reason)
+}
+
+// RouteInfo contains the information of a registed route, see Station.Routes
+type RouteInfo struct {
+	Method string
+	Domain string
+	Path   string
+	Name   string
+	// Handler is the name of the last handler, see Context.GetHandlerName
+	Handler string
+	// Middleware are the names of the handlers before the last, by order
+	Middleware []string
+	// Source is the file:line which the route registed from
+	Source string
+}
+
+// RoutesTable is the list of the registed routes, sorted by domain, path and method
+// its String returns a table, useful to print it at the startup: fmt.Println(iris.Routes())
+type RoutesTable []RouteInfo
+
+// String returns the routes as a table with aligned columns
+func (t RoutesTable) String() string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tDOMAIN\tPATH\tNAME\tHANDLER\tMIDDLEWARE")
+	for _, r := range t {
+		method := r.Method
+		if method == "" {
+			method = "ANY"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", method, r.Domain, r.Path, r.Name, r.Handler, strings.Join(r.Middleware, " -> "))
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// Routes returns the registed routes, sorted by domain, path and method
+func (s *Station) Routes() RoutesTable {
+	table := make(RoutesTable, 0, len(s.routes))
+	for _, r := range s.routes {
+		table = append(table, r.info())
+	}
+	sort.SliceStable(table, func(i, j int) bool {
+		if table[i].Domain != table[j].Domain {
+			return table[i].Domain < table[j].Domain
+		}
+		if table[i].Path != table[j].Path {
+			return table[i].Path < table[j].Path
+		}
+		return methodIndex(table[i].Method) < methodIndex(table[j].Method)
+	})
+	return table
+}
+
+// methodIndex returns the index of the method inside the HTTPMethods.ANY, the ANY ("") is the last
+func methodIndex(method string) int {
+	for i, m := range HTTPMethods.ANY {
+		if m == method {
+			return i
+		}
+	}
+	return len(HTTPMethods.ANY)
+}
+
+// info returns the RouteInfo of the route
+func (r *Route) info() RouteInfo {
+	info := RouteInfo{Method: r.method, Domain: r.domain, Path: r.fullpath, Name: r.name, Source: r.source}
+	if n := len(r.middleware); n > 0 {
+		info.Handler = handlerName(r.middleware[n-1])
+		for _, h := range r.middleware[:n-1] {
+			info.Middleware = append(info.Middleware, handlerName(h))
+		}
+	}
+	return info
+}
+
+// handlerName returns the name of the function of a HandlerFunc or the type of any other Handler
+func handlerName(h Handler) string {
+	switch h.(type) {
+	case HandlerFunc, HandlerFuncErr:
+		if f := runtime.FuncForPC(reflect.ValueOf(h).Pointer()); f != nil {
+			return f.Name()
+		}
+	}
+	return fmt.Sprintf("%T", h)
+}
+
+// registrationSource returns the file:line of the first caller outside of this package, which registers a route
+func registrationSource() string {
+	pc := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(3, pc)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "github.com/kataras/iris.") || strings.HasSuffix(frame.File, "_test.go") || !more {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+	}
+}
+
+// checkConflict returns a *RouteConflictError if the route collides with one of the registed routes
+func (s *Station) checkConflict(route *Route) error {
+	for _, existing := range s.routes {
+		if existing.method != route.method || existing.domain != route.domain {
+			continue
+		}
+		if reason := routesConflict(existing, route); reason != "" {
+			return &RouteConflictError{Route: route.info(), Existing: existing.info(), Reason: reason}
+		}
+	}
+	return nil
+}
+
+// routesConflict returns the reason if the two paths, of the same method and domain, match the same requests
+//
+// the routes are matched by priority, static > in-segment parameters > parameter > match everything,
+// so they collide only when they have the same segments, the same parameters with any names, and none of them has constraints,
+// ex: /users/:id and /users/:name or /docs/:version?/intro and /docs/intro
+func routesConflict(a, b *Route) string {
+	if len(a.constraints) > 0 || len(b.constraints) > 0 {
+		return ""
+	}
+	for _, pathA := range expandOptional(a.fullpath) {
+		for _, pathB := range expandOptional(b.fullpath) {
+			if pathShape(pathA) == pathShape(pathB) {
+				return "they match the same paths, use parameters' constraints, ex: :id(int), in order to register both"
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,100 @@
+package iris
+
+import (
+	"strings"
+	"testing"
+)
+
+func testRoutesHandler(c *Context) {}
+
+func testRoutesMiddleware(c *Context) { c.Next() }
+
+func TestRouteConflicts(t *testing.T) {
+	tests := []struct {
+		paths    []string
+		conflict bool
+	}{
+		{[]string{"/users/:id", "/users/:name"}, true},
+		{[]string{"/users/:id", "/users/:id"}, true},
+		{[]string{"/files/*path", "/files/*other"}, true},
+		{[]string{"/docs/:version?/intro", "/docs/intro"}, true},
+		{[]string{"/report-:year.:format", "/report-:y.:f"}, true},
+		{[]string{"/users/:id", "/users/new"}, false},
+		{[]string{"/users/new", "/users/:id"}, false},
+		{[]string{"/files/*path", "/files/new"}, false},
+		{[]string{"/files/", "/files/*path"}, false},
+		{[]string{"/report-:year.:format", "/report-:year"}, false},
+		{[]string{"/users/:id(int)", "/users/:name"}, false},
+		{[]string{"/users/", "/users/:id"}, false},
+		{[]string{"/users/:id/posts", "/users/:name/likes"}, false},
+		{[]string{"/users/:id", "/users/:id/posts"}, false},
+		{[]string{"/src/*path", "/src2/:name"}, false},
+		{[]string{"/a", "/ab"}, false},
+		{[]string{"mydomain.com/users/:id", "/users/new"}, false},
+	}
+
+	for i, tt := range tests {
+		func() {
+			defer func() {
+				r := recover()
+				err, isConflict := r.(*RouteConflictError)
+				if r != nil && !isConflict {
+					panic(r)
+				}
+				if isConflict != tt.conflict {
+					t.Fatalf("[%d] %v expected conflict: %v but got %v", i, tt.paths, tt.conflict, err)
+				}
+				if isConflict {
+					msg := err.Error()
+					if !strings.Contains(msg, tt.paths[0]) || !strings.Contains(msg, tt.paths[1]) || strings.Count(msg, "routes_test.go:") != 2 {
+						t.Fatalf("[%d] the error should name both routes and where they registed: %s", i, msg)
+					}
+				}
+			}()
+			s := New()
+			for _, path := range tt.paths {
+				s.Get(path, HandlerFunc(testRoutesHandler))
+			}
+		}()
+	}
+
+	// other methods don't collide
+	s := New()
+	s.Get("/users/:id", HandlerFunc(testRoutesHandler))
+	s.Post("/users/new", HandlerFunc(testRoutesHandler))
+}
+
+func TestStationRoutes(t *testing.T) {
+	s := New()
+	s.UseFunc(testRoutesMiddleware)
+	s.Post("/users", HandlerFunc(testRoutesHandler))
+	s.Get("/users/:id", HandlerFunc(testRoutesHandler)).Name("user")
+	s.Get("/users", HandlerFunc(testRoutesHandler))
+	s.Get("admin.mydomain.com/", HandlerFunc(testRoutesHandler))
+
+	routes := s.Routes()
+	expected := []string{"GET:/users", "POST:/users", "GET:/users/:id", "GET:admin.mydomain.com/"}
+	if len(routes) != len(expected) {
+		t.Fatalf("Expected %d routes but got %d", len(expected), len(routes))
+	}
+	for i, r := range routes {
+		if got := r.Method + ":" + r.Domain + r.Path; got != expected[i] {
+			t.Fatalf("[%d] Expected %s but got %s", i, expected[i], got)
+		}
+		if r.Handler != "github.com/kataras/iris.testRoutesHandler" || len(r.Middleware) != 1 || r.Middleware[0] != "github.com/kataras/iris.testRoutesMiddleware" {
+			t.Fatalf("[%d] Unexpected handlers %s %v", i, r.Handler, r.Middleware)
+		}
+		if !strings.Contains(r.Source, "routes_test.go:") {
+			t.Fatalf("[%d] Unexpected source %s", i, r.Source)
+		}
+	}
+	if routes[2].Name != "user" {
+		t.Fatalf("Expected the name of the route")
+	}
+
+	table := routes.String()
+	if lines := strings.Split(strings.TrimSpace(table), "\n"); len(lines) != 5 || !strings.HasPrefix(lines[0], "METHOD") ||
+		!strings.Contains(lines[3], " user ") || !strings.Contains(lines[3], "testRoutesMiddleware") {
+		t.Fatalf("Unexpected table:\n%s", table)
+	}
+}
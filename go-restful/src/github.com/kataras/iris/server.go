@@ -27,11 +27,16 @@
 package iris
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -41,9 +46,11 @@ import (
 // go away.
 //
 // this is excatcly a copy of the Go Source (net/http) server.go
-// and it used only on non-tsl server (HTTP/1.1)
+// with a configurable period, see ServerOptions.KeepAlivePeriod
 type tcpKeepAliveListener struct {
 	*net.TCPListener
+	// period is the keep-alive period, if zero then 3 minutes are used
+	period time.Duration
 }
 
 func (ln tcpKeepAliveListener) Accept() (c net.Conn, err error) {
@@ -51,11 +58,64 @@ func (ln tcpKeepAliveListener) Accept() (c net.Conn, err error) {
 	if err != nil {
 		return
 	}
+	period := ln.period
+	if period <= 0 {
+		period = DefaultKeepAlivePeriod
+	}
 	tc.SetKeepAlive(true)
-	tc.SetKeepAlivePeriod(3 * time.Minute)
+	tc.SetKeepAlivePeriod(period)
 	return tc, nil
 }
 
+// limitListener accepts at most 'cap(sem)' connections at the same time, the rest are waiting
+// until one of the accepted connections is closed
+//
+// it's the same as the golang.org/x/net/netutil's LimitListener
+type limitListener struct {
+	net.Listener
+	sem       chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newLimitListener(listener net.Listener, max int) *limitListener {
+	return &limitListener{Listener: listener, sem: make(chan struct{}, max), done: make(chan struct{})}
+}
+
+func (ln *limitListener) Accept() (net.Conn, error) {
+	select {
+	case ln.sem <- struct{}{}:
+	case <-ln.done:
+		// closed, the underline listener returns the error
+		return ln.Listener.Accept()
+	}
+	c, err := ln.Listener.Accept()
+	if err != nil {
+		<-ln.sem
+		return nil, err
+	}
+	return &limitConn{Conn: c, release: func() { <-ln.sem }}, nil
+}
+
+func (ln *limitListener) Close() error {
+	err := ln.Listener.Close()
+	ln.closeOnce.Do(func() { close(ln.done) })
+	return err
+}
+
+// limitConn releases its place on the limitListener when it's closed
+type limitConn struct {
+	net.Conn
+	releaseOnce sync.Once
+	release     func()
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.releaseOnce.Do(c.release)
+	return err
+}
+
 // Server is the container of the tcp listener used to start an http server,
 //
 // it holds it's router and it's config,
@@ -64,8 +124,19 @@ func (ln tcpKeepAliveListener) Accept() (c net.Conn, err error) {
 // Server's New() located at the iris.go file
 type Server struct {
 	// the handler which comes from the station which comes from the router.
-	handler       http.Handler
-	listener      net.Listener
+	handler  http.Handler
+	options  ServerOptions
+	listener net.Listener
+	// server is the underline http.Server which serves the listener
+	server *http.Server
+	// hijacked keeps the connections which are hijacked from the server (websockets), Shutdown waits for them
+	hijacked hijackedConns
+	// closed is closed when the server is closed or shutdown
+	closed    chan struct{}
+	errors    chan error
+	closeOnce sync.Once
+	// mu guards the IsRunning, it's set to false from the serve's goroutine too
+	mu            sync.Mutex
 	IsRunning     bool
 	ListeningAddr string
 	// IsSecure true if ListenTLS (https/http2)
@@ -100,38 +171,221 @@ func ParseAddr(fullHostOrPort []string) string {
 	return addr
 }
 
+var errReusePortUnsupported = errors.New("[Iris] SO_REUSEPORT is not supported on this operating system")
+
+// DefaultKeepAlivePeriod is the default keep-alive period of the accepted tcp connections
+const DefaultKeepAlivePeriod = 3 * time.Minute
+
+// ServerOptions are the options of the standalone http Server
+// they are used by all the .Listen methods (Listen, ListenTLS, ListenUNIX, ListenOn)
+type ServerOptions struct {
+	// ReusePort sets the SO_REUSEPORT option on the tcp listener, in order to run more than one processes on the same port
+	// and let the kernel to balance the connections between them
+	// Supported only on linux and bsd (darwin included)
+	// Default is false
+	ReusePort bool
+
+	// ReadTimeout is the maximum duration for reading the entire request, including the body
+	// Default is 0, no timeout
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout is the amount of time allowed to read the request's headers,
+	// it protects the server from the slow clients (slowloris)
+	// Default is 0, the ReadTimeout is used
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout is the maximum duration before timing out the writes of the response
+	// Default is 0, no timeout
+	WriteTimeout time.Duration
+	// IdleTimeout is the maximum amount of time to wait for the next request when keep-alives are enabled
+	// Default is 0, the ReadTimeout is used
+	IdleTimeout time.Duration
+	// MaxHeaderBytes is the maximum number of bytes the server will read parsing the request's headers, including the request line
+	// Default is 0, the net/http's DefaultMaxHeaderBytes (1MB) is used
+	MaxHeaderBytes int
+	// KeepAlivePeriod is the tcp keep-alive period of the accepted connections
+	// Default is 3 * time.Minute
+	KeepAlivePeriod time.Duration
+	// MaxConnections is the maximum number of the concurrent connections, the rest are waiting to be accepted
+	// Default is 0, no limit
+	MaxConnections int
+	// H2C enables the HTTP/2 without TLS (cleartext) on the .Listen, .ListenUNIX and .ListenOn,
+	// the clients should use the HTTP/2 with prior knowledge, the HTTP/1.1 requests are served too
+	// the .ListenTLS serves the HTTP/2 always
+	// Default is false
+	H2C bool
+}
+
+// ActivationListeners returns the listeners which are passed to this process by the systemd's socket activation
+// or by a parent process which uses the same protocol (LISTEN_PID, LISTEN_FDS environment variables) for zero-downtime restarts
+// the file descriptors are starting from 3
+//
+// returns nil if this process has no passed listeners
+func ActivationListeners() ([]net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	nfds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || nfds <= 0 {
+		return nil, nil
+	}
+	// the children of this process should not use them
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+
+	listeners := make([]net.Listener, nfds)
+	for i := 0; i < nfds; i++ {
+		fd := 3 + i
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		listeners[i], err = net.FileListener(f)
+		// net.FileListener dups the file descriptor, the original one is not needed anymore
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("[Iris] Error on file descriptor %d: %s", fd, err.Error())
+		}
+	}
+	return listeners, nil
+}
+
 // listen starts the standalone http server
 // which listens to the fullHostOrPort parameter which as the form of
 // host:port or just port
+//
+// if nothing passed and a listener is passed to this process (see ActivationListeners) then it serves this listener
 func (s *Server) listen(fullHostOrPort ...string) error {
+	if len(fullHostOrPort) == 0 {
+		listeners, err := ActivationListeners()
+		if err != nil {
+			return err
+		}
+		if len(listeners) > 0 {
+			return s.listenOn(listeners[0])
+		}
+	}
+
 	fulladdr := ParseAddr(fullHostOrPort)
 	//mux := http.NewServeMux() //we use the http's ServeMux for now as the top- middleware of the server, for now.
 
 	//mux.Handle("/", s.handler)
 
 	//return http.ListenAndServe(s.config.Host+strconv.Itoa(s.config.Port), mux)
-	listener, err := net.Listen("tcp", fulladdr)
+	listener, err := s.listenTCP(fulladdr)
 
 	if err != nil {
 		//panic("Cannot run the server [problem with tcp listener on host:port]: " + fulladdr + " err:" + err.Error())
 		return err
 	}
-	s.listener = &tcpKeepAliveListener{listener.(*net.TCPListener)}
-	//err = http.Serve(s.listener, s.handler)
-	//TODO: MAKE IT RETURN A CHANNEL WITH AN ERROR IF NOT NIL THEN THE USER MUST KNOW .
-	//I changed that because we need PostListen on the plugins, the blocking is made at the station level now.
-	go http.Serve(s.listener, s.handler)
-	if err == nil {
-		s.ListeningAddr = fulladdr
-		s.IsRunning = true
-		s.IsSecure = false
-		s.CertFile = ""
-		s.KeyFile = ""
+	s.listener = listener
+	s.ListeningAddr = fulladdr
+	s.setRunning(true)
+	s.IsSecure = false
+	s.CertFile = ""
+	s.KeyFile = ""
+	//the blocking is made at the station level, because we need PostListen on the plugins, the serve errors are sent to the s.errors
+	s.serve(s.newHTTPServer())
+
+	return nil
+}
+
+// newHTTPServer creates the underline http.Server with the timeouts and limits of the options
+func (s *Server) newHTTPServer() *http.Server {
+	return &http.Server{
+		Handler:           s.handler,
+		ReadTimeout:       s.options.ReadTimeout,
+		ReadHeaderTimeout: s.options.ReadHeaderTimeout,
+		WriteTimeout:      s.options.WriteTimeout,
+		IdleTimeout:       s.options.IdleTimeout,
+		MaxHeaderBytes:    s.options.MaxHeaderBytes,
+		Protocols:         s.protocols(),
 	}
+}
 
-	//listener.Close()
+// protocols returns the protocols of the http.Server, nil for the defaults (HTTP/1.1 and HTTP/2 over TLS)
+func (s *Server) protocols() *http.Protocols {
+	if !s.options.H2C {
+		return nil
+	}
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return protocols
+}
 
-	return err
+// limit limits the concurrent connections of the listener if the options.MaxConnections > 0
+func (s *Server) limit(listener net.Listener) net.Listener {
+	if s.options.MaxConnections <= 0 {
+		return listener
+	}
+	return newLimitListener(listener, s.options.MaxConnections)
+}
+
+// serve starts to serve the listener in a goroutine,
+// a fatal error (not caused by .Close or .Shutdown) of the http.Server is sent to the Errors channel
+func (s *Server) serve(httpServer *http.Server) {
+	s.server = httpServer
+	s.closed = make(chan struct{})
+	s.errors = make(chan error, 1)
+
+	go func() {
+		err := httpServer.Serve(s.listener)
+		if err == http.ErrServerClosed || s.isClosed() {
+			return
+		}
+		s.setRunning(false)
+		s.errors <- err
+	}()
+}
+
+// listenTCP creates the tcp listener, with the SO_REUSEPORT if the options.ReusePort is true
+// the accepted connections have the options.KeepAlivePeriod and they are limited by the options.MaxConnections
+func (s *Server) listenTCP(fulladdr string) (net.Listener, error) {
+	lc := net.ListenConfig{}
+	if s.options.ReusePort {
+		lc.Control = reusePortControl
+	}
+	listener, err := lc.Listen(context.Background(), "tcp", fulladdr)
+	if err != nil {
+		return nil, err
+	}
+	return s.limit(&tcpKeepAliveListener{listener.(*net.TCPListener), s.options.KeepAlivePeriod}), nil
+}
+
+// listenUNIX starts the standalone http server which listens to a unix domain socket
+// the file mode of the socket is changed to the given mode
+//
+// a socket file which is already exists (from a previous run) is removed
+func (s *Server) listenUNIX(path string, mode os.FileMode) error {
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("[Iris] Error on ListenUNIX: %s is already exists and it is not a socket", path)
+		}
+		if err = os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if err = os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return err
+	}
+
+	return s.listenOn(listener)
+}
+
+// listenOn starts the standalone http server which serves an already created listener
+func (s *Server) listenOn(listener net.Listener) error {
+	s.listener = s.limit(listener)
+	s.ListeningAddr = listener.Addr().String()
+	s.setRunning(true)
+	s.IsSecure = false
+	s.CertFile = ""
+	s.KeyFile = ""
+	s.serve(s.newHTTPServer())
+
+	return nil
 }
 
 /*///TODO: MANUAL HOOK THE LISTENER, BECAUSE STANDAR NET/HTTP PACKAGE MAKES SO MANY CHECKS MAYBE I CAN DO IT WORKS EVEN FASTER
@@ -154,43 +408,186 @@ func (s *Server) accept(l net.Listener) error {
 // only https:// connections are allowed
 // which listens to the fullHostOrPort parameter which as the form of
 // host:port or just port
+//
+// the certificate and key files are reloaded when they are changed, see CertificateManager
 func (s *Server) listenTLS(fulladdr string, certFile, keyFile string) error {
-	var err error
-	httpServer := http.Server{
-		Addr:    fulladdr,
-		Handler: s.handler,
+	certificates := NewCertificateManager()
+	if err := certificates.AddPair(certFile, keyFile); err != nil {
+		return err
+	}
+	if err := s.listenTLSProvider(fulladdr, certificates); err != nil {
+		return err
 	}
+	s.CertFile = certFile
+	s.KeyFile = keyFile
+	return nil
+}
 
-	config := &tls.Config{}
+// listenTLSProvider Starts a httpS/http2 server which takes the certificates from the provider on each tls handshake
+func (s *Server) listenTLSProvider(fulladdr string, provider ICertificateProvider) error {
+	httpServer := s.newHTTPServer()
+	httpServer.Addr = fulladdr
 
-	configHasCert := len(config.Certificates) > 0 || config.GetCertificate != nil
-	if !configHasCert && certFile != "" && keyFile != "" {
-		config.Certificates = make([]tls.Certificate, 1)
-		config.Certificates[0], err = tls.LoadX509KeyPair(certFile, keyFile)
+	// the listener is served by the http.Server.Serve, so the protocols (ALPN) are set here, as the http.Server.ServeTLS does
+	config := &tls.Config{GetCertificate: provider.GetCertificate, NextProtos: []string{"h2", "http/1.1"}}
+	if _, isACME := provider.(*ACMEManager); isACME {
+		// the tls-alpn-01 challenge is validated by a handshake with this protocol
+		config.NextProtos = append(config.NextProtos, acmeALPNProto)
 	}
+
 	httpServer.TLSConfig = config
-	s.listener, err = tls.Listen("tcp", fulladdr, httpServer.TLSConfig)
+	listener, err := s.listenTCP(fulladdr)
 	if err != nil {
-		panic("Cannot run the server [problem with tcp listener on host:port]: " + fulladdr + " err:" + err.Error())
-	}
-	//TODO: MAKE IT RETURN A CHANNEL WITH AN ERROR IF NOT NIL THEN THE USER MUST KNOW .
-	//err = httpServer.Serve(s.listener)
-	go httpServer.Serve(s.listener)
-	if err == nil {
-		s.IsRunning = true
-		s.IsSecure = true
-		s.ListeningAddr = fulladdr
-		s.CertFile = certFile
-		s.KeyFile = keyFile
+		return err
 	}
-	//s.listener.Close()
-	return err
+	s.listener = tls.NewListener(listener, httpServer.TLSConfig)
+
+	s.setRunning(true)
+	s.IsSecure = true
+	s.ListeningAddr = fulladdr
+	s.CertFile = ""
+	s.KeyFile = ""
+	s.serve(httpServer)
+
+	return nil
+}
+
+// Errors returns the channel which receives the fatal error of the server, if any, while it's running
+// the Station.Listen returns this error, use it only if the server is started by other way
+func (s *Server) Errors() <-chan error {
+	return s.errors
 }
 
 // closeServer is used to close the net.Listener of the standalone http server which has already running via .Listen
 func (s *Server) closeServer() {
-	if s.IsRunning && s.listener != nil {
-		s.listener.Close()
+	s.mu.Lock()
+	running := s.IsRunning && s.listener != nil
+	if running {
 		s.IsRunning = false
 	}
+	s.mu.Unlock()
+	if running {
+		// mark it first, the serve's error is expected now
+		s.markClosed()
+		s.listener.Close()
+	}
+}
+
+// shutdown stops the server from accepting new connections and waits for the active requests
+// and the hijacked connections to finish, until the context is done.
+// If the context is done before that, then it closes all the connections and returns an error
+func (s *Server) shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.IsRunning || s.server == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	s.IsRunning = false
+	s.mu.Unlock()
+	defer s.markClosed()
+
+	err := s.server.Shutdown(ctx)
+	if err == nil {
+		err = s.hijacked.wait(ctx)
+	}
+
+	if err != nil {
+		// time is up, close them
+		s.server.Close()
+		s.hijacked.closeAll()
+		return fmt.Errorf("[Iris] Error on Shutdown: %w", err)
+	}
+	return nil
+}
+
+// setRunning sets the IsRunning, use it instead of writing the field
+func (s *Server) setRunning(running bool) {
+	s.mu.Lock()
+	s.IsRunning = running
+	s.mu.Unlock()
+}
+
+// markClosed unblocks the ones who are waiting the server to be closed, see Station.Listen
+func (s *Server) markClosed() {
+	s.closeOnce.Do(func() {
+		if s.closed != nil {
+			close(s.closed)
+		}
+	})
+}
+
+// isClosed returns true if the server is closed or shutdown
+func (s *Server) isClosed() bool {
+	select {
+	case <-s.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// hijackedConns keeps the connections which are hijacked from the http server, ex: the websockets.
+// The http.Server doesn't track them after the hijack, so the Server.shutdown waits for them here
+type hijackedConns struct {
+	mu    sync.Mutex
+	conns map[*hijackedConn]struct{}
+}
+
+// hijackedConn is a net.Conn which removes itself from the hijackedConns when it's closed
+type hijackedConn struct {
+	net.Conn
+	owner *hijackedConns
+}
+
+// Close closes the connection and removes it from the hijacked connections
+func (c *hijackedConn) Close() error {
+	c.owner.mu.Lock()
+	delete(c.owner.conns, c)
+	c.owner.mu.Unlock()
+	return c.Conn.Close()
+}
+
+// add adds a hijacked connection and returns it wrapped, the returned connection must be used instead
+func (h *hijackedConns) add(conn net.Conn) net.Conn {
+	c := &hijackedConn{Conn: conn, owner: h}
+	h.mu.Lock()
+	if h.conns == nil {
+		h.conns = make(map[*hijackedConn]struct{})
+	}
+	h.conns[c] = struct{}{}
+	h.mu.Unlock()
+	return c
+}
+
+// len returns the number of the open hijacked connections
+func (h *hijackedConns) len() int {
+	h.mu.Lock()
+	n := len(h.conns)
+	h.mu.Unlock()
+	return n
+}
+
+// wait blocks until all hijacked connections are closed or the context is done
+func (h *hijackedConns) wait(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for h.len() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// closeAll closes all the open hijacked connections
+func (h *hijackedConns) closeAll() {
+	h.mu.Lock()
+	conns := h.conns
+	h.conns = nil
+	h.mu.Unlock()
+	for c := range conns {
+		c.Conn.Close()
+	}
 }
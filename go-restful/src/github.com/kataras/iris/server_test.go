@@ -0,0 +1,425 @@
+package iris
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// testListenPlugin notifies when the server is started
+type testListenPlugin struct {
+	listening chan struct{}
+}
+
+func (p *testListenPlugin) GetName() string                   { return "testListenPlugin" }
+func (p *testListenPlugin) GetDescription() string            { return "notifies when the server is started" }
+func (p *testListenPlugin) Activate(c IPluginContainer) error { return nil }
+func (p *testListenPlugin) PostListen(s *Station)             { close(p.listening) }
+
+// listenTest starts the station to a random port and returns the base url of the server and a channel with the Listen's result
+func listenTest(t *testing.T, s *Station) (string, chan error) {
+	result := listenTestWith(t, s, func() error { return s.Listen("127.0.0.1:0") })
+	return "http://" + s.Server.listener.Addr().String(), result
+}
+
+func TestStationShutdown(t *testing.T) {
+	s := New()
+	started := make(chan struct{})
+	s.Get("/slow", HandlerFunc(func(c *Context) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		c.Text("done")
+	}))
+	url, result := listenTest(t, s)
+
+	response := make(chan string, 1)
+	go func() {
+		res, err := http.Get(url + "/slow")
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		body, _ := ioutil.ReadAll(res.Body)
+		response <- string(body)
+	}()
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown should finish without error, but we got: %s", err.Error())
+	}
+
+	if body := <-response; body != "done" {
+		t.Fatalf("Expecting the active request to finish with 'done' but we got '%s'", body)
+	}
+	if err := <-result; err != nil {
+		t.Fatalf("Listen should return nil after Shutdown but we got: %s", err.Error())
+	}
+	if _, err := http.Get(url + "/slow"); err == nil {
+		t.Fatal("Expecting the server to not accept new connections after Shutdown")
+	}
+}
+
+func TestStationShutdownTimeout(t *testing.T) {
+	s := New()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	s.Get("/blocked", HandlerFunc(func(c *Context) {
+		close(started)
+		<-release
+	}))
+	url, result := listenTest(t, s)
+
+	go http.Get(url + "/blocked")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err == nil {
+		t.Fatal("Expecting error from the Shutdown when the timeout is hit")
+	}
+	<-result
+}
+
+func TestStationShutdownHijacked(t *testing.T) {
+	s := New()
+	s.Get("/hijack", HandlerFunc(func(c *Context) {
+		conn, _, err := c.ResponseWriter.Hijack()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		time.AfterFunc(100*time.Millisecond, func() { conn.Close() })
+	}))
+	url, result := listenTest(t, s)
+
+	go http.Get(url + "/hijack")
+	for s.Server.hijacked.len() == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown should wait the hijacked connection, but we got: %s", err.Error())
+	}
+	if n := s.Server.hijacked.len(); n != 0 {
+		t.Fatalf("Expecting zero hijacked connections after Shutdown but we got %d", n)
+	}
+	<-result
+}
+
+// testServeErrorPlugin keeps the error which the server failed with
+type testServeErrorPlugin struct {
+	err error
+}
+
+func (p *testServeErrorPlugin) GetName() string                   { return "testServeErrorPlugin" }
+func (p *testServeErrorPlugin) GetDescription() string            { return "keeps the server's error" }
+func (p *testServeErrorPlugin) Activate(c IPluginContainer) error { return nil }
+func (p *testServeErrorPlugin) ServeError(s *Station, err error)  { p.err = err }
+
+func TestStationListenServeError(t *testing.T) {
+	s := New()
+	p := &testServeErrorPlugin{}
+	s.Plugin(p)
+	_, result := listenTest(t, s)
+
+	// close the listener under the server, the http.Server fails to accept
+	s.Server.listener.Close()
+
+	err := <-result
+	if err == nil {
+		t.Fatal("Expecting Listen to return the server's error")
+	}
+	if p.err != err {
+		t.Fatalf("Expecting the plugin to receive the server's error '%v' but it received '%v'", err, p.err)
+	}
+	// the failed server is not running, so the Close has nothing to do
+	s.Server.mu.Lock()
+	running := s.Server.IsRunning
+	s.Server.mu.Unlock()
+	if running {
+		t.Fatal("Expecting the server to not be running after its error")
+	}
+	s.Close()
+	if s.Server.isClosed() {
+		t.Fatal("Expecting the Close to not mark a failed server as closed")
+	}
+}
+
+func TestStationListenTLSError(t *testing.T) {
+	s := New()
+	if err := s.ListenTLS("127.0.0.1:0", "missing.cert", "missing.key"); err == nil {
+		t.Fatal("Expecting ListenTLS to return an error when the certificates are missing")
+	}
+}
+
+// listenTestWith starts the station with a custom Listen method and blocks until it's started
+func listenTestWith(t *testing.T, s *Station, listen func() error) chan error {
+	p := &testListenPlugin{listening: make(chan struct{})}
+	s.Plugin(p)
+	result := make(chan error, 1)
+	go func() {
+		result <- listen()
+	}()
+	select {
+	case <-p.listening:
+	case err := <-result:
+		t.Fatalf("Listen failed: %v", err)
+	}
+	return result
+}
+
+func TestStationListenUNIX(t *testing.T) {
+	dir, err := ioutil.TempDir("", "iris")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "iris.sock")
+
+	s := New()
+	s.Get("/unix", HandlerFunc(func(c *Context) {
+		c.Text("unix")
+	}))
+	result := listenTestWith(t, s, func() error { return s.ListenUNIX(socket, 0600) })
+	defer func() {
+		s.Close()
+		<-result
+	}()
+
+	if info, err := os.Stat(socket); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("Expecting the socket file with mode 0600, err: %v", err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("unix", socket)
+		},
+	}}
+	res, err := client.Get("http://unix/unix")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer res.Body.Close()
+	if body, _ := ioutil.ReadAll(res.Body); string(body) != "unix" {
+		t.Fatalf("Expecting body 'unix' but we got '%s'", string(body))
+	}
+}
+
+func TestStationListenOn(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	s := New()
+	s.Get("/on", HandlerFunc(func(c *Context) {
+		c.Text("on")
+	}))
+	result := listenTestWith(t, s, func() error { return s.ListenOn(listener) })
+	defer func() {
+		s.Close()
+		<-result
+	}()
+
+	if s.Server.ListeningAddr != listener.Addr().String() {
+		t.Fatalf("Expecting ListeningAddr %s but we got %s", listener.Addr().String(), s.Server.ListeningAddr)
+	}
+	res, err := http.Get("http://" + listener.Addr().String() + "/on")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expecting status 200 but we got %d", res.StatusCode)
+	}
+}
+
+func TestServerReusePort(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("SO_REUSEPORT test runs only on linux")
+	}
+	srv1 := &Server{options: ServerOptions{ReusePort: true}}
+	l1, err := srv1.listenTCP("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer l1.Close()
+
+	srv2 := &Server{options: ServerOptions{ReusePort: true}}
+	l2, err := srv2.listenTCP(l1.Addr().String())
+	if err != nil {
+		t.Fatalf("Expecting the second listener on the same port, but we got: %s", err.Error())
+	}
+	l2.Close()
+}
+
+func TestActivationListenersWithoutEnv(t *testing.T) {
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	if listeners, err := ActivationListeners(); listeners != nil || err != nil {
+		t.Fatalf("Expecting no listeners and no error, but we got %v, %v", listeners, err)
+	}
+}
+
+func TestServerOptions(t *testing.T) {
+	options := defaultOptions()
+	options.Server.ReadHeaderTimeout = 2 * time.Second
+	options.Server.WriteTimeout = 3 * time.Second
+	options.Server.MaxHeaderBytes = 4096
+	s := Custom(options)
+	_, result := listenTest(t, s)
+	defer func() {
+		s.Close()
+		<-result
+	}()
+
+	httpServer := s.Server.server
+	if httpServer.ReadHeaderTimeout != 2*time.Second || httpServer.WriteTimeout != 3*time.Second || httpServer.MaxHeaderBytes != 4096 {
+		t.Fatalf("Expecting the http.Server to have the options' timeouts and limits but we got %v, %v, %d",
+			httpServer.ReadHeaderTimeout, httpServer.WriteTimeout, httpServer.MaxHeaderBytes)
+	}
+}
+
+func TestServerMaxConnections(t *testing.T) {
+	options := defaultOptions()
+	options.Server.MaxConnections = 1
+	s := Custom(options)
+	s.Get("/", HandlerFunc(func(c *Context) {
+		c.Text("ok")
+	}))
+	url, result := listenTest(t, s)
+	defer func() {
+		s.Close()
+		<-result
+	}()
+
+	// keep the only one connection
+	conn, err := net.Dial("tcp", s.Server.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	client := &http.Client{Timeout: 200 * time.Millisecond}
+	if _, err = client.Get(url); err == nil {
+		t.Fatal("Expecting the second connection to wait, the MaxConnections is 1")
+	}
+
+	conn.Close()
+	client.Timeout = 2 * time.Second
+	res, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("Expecting the connection to be accepted after the first one closed, but we got: %s", err.Error())
+	}
+	res.Body.Close()
+}
+
+func TestServerH2C(t *testing.T) {
+	options := defaultOptions()
+	options.Server.H2C = true
+	s := Custom(options)
+	s.Get("/proto", HandlerFunc(func(c *Context) { c.Write("%s", c.Request.Proto) }))
+	url, result := listenTest(t, s)
+	defer func() {
+		s.Close()
+		<-result
+	}()
+
+	protocols := &http.Protocols{}
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	defer client.CloseIdleConnections()
+	res, err := client.Get(url + "/proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "HTTP/2.0" {
+		t.Fatalf("Expecting HTTP/2.0 but got %q", body)
+	}
+
+	// the HTTP/1.1 is served too
+	res, err = http.Get(url + "/proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "HTTP/1.1" {
+		t.Fatalf("Expecting HTTP/1.1 but got %q", body)
+	}
+}
+
+func TestServerHTTP2TLS(t *testing.T) {
+	certificates := NewCertificateManager()
+	if err := certificates.AddPair(writeTestCertificate(t, t.TempDir(), 1, "localhost")); err != nil {
+		t.Fatal(err)
+	}
+	s := New()
+	s.Get("/proto", HandlerFunc(func(c *Context) {
+		// the go client disables the server push, it should be a no-op
+		if err := c.Push("/style.css", nil); err != nil {
+			t.Errorf("Expecting Push to be a no-op but got %v", err)
+		}
+		c.Write("%s", c.Request.Proto)
+	}))
+	result := listenTestWith(t, s, func() error { return s.ListenTLSProvider("127.0.0.1:0", certificates) })
+	defer func() {
+		s.Close()
+		<-result
+	}()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, ForceAttemptHTTP2: true}}
+	defer client.CloseIdleConnections()
+	res, err := client.Get("https://" + s.Server.listener.Addr().String() + "/proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "HTTP/2.0" {
+		t.Fatalf("Expecting HTTP/2.0 but got %q", body)
+	}
+}
+
+// testPusher is a http.ResponseWriter which supports the server push
+type testPusher struct {
+	http.ResponseWriter
+	targets []string
+}
+
+func (p *testPusher) Push(target string, opts *http.PushOptions) error {
+	p.targets = append(p.targets, target)
+	return nil
+}
+
+func TestContextPush(t *testing.T) {
+	s := New()
+	s.Get("/", HandlerFunc(func(c *Context) {
+		if err := c.Push("/style.css", nil); err != nil {
+			t.Errorf("Push failed: %v", err)
+		}
+	}))
+	handler := s.Serve()
+
+	pusher := &testPusher{ResponseWriter: httptest.NewRecorder()}
+	handler.ServeHTTP(pusher, httptest.NewRequest("GET", "/", nil))
+	if len(pusher.targets) != 1 || pusher.targets[0] != "/style.css" {
+		t.Fatalf("Expecting the push to be forwarded but got %v", pusher.targets)
+	}
+
+	// HTTP/1.x, no-op
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
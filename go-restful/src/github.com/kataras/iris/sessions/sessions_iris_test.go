@@ -69,7 +69,7 @@ func TestSessionsWithIris(t *testing.T) {
 	store := NewCookieStore(secret)
 	wrapper := New("test_sessions", store)
 
-	iris.Get("/test_set", func(c *iris.Context) {
+	iris.Get("/test_set", iris.HandlerFunc(func(c *iris.Context) {
 		//get the session for this context
 		session, err := wrapper.Get(c)
 
@@ -88,9 +88,9 @@ func TestSessionsWithIris(t *testing.T) {
 
 		//write anthing
 		c.SendStatus(200, "ok")
-	})
+	}))
 
-	iris.Get("/test_get", func(c *iris.Context) {
+	iris.Get("/test_get", iris.HandlerFunc(func(c *iris.Context) {
 		//again get the session for this context
 		session, err := wrapper.Get(c)
 
@@ -112,9 +112,9 @@ func TestSessionsWithIris(t *testing.T) {
 		}
 
 		c.SendStatus(200, "ok")
-	})
+	}))
 
-	iris.Get("/test_clear", func(c *iris.Context) {
+	iris.Get("/test_clear", iris.HandlerFunc(func(c *iris.Context) {
 		session, err := wrapper.Get(c)
 		if err != nil {
 			t.Fatal("Sessions error: " + err.Error())
@@ -132,7 +132,7 @@ func TestSessionsWithIris(t *testing.T) {
 		}
 		c.SendStatus(200, "ok")
 
-	})
+	}))
 
 	res := new(fakeResponseWriter)
 	req, _ := http.NewRequest("GET", "/", nil)
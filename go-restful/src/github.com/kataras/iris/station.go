@@ -27,12 +27,18 @@
 package iris
 
 import (
+	"context"
+	"fmt"
 	"html/template"
+	"net"
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -86,6 +92,11 @@ type (
 		//
 		// If CacheMaxItems <= 0 then it clears the whole cache bag at this duration.
 		CacheResetDuration time.Duration
+		// CachePolicy is the type of the cache, CacheTick, CacheLRU or CacheTinyLFU
+		// the CacheLRU and CacheTinyLFU never have more than CacheMaxItems routes (DefaultCacheMaxItems if CacheMaxItems <= 0),
+		// they don't use the CacheResetDuration, see BoundedContextCache
+		// Default is CacheTick
+		CachePolicy CachePolicy
 
 		// PathCorrection corrects and redirects the requested path to the registed path
 		// for example, if /home/ path is requested but no handler for this Route found,
@@ -94,6 +105,36 @@ type (
 		//
 		// Default is true
 		PathCorrection bool
+
+		// MethodNotAllowed sends 405 with the Allow header, which lists the methods of the requested path, instead of 404
+		// when the path is registed with other methods, the 405 error handler can be changed with the iris.OnError(405,...)
+		// Default is true
+		MethodNotAllowed bool
+
+		// AutoHead serves the HEAD requests with the GET routes, without the body, if there is no HEAD route for the requested path
+		// Default is true
+		AutoHead bool
+
+		// AutoOptions answers the OPTIONS requests with the Allow header, if there is no OPTIONS route for the requested path
+		// Default is true
+		AutoOptions bool
+
+		// TrustForwardedHost uses the X-Forwarded-Host header, which a proxy sets, as the request's host for the routes with domains,
+		// enable it only when the server is behind a proxy which sets this header, otherwise a client can choose the domain's routes
+		// Default is false
+		TrustForwardedHost bool
+
+		// ShutdownTimeout is the maximum duration which the server waits for the active requests and the websocket connections
+		// to finish when an interrupt or terminate signal received while .Listen/.ListenTLS is running
+		// Default is 10 * time.Second
+		ShutdownTimeout time.Duration
+
+		// MaxBodySize is the maximum size of the request body which the Context.Bind reads, in bytes
+		// Default is 32MB (DefaultMaxBodySize)
+		MaxBodySize int64
+
+		// Server contains the options of the standalone http server, used by the .Listen methods
+		Server ServerOptions
 	}
 
 	// Station is the container of all, server, router, cache and the sync.Pool
@@ -107,6 +148,22 @@ type (
 		//it's true if OptimusPrime has run one time
 		optimized bool
 		logger    *Logger
+		// bindErrorHandler renders the errors of the Context.MustBind
+		bindErrorHandler func(*Context, *BindError)
+		// namedRoutes are the routes with a name, see Route.Name and Station.URL
+		namedRoutes map[string]*Route
+		// routes are the registed routes, by the order they registed, see Station.Routes
+		routes []*Route
+		// routePaths are the registed paths of the routes by the first handler of their middleware, see Context.GetRoutePath
+		routePaths map[*Handler]string
+		// partyErrors are the error handlers of the parties, the ones with the longest path first, see GardenParty.OnError
+		partyErrors []*partyErrors
+		// errorHandler handles the errors of the handlers, nil for the default, see Station.SetErrorHandler
+		errorHandler func(*Context, error)
+		// errorStatuses are the http status codes of the errors, see Station.MapError
+		errorStatuses []errorStatus
+		// tracing is nil if the tracing is disabled, see Station.Tracing
+		tracing *TracingOptions
 	}
 )
 
@@ -117,7 +174,9 @@ var _ IStation = &Station{}
 // newStation creates and returns a station, is used only inside main file iris.go
 func newStation(options StationOptions) *Station {
 	// create the station
-	s := &Station{options: options, pluginContainer: &PluginContainer{}}
+	s := &Station{options: options, routePaths: make(map[*Handler]string)}
+	s.logger = NewLogger(LoggerOutTerminal, "", 0)
+	s.pluginContainer = &PluginContainer{logger: s.logger}
 	// create the router
 	var r IRouter
 	//for now, we can't directly use NewRouter and after NewMemoryRouter, types are not the same.
@@ -230,11 +289,13 @@ func (s *Station) forceOptimusPrime() {
 		if !r.hasCache() {
 			var cache IContextCache
 
-			cache = NewContextCache()
-			//check if we have more than one core then use theMemoryRouterCache,otherwise use the SyncMemoryRouterCache and it's underline MemoryRouterCache
-			if runtime.GOMAXPROCS(-1) > 1 {
-				cache = NewSyncContextCache(cache.(*ContextCache))
-				//println("SYNCED MULTI_CORE CACHE WITH CORES: ", runtime.GOMAXPROCS(-1))
+			switch s.options.CachePolicy {
+			case CacheLRU:
+				cache = NewLRUContextCache(s.options.CacheMaxItems)
+			case CacheTinyLFU:
+				cache = NewTinyLFUContextCache(s.options.CacheMaxItems)
+			default:
+				cache = NewContextCache()
 			}
 
 			r.setCache(cache)
@@ -287,22 +348,12 @@ func (s *Station) HasOptimized() bool {
 // Listen starts the standalone http server
 // which listens to the fullHostOrPort parameter which as the form of
 // host:port or just port
+//
+// if nothing passed and this process has a passed listener (systemd's socket activation, see ActivationListeners) then this listener is used
 func (s *Station) Listen(fullHostOrPort ...string) error {
-	s.OptimusPrime()
-
-	s.pluginContainer.DoPreListen(s)
-	// I moved the s.Server here because we want to be able to change the Router before listen (with plugins)
-	// set the server with the server handler
-	s.Server = &Server{handler: s.IRouter}
-	err := s.Server.listen(fullHostOrPort...)
-	if err == nil {
-		s.pluginContainer.DoPostListen(s)
-		ch := make(chan os.Signal)
-		<-ch
-		s.Close()
-	}
-
-	return err
+	return s.start(func(srv *Server) error {
+		return srv.listen(fullHostOrPort...)
+	})
 }
 
 // ListenTLS Starts a httpS/http2 server with certificates,
@@ -311,22 +362,152 @@ func (s *Station) Listen(fullHostOrPort ...string) error {
 // which listens to the fullHostOrPort parameter which as the form of
 // host:port or just port
 func (s *Station) ListenTLS(fullAddress string, certFile, keyFile string) error {
+	return s.start(func(srv *Server) error {
+		return srv.listenTLS(fullAddress, certFile, keyFile)
+	})
+}
+
+// ListenTLSProvider Starts a httpS/http2 server which takes the certificates from the provider,
+// ex: a CertificateManager with a certificate for each domain or an ACMEManager
+// if the provider is an IDomainsReceiver then it receives the domains of the registed routes before the server is started,
+// ex: the CertificateManager fails to start if a domain has no certificate
+func (s *Station) ListenTLSProvider(fullAddress string, provider ICertificateProvider) error {
+	return s.start(func(srv *Server) error {
+		if receiver, ok := provider.(IDomainsReceiver); ok {
+			if err := receiver.SetDomains(s.domains()); err != nil {
+				return err
+			}
+		}
+		if acme, ok := provider.(*ACMEManager); ok && acme.Logger == nil {
+			acme.Logger = s.GetLogger()
+		}
+		return srv.listenTLSProvider(fullAddress, provider)
+	})
+}
+
+// ListenUNIX starts the standalone http server which listens to a unix domain socket
+// first parameter is the path of the socket file, an old socket file with this path is removed
+// second parameter is the file mode of the socket file, ex: 0666
+func (s *Station) ListenUNIX(path string, mode os.FileMode) error {
+	return s.start(func(srv *Server) error {
+		return srv.listenUNIX(path, mode)
+	})
+}
+
+// ListenOn starts the standalone http server which serves an already created listener
+// useful for custom listeners, ex: the ones from the ActivationListeners
+func (s *Station) ListenOn(listener net.Listener) error {
+	return s.start(func(srv *Server) error {
+		return srv.listenOn(listener)
+	})
+}
+
+// start is used by all the Listen methods, it calls the plugins and blocks until the server is closed
+func (s *Station) start(listen func(*Server) error) error {
 	s.OptimusPrime()
 	s.pluginContainer.DoPreListen(s)
 	// I moved the s.Server here because we want to be able to change the Router before listen (with plugins)
 	// set the server with the server handler
-	s.Server = &Server{handler: s.IRouter}
-	err := s.Server.listenTLS(fullAddress, certFile, keyFile)
+	s.Server = &Server{handler: s.IRouter, options: s.options.Server}
+	err := listen(s.Server)
 	if err == nil {
 		s.pluginContainer.DoPostListen(s)
-		ch := make(chan os.Signal)
-		<-ch
-		s.Close()
+		err = s.wait()
 	}
 
 	return err
 }
 
+// wait blocks until the server is closed (via .Close or .Shutdown), the server fails
+// or an interrupt/terminate signal received, then it shutdowns the server gracefully, with the options.ShutdownTimeout
+//
+// returns the server's error, if any
+func (s *Station) wait() error {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(ch)
+
+	select {
+	case <-ch:
+		ctx, cancel := context.WithTimeout(context.Background(), s.options.ShutdownTimeout)
+		defer cancel()
+		return s.Shutdown(ctx)
+	case err := <-s.Server.Errors():
+		s.pluginContainer.DoServeError(s, err)
+		return err
+	case <-s.Server.closed:
+		return nil
+	}
+}
+
+// OnBindError sets the handler which renders the errors of the Context.MustBind
+// the default handler sends the error as JSON with the error's status code (400, 413, 415 or 422)
+func (s *Station) OnBindError(handler func(ctx *Context, err *BindError)) {
+	s.bindErrorHandler = handler
+}
+
+// CacheStats returns the counters of the router's cache,
+// they are zero if the cache is disabled or it doesn't keep counters (only the CacheLRU and CacheTinyLFU keep them)
+func (s *Station) CacheStats() ContextCacheStats {
+	if r, ok := s.IRouter.(IMemoryRouter); ok && r.getCache() != nil {
+		if cache, ok := r.getCache().(IContextCacheStats); ok {
+			return cache.Stats()
+		}
+	}
+	return ContextCacheStats{}
+}
+
+// nameRoute registers the route with its name, it panics if the name is already used
+func (s *Station) nameRoute(name string, route *Route) {
+	if name == "" {
+		panic("[Iris] Error on Route.Name: empty name for the route " + route.method + ":" + route.domain + route.fullpath)
+	}
+	if s.namedRoutes == nil {
+		s.namedRoutes = make(map[string]*Route)
+	}
+	if existing, ok := s.namedRoutes[name]; ok && existing != route {
+		panic("[Iris] Error on Route.Name: the name '" + name + "' is already used by the route " + existing.method + ":" + existing.domain + existing.fullpath)
+	}
+	if route.name != "" && route.name != name {
+		delete(s.namedRoutes, route.name)
+	}
+	s.namedRoutes[name] = route
+}
+
+// URL returns the path of the route which has this name, the parameters (:name and *name) are filled with the values by order,
+// ex: iris.Get("/users/:id/edit", h).Name("user.edit") and iris.URL("user.edit", 42) returns /users/42/edit
+//
+// routes with domain are returned without the scheme, ex: //admin.mydomain.com/users/42
+// it's available inside the templates too: {{ url "user.edit" .ID }}
+func (s *Station) URL(name string, values ...interface{}) (string, error) {
+	route, ok := s.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("[Iris] Error on URL: route with name '%s' doesn't exists", name)
+	}
+	return route.buildURL(values...)
+}
+
+// domains returns the unique domains of the registed routes, without the ports
+// the domains with parameters, ex: :tenant.mydomain.com or *.mydomain.com, are not included
+func (s *Station) domains() []string {
+	var domains []string
+	seen := make(map[string]bool)
+	for _, t := range s.IRouter.getGarden() {
+		if t.hostKind() != hostLiteral {
+			continue
+		}
+		domain := t.domain
+		if host, _, err := net.SplitHostPort(domain); err == nil {
+			domain = host
+		}
+		if domain != "" && !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
 // Serve is used instead of the iris.Listen
 // eg  http.ListenAndServe(":80",iris.Serve()) if you don't want to use iris.Listen(":80")
 func (s *Station) Serve() http.Handler {
@@ -342,11 +523,43 @@ func (s *Station) Close() {
 
 }
 
+// Shutdown gracefully shutdowns the server, it stops accepting new connections
+// and waits for the active requests and the websocket connections to finish, until the context is done.
+// If the context is done before that, then all the connections are closed and an error is returned
+func (s *Station) Shutdown(ctx context.Context) error {
+	s.pluginContainer.DoPreClose(s)
+	if s.Server == nil {
+		return nil
+	}
+	return s.Server.shutdown(ctx)
+}
+
+// templateFuncs returns the functions which are available inside the templates
+// url: {{ url "user.edit" .ID }}, see Station.URL
+func (s *Station) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"url": s.URL,
+	}
+}
+
+// parseTemplates parses the files of the glob with the template functions, as the template.ParseGlob does
+func (s *Station) parseTemplates(pathGlob string) (*template.Template, error) {
+	filenames, err := filepath.Glob(pathGlob)
+	if err != nil {
+		return nil, err
+	}
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("[Iris] Error on Templates: pattern matches no files: %#q", pathGlob)
+	}
+	return template.New(filepath.Base(filenames[0])).Funcs(s.templateFuncs()).ParseFiles(filenames...)
+}
+
 // Templates sets the templates glob path for the web app
+// the templates can use the url function for the named routes, ex: {{ url "user.edit" .ID }}
 func (s *Station) Templates(pathGlob string) {
 	var err error
 	//s.htmlTemplates = template.Must(template.ParseGlob(pathGlob))
-	s.templates, err = template.ParseGlob(pathGlob)
+	s.templates, err = s.parseTemplates(pathGlob)
 
 	if err != nil {
 		//if err then try to load the same path but with the current directory prefix
@@ -356,7 +569,7 @@ func (s *Station) Templates(pathGlob string) {
 			panic(err.Error())
 
 		}
-		s.templates, cerr = template.ParseGlob(pwd + pathGlob)
+		s.templates, cerr = s.parseTemplates(pwd + pathGlob)
 		if cerr != nil {
 			panic(err.Error())
 		}
@@ -0,0 +1,265 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package iris
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ContentEventStream is the Content-Type of the Server-Sent Events
+	ContentEventStream = "text/event-stream"
+	// DefaultSSEHeartbeat is the default interval of the SSEWriter's heartbeat comments, 15 seconds
+	DefaultSSEHeartbeat = 15 * time.Second
+)
+
+// ErrStreamClosed is returned from the SSEWriter when the client has gone or the writer is closed
+var ErrStreamClosed = errors.New("[Iris] Error on Stream: the client has gone or the stream is closed")
+
+// SSEvent is a Server-Sent Event
+type SSEvent struct {
+	// ID is the id of the event, the client sends it back on reconnect with the Last-Event-ID header
+	ID string
+	// Event is the name of the event, the client listens to it with the addEventListener, empty means 'message'
+	Event string
+	// Retry is the time which the client waits before reconnect, zero means the client's default
+	Retry time.Duration
+	// Data is the payload of the event, strings and []byte are sent as they are, other values as JSON
+	Data interface{}
+}
+
+// SSEWriter writes Server-Sent Events to the client, it's created by the Context.SSE
+//
+// Usage:
+// sse := ctx.SSE()
+// defer sse.Close()
+// sse.Heartbeat(iris.DefaultSSEHeartbeat)
+//
+//	for {
+//		select {
+//		case <-sse.CloseNotify():
+//			return
+//		case progress := <-updates:
+//			sse.Send(iris.SSEvent{Event: "progress", Data: progress})
+//		}
+//	}
+type SSEWriter struct {
+	ctx *Context
+	mu  sync.Mutex
+	// closeNotify is closed when the client has gone or the writer is closed
+	closeNotify chan bool
+	// closing stops the heartbeat and the client's watcher
+	closing   chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+	err       error
+}
+
+// SSE sets the headers of the Server-Sent Events, flushes them and returns an SSEWriter,
+// the handler should not return before the sse.Close
+func (ctx *Context) SSE() *SSEWriter {
+	h := ctx.ResponseWriter.Header()
+	h.Set(ContentType, ContentEventStream+" ;charset="+Charset)
+	h.Set("Cache-Control", "no-cache")
+	// for nginx, do not buffer the response
+	h.Set("X-Accel-Buffering", "no")
+	h.Del(ContentLength)
+	ctx.WriteStatus(http.StatusOK)
+	ctx.ResponseWriter.ForceHeader()
+
+	s := &SSEWriter{ctx: ctx, closeNotify: make(chan bool), closing: make(chan struct{})}
+	s.flush()
+	clientGone := ctx.clientGone()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		select {
+		case <-clientGone:
+		case <-s.closing:
+		}
+		s.mu.Lock()
+		if s.err == nil {
+			s.err = ErrStreamClosed
+		}
+		s.mu.Unlock()
+		close(s.closeNotify)
+	}()
+	return s
+}
+
+// CloseNotify returns a channel which is closed when the client has gone or the writer is closed
+func (s *SSEWriter) CloseNotify() <-chan bool {
+	return s.closeNotify
+}
+
+// Send writes and flushes an event
+func (s *SSEWriter) Send(event SSEvent) error {
+	if strings.ContainsAny(event.ID, "\r\n") || strings.ContainsAny(event.Event, "\r\n") {
+		return errors.New("[Iris] Error on SSEWriter.Send: the id and the event should not contain new lines")
+	}
+
+	var buf bytes.Buffer
+	if event.ID != "" {
+		buf.WriteString("id: " + event.ID + "\n")
+	}
+	if event.Event != "" {
+		buf.WriteString("event: " + event.Event + "\n")
+	}
+	if event.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(int64(event.Retry/time.Millisecond), 10) + "\n")
+	}
+
+	var data string
+	switch v := event.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+	data = strings.ReplaceAll(strings.ReplaceAll(data, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteByte('\n')
+	return s.write(buf.Bytes())
+}
+
+// Comment writes and flushes a comment, the clients ignore it, it's used to keep the connection alive
+func (s *SSEWriter) Comment(text string) error {
+	var buf bytes.Buffer
+	for _, line := range strings.Split(text, "\n") {
+		buf.WriteString(": " + line + "\n")
+	}
+	buf.WriteByte('\n')
+	return s.write(buf.Bytes())
+}
+
+// Heartbeat writes a comment every interval until the client has gone or the writer is closed,
+// it keeps the connection alive through proxies which close the idle connections
+func (s *SSEWriter) Heartbeat(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSSEHeartbeat
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.closeNotify:
+				return
+			case <-ticker.C:
+				if s.Comment("heartbeat") != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the heartbeat and waits for it, the next writes return ErrStreamClosed.
+// It should be called before the handler returns, the Context is reused after
+func (s *SSEWriter) Close() {
+	s.closeOnce.Do(func() { close(s.closing) })
+	s.wg.Wait()
+}
+
+func (s *SSEWriter) write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if _, err := s.ctx.ResponseWriter.Write(b); err != nil {
+		s.err = err
+		return err
+	}
+	s.flush()
+	return nil
+}
+
+func (s *SSEWriter) flush() {
+	if flusher, ok := s.ctx.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Stream writes a chunked response, it executes the step, which writes to the w, and flushes, until the step returns false or the client has gone.
+// Returns true if the client has gone before the end
+func (ctx *Context) Stream(step func(w io.Writer) bool) bool {
+	ctx.ResponseWriter.Header().Del(ContentLength)
+	clientGone := ctx.clientGone()
+	flusher, _ := ctx.ResponseWriter.(http.Flusher)
+	for {
+		select {
+		case <-clientGone:
+			return true
+		default:
+			keepOpen := step(ctx.ResponseWriter)
+			if flusher != nil {
+				flusher.Flush()
+			}
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+// clientGone returns a channel which receives when the client has gone, by the CloseNotify and the request's context
+func (ctx *Context) clientGone() <-chan struct{} {
+	gone := make(chan struct{})
+	closeNotify := ctx.ResponseWriter.CloseNotify()
+	done := ctx.Request.Context().Done()
+	if closeNotify == nil {
+		return done
+	}
+	go func() {
+		select {
+		case <-closeNotify:
+		case <-done:
+		}
+		close(gone)
+	}()
+	return gone
+}
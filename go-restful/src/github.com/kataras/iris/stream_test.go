@@ -0,0 +1,130 @@
+package iris
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextSSE(t *testing.T) {
+	s := New()
+	s.Get("/events", HandlerFunc(func(c *Context) {
+		sse := c.SSE()
+		defer sse.Close()
+		sse.Send(SSEvent{ID: "1", Event: "progress", Retry: 3 * time.Second, Data: map[string]int{"percent": 50}})
+		sse.Send(SSEvent{Data: "line1\nline2"})
+		if err := sse.Send(SSEvent{Event: "bad\nevent"}); err == nil {
+			t.Errorf("Expected an error for an event with new line")
+		}
+		sse.Comment("bye")
+	}))
+	srv := httptest.NewServer(s.Serve())
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); !strings.HasPrefix(ct, ContentEventStream) || res.Header.Get("Cache-Control") != "no-cache" {
+		t.Fatalf("Unexpected headers %v", res.Header)
+	}
+	body, _ := io.ReadAll(res.Body)
+	expected := "id: 1\nevent: progress\nretry: 3000\ndata: {\"percent\":50}\n\n" +
+		"data: line1\ndata: line2\n\n" +
+		": bye\n\n"
+	if string(body) != expected {
+		t.Fatalf("Expected body %q but got %q", expected, string(body))
+	}
+}
+
+func TestContextSSEHeartbeatClientGone(t *testing.T) {
+	done := make(chan error, 1)
+	s := New()
+	s.Get("/events", HandlerFunc(func(c *Context) {
+		sse := c.SSE()
+		defer sse.Close()
+		sse.Heartbeat(10 * time.Millisecond)
+		select {
+		case <-sse.CloseNotify():
+			done <- sse.Send(SSEvent{Data: "late"})
+		case <-time.After(5 * time.Second):
+			done <- fmt.Errorf("client disconnect was not detected")
+		}
+	}))
+	srv := httptest.NewServer(s.Serve())
+	defer srv.Close()
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(reqCtx, "GET", srv.URL+"/events", nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	if err != nil || line != ": heartbeat\n" {
+		t.Fatalf("Expected a heartbeat but got %q, error: %v", line, err)
+	}
+	cancel()
+	res.Body.Close()
+
+	if err := <-done; err != ErrStreamClosed {
+		t.Fatalf("Expected ErrStreamClosed after the client has gone but got %v", err)
+	}
+}
+
+func TestContextStream(t *testing.T) {
+	var clientGone bool
+	s := New()
+	s.Get("/stream", HandlerFunc(func(c *Context) {
+		i := 0
+		clientGone = c.Stream(func(w io.Writer) bool {
+			i++
+			fmt.Fprintf(w, "chunk%d\n", i)
+			return i < 3
+		})
+	}))
+	w := httptest.NewRecorder()
+	s.Serve().ServeHTTP(w, httptest.NewRequest("GET", "/stream", nil))
+	if clientGone || !w.Flushed || w.Body.String() != "chunk1\nchunk2\nchunk3\n" {
+		t.Fatalf("Unexpected stream, client gone: %v, flushed: %v, body: %q", clientGone, w.Flushed, w.Body.String())
+	}
+}
+
+func TestContextStreamClientGone(t *testing.T) {
+	done := make(chan bool, 1)
+	s := New()
+	s.Get("/stream", HandlerFunc(func(c *Context) {
+		done <- c.Stream(func(w io.Writer) bool {
+			io.WriteString(w, "tick\n")
+			time.Sleep(5 * time.Millisecond)
+			return true
+		})
+	}))
+	srv := httptest.NewServer(s.Serve())
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line, _ := bufio.NewReader(res.Body).ReadString('\n'); line != "tick\n" {
+		t.Fatalf("Expected a chunk but got %q", line)
+	}
+	res.Body.Close()
+
+	select {
+	case clientGone := <-done:
+		if !clientGone {
+			t.Fatalf("Expected the Stream to return true after the client has gone")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("The Stream didn't stop after the client has gone")
+	}
+}
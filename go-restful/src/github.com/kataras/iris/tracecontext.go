@@ -0,0 +1,186 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package iris
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	// RequestIDHeader is the header of the request id, the requestid middleware accepts it from the request and it sends it with the response
+	RequestIDHeader = "X-Request-Id"
+	// TraceparentHeader is the header of the W3C trace context, ex: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+	TraceparentHeader = "traceparent"
+	// TracestateHeader is the header of the vendor specific values of the W3C trace context, ex: congo=t61rcWkgMzE
+	TracestateHeader = "tracestate"
+
+	// TraceFlagSampled is the flag of the traceparent which means that the caller may record the trace
+	TraceFlagSampled byte = 0x01
+
+	traceparentVersion = "00"
+	traceparentLen     = 55
+)
+
+// errInvalidTraceparent is returned by the ParseTraceparent
+var errInvalidTraceparent = errors.New("invalid traceparent")
+
+// TraceID is the id of a whole trace, of all the requests between the services for one operation
+type TraceID [16]byte
+
+// SpanID is the id of one operation of a trace, ex: of the request which a service serves
+type SpanID [8]byte
+
+// IsValid returns false if the id is all zeros
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid returns false if the id is all zeros
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// NewTraceID returns a random TraceID
+func NewTraceID() (t TraceID) {
+	for !t.IsValid() {
+		rand.Read(t[:])
+	}
+	return
+}
+
+// NewSpanID returns a random SpanID
+func NewSpanID() (s SpanID) {
+	for !s.IsValid() {
+		rand.Read(s[:])
+	}
+	return
+}
+
+// TraceContext is the W3C trace context of a request, https://www.w3.org/TR/trace-context/
+// the zero value means that the request has no trace context
+type TraceContext struct {
+	TraceID TraceID
+	// SpanID is the id of the current operation, it's the parent-id of the traceparent which is sent to the next services
+	SpanID SpanID
+	// ParentID is the span id of the caller, the parent-id of the traceparent which is received, empty if the trace started here
+	ParentID SpanID
+	Flags    byte
+	// State is the tracestate header, the vendor specific values, it's passed as it's to the next services
+	State string
+}
+
+// IsValid returns true if the trace context has a trace id and a span id
+func (t TraceContext) IsValid() bool {
+	return t.TraceID.IsValid() && t.SpanID.IsValid()
+}
+
+// Sampled returns true if the sampled flag is set
+func (t TraceContext) Sampled() bool {
+	return t.Flags&TraceFlagSampled != 0
+}
+
+// Traceparent returns the traceparent header of the trace context, the parent-id is the SpanID
+// ex: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func (t TraceContext) Traceparent() string {
+	b := make([]byte, 0, traceparentLen)
+	b = append(b, traceparentVersion...)
+	b = append(b, '-')
+	b = append(b, t.TraceID.String()...)
+	b = append(b, '-')
+	b = append(b, t.SpanID.String()...)
+	b = append(b, '-')
+	b = append(b, hex.EncodeToString([]byte{t.Flags})...)
+	return string(b)
+}
+
+// isLowerHex returns true if the s has only lowercase hex digits
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTraceparent parses a traceparent header, the received parent-id is the SpanID of the result,
+// the versions after the 00 are parsed by the 00 format, as the specification says
+func ParseTraceparent(s string) (TraceContext, error) {
+	var t TraceContext
+	s = strings.TrimSpace(s)
+	if len(s) < traceparentLen || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return t, errInvalidTraceparent
+	}
+	version := s[:2]
+	if !isLowerHex(version) || version == "ff" || (version == traceparentVersion && len(s) != traceparentLen) ||
+		(len(s) > traceparentLen && s[traceparentLen] != '-') {
+		return t, errInvalidTraceparent
+	}
+	traceID, spanID, flags := s[3:35], s[36:52], s[53:55]
+	if !isLowerHex(traceID) || !isLowerHex(spanID) || !isLowerHex(flags) {
+		return t, errInvalidTraceparent
+	}
+	hex.Decode(t.TraceID[:], []byte(traceID))
+	hex.Decode(t.SpanID[:], []byte(spanID))
+	var f [1]byte
+	hex.Decode(f[:], []byte(flags))
+	t.Flags = f[0]
+	if !t.IsValid() {
+		return TraceContext{}, errInvalidTraceparent
+	}
+	return t, nil
+}
+
+// maxTracestateLength is the maximum length of the tracestate which is passed to the next services, W3C trace context 3.3.1.5
+const maxTracestateLength = 512
+
+// ContinueTrace returns the trace context of a request which continues the trace of its traceparent and tracestate headers,
+// the caller's span is the ParentID and the SpanID is a new one, returns an error if the request has no valid traceparent
+func ContinueTrace(h http.Header) (TraceContext, error) {
+	t, err := ParseTraceparent(h.Get(TraceparentHeader))
+	if err != nil {
+		return t, err
+	}
+	t.ParentID = t.SpanID
+	t.SpanID = NewSpanID()
+	if state := strings.TrimSpace(strings.Join(h.Values(TracestateHeader), ",")); len(state) <= maxTracestateLength {
+		t.State = state
+	}
+	return t, nil
+}
@@ -0,0 +1,74 @@
+package iris
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	valid := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tc, err := ParseTraceparent(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || tc.SpanID.String() != "00f067aa0ba902b7" || !tc.Sampled() {
+		t.Fatalf("unexpected trace context %#v", tc)
+	}
+	if tc.Traceparent() != valid {
+		t.Fatalf("expected the traceparent %s but got %s", valid, tc.Traceparent())
+	}
+
+	// a future version may have more fields, the 00 format is used
+	if tc, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra"); err != nil || tc.Sampled() {
+		t.Fatalf("expected the future version to be parsed, got %#v %v", tc, err)
+	}
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", // uppercase
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01", // zero trace id
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", // zero span id
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", // invalid version
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01",
+	}
+	for _, s := range invalid {
+		if tc, err := ParseTraceparent(s); err == nil || tc.IsValid() {
+			t.Fatalf("expected %q to be invalid but got %#v", s, tc)
+		}
+	}
+
+	if id := NewTraceID(); !id.IsValid() || len(id.String()) != 32 {
+		t.Fatalf("unexpected trace id %s", id)
+	}
+	if id := NewSpanID(); !id.IsValid() || len(id.String()) != 16 {
+		t.Fatalf("unexpected span id %s", id)
+	}
+}
+
+func TestContextRequestID(t *testing.T) {
+	s := New()
+	var got []string
+	s.Get("/", HandlerFunc(func(c *Context) {
+		if c.TraceContext().IsValid() {
+			t.Fatalf("expected no trace context before it's set")
+		}
+		got = append(got, c.RequestID())
+		if c.URLParam("set") != "" {
+			c.SetRequestID("set")
+			c.SetTraceContext(TraceContext{TraceID: NewTraceID(), SpanID: NewSpanID()})
+			got = append(got, c.RequestID())
+		}
+	}))
+	handler := s.Serve()
+	req := httptest.NewRequest("GET", "/?set=1", nil)
+	req.Header.Set(RequestIDHeader, "header")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	// the next request of the same (pooled) context
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if len(got) != 3 || got[0] != "header" || got[1] != "set" || got[2] != "" {
+		t.Fatalf("unexpected request ids %q", got)
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2016, Gerasimos Maropoulos
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without modification,
+// are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//	  this list of conditions and the following disclaimer
+//    in the documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors may be used to endorse
+//    or promote products derived from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL JULIEN SCHMIDT BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+// (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+// ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package iris
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SpanKind is the kind of a span, the values are these of the OpenTelemetry
+type SpanKind int
+
+const (
+	// SpanKindInternal is an operation inside the server, ex: the route lookup or a handler
+	SpanKindInternal SpanKind = 1
+	// SpanKindServer is the span of a request which the server serves
+	SpanKindServer SpanKind = 2
+	// SpanKindClient is the span of an outgoing request
+	SpanKindClient SpanKind = 3
+)
+
+// SpanStatus is the status of a span, the values are these of the OpenTelemetry
+type SpanStatus int
+
+const (
+	// SpanStatusUnset is the default status
+	SpanStatusUnset SpanStatus = iota
+	// SpanStatusOK means that the operation is completed successfully
+	SpanStatusOK
+	// SpanStatusError means that the operation has failed, the server spans of the responses with status code >= 500 have this status
+	SpanStatusError
+)
+
+// Span is a timed operation of a request, the spans of a request are a tree which its root is the server span, see Station.Tracing
+type Span struct {
+	// Trace is the trace context of the span, its SpanID is the span's id and its ParentID is the parent span's id
+	Trace         TraceContext
+	Name          string
+	Kind          SpanKind
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    []Field
+	Status        SpanStatus
+	StatusMessage string
+
+	processor SpanProcessor
+	// ctx and parent are used to restore the current span of the context when the span ends, they are cleared after
+	ctx    *Context
+	parent *Span
+	ended  bool
+}
+
+// SetAttribute adds an attribute to the span, the value is a string, a bool, an integer or a float
+// it does nothing if the span is nil, the request is not traced
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.Attributes = append(s.Attributes, Field{key, value})
+}
+
+// SetStatus sets the status of the span, the message is used with the SpanStatusError
+// it does nothing if the span is nil, the request is not traced
+func (s *Span) SetStatus(status SpanStatus, message string) {
+	if s == nil {
+		return
+	}
+	s.Status = status
+	s.StatusMessage = message
+}
+
+// End ends the span and passes it to the station's SpanProcessor, the parent becomes the current span of the Context
+// it does nothing if the span is nil, the request is not traced, or it's already ended
+func (s *Span) End() {
+	if s == nil || s.ended {
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	if s.ctx != nil && s.ctx.span == s {
+		s.ctx.span = s.parent
+	}
+	s.ctx = nil
+	s.parent = nil
+	s.processor.OnEnd(s)
+}
+
+// SpanProcessor receives the spans which end, ex: the middleware/tracing.Exporter which exports them with the OTLP/HTTP
+// the OnEnd is called by many goroutines, it should not block
+type SpanProcessor interface {
+	OnEnd(*Span)
+}
+
+// TracingOptions are the options of the Station.Tracing
+type TracingOptions struct {
+	// Processor receives the spans which end, the tracing is disabled if it's nil
+	Processor SpanProcessor
+	// Sampler returns true if a new trace, of a request without a traceparent header, is recorded,
+	// the traces of the requests with a traceparent are recorded if the caller has recorded them (the sampled flag)
+	// Default records all the new traces
+	Sampler func(TraceID) bool
+	// MiddlewareSpans creates a child span for each handler of the route, named after the handler (see Context.GetHandlerName)
+	// Default is false
+	MiddlewareSpans bool
+}
+
+// Tracing enables the tracing of the requests,
+// each request has a server span named after its method and its route (ex: GET /users/:id) with child spans for the route lookup ("iris.route"),
+// the cache lookup ("iris.cache"), the templates ("iris.render"), the handlers if the MiddlewareSpans option is true, and the spans of the Context.StartSpan
+//
+// The traceparent header of the request is continued, the trace context of the server span is the Context.TraceContext
+// call it before the server starts, a nil Processor disables the tracing
+func (s *Station) Tracing(options TracingOptions) {
+	if options.Processor == nil {
+		s.tracing = nil
+		return
+	}
+	s.tracing = &options
+}
+
+// startServerSpan starts the server span of the request, if the request is sampled, and sets the request's trace context
+func (s *Station) startServerSpan(ctx *Context) {
+	options := s.tracing
+	trace, err := ContinueTrace(ctx.Request.Header)
+	if err != nil {
+		trace = TraceContext{TraceID: NewTraceID(), SpanID: NewSpanID()}
+		if options.Sampler == nil || options.Sampler(trace.TraceID) {
+			trace.Flags = TraceFlagSampled
+		}
+	}
+	ctx.trace = trace
+	if !trace.Sampled() {
+		return
+	}
+	ctx.span = &Span{Trace: trace, Name: ctx.Request.Method, Kind: SpanKindServer, StartTime: time.Now(), processor: options.Processor, ctx: ctx}
+	ctx.traceHandlers = options.MiddlewareSpans
+}
+
+// traceCacheLookup records the span of the router cache's lookup, which started before the server span
+func (ctx *Context) traceCacheLookup(start time.Time, hit bool) {
+	if ctx.span == nil {
+		return
+	}
+	ctx.span.StartTime = start
+	span := ctx.startSpan("iris.cache", SpanKindInternal)
+	span.StartTime = start
+	span.SetAttribute("iris.cache.hit", hit)
+	span.End()
+}
+
+// endServerSpan names the server span after the route, sets the http attributes and ends it
+func (s *Station) endServerSpan(ctx *Context) {
+	span := ctx.span
+	if span == nil {
+		return
+	}
+	// the spans which are not ended, ex: a handler's span on panic, are not exported
+	for span.parent != nil {
+		span = span.parent
+	}
+	status := ctx.ResponseWriter.Status()
+	span.SetAttribute("http.request.method", ctx.Request.Method)
+	span.SetAttribute("url.path", ctx.Request.URL.Path)
+	if route := ctx.GetRoutePath(); route != "" {
+		span.Name = ctx.Request.Method + " " + route
+		span.SetAttribute("http.route", route)
+	}
+	span.SetAttribute("http.response.status_code", status)
+	if host := s.requestHost(ctx.Request); host != "" {
+		span.SetAttribute("server.address", host)
+	}
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(SpanStatusError, strconv.Itoa(status)+" "+http.StatusText(status))
+	}
+	span.End()
+}
+
+// startSpan starts a child span of the current span of the Context, returns nil if the request is not traced
+func (ctx *Context) startSpan(name string, kind SpanKind) *Span {
+	parent := ctx.span
+	if parent == nil {
+		return nil
+	}
+	trace := parent.Trace
+	trace.ParentID = trace.SpanID
+	trace.SpanID = NewSpanID()
+	s := &Span{Trace: trace, Name: name, Kind: kind, StartTime: time.Now(), processor: parent.processor, ctx: ctx, parent: parent}
+	ctx.span = s
+	return s
+}
+
+// serveTraced serves the handler inside its own span, see TracingOptions.MiddlewareSpans
+func (ctx *Context) serveTraced(h Handler) {
+	span := ctx.startSpan(handlerName(h), SpanKindInternal)
+	defer span.End()
+	h.Serve(ctx)
+}
+
+// StartSpan starts a child span of the current span and returns it, the span becomes the current span until its End,
+// ex: span := c.StartSpan("load user"); defer span.End()
+// returns nil if the request is not traced, the methods of a nil span do nothing
+func (ctx *Context) StartSpan(name string) *Span {
+	return ctx.startSpan(name, SpanKindInternal)
+}
+
+// Span returns the current span of the request, ex: to add attributes, nil if the request is not traced
+func (ctx *Context) Span() *Span {
+	return ctx.span
+}
@@ -0,0 +1,215 @@
+package iris
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordProcessor struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (p *recordProcessor) OnEnd(s *Span) {
+	p.mu.Lock()
+	p.spans = append(p.spans, s)
+	p.mu.Unlock()
+}
+
+func (p *recordProcessor) take() []*Span {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	spans := p.spans
+	p.spans = nil
+	return spans
+}
+
+func findSpan(spans []*Span, name string) *Span {
+	for _, s := range spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func spanAttribute(s *Span, key string) interface{} {
+	for _, f := range s.Attributes {
+		if f.Key == key {
+			return f.Value
+		}
+	}
+	return nil
+}
+
+func tracedHandler(c *Context) {
+	c.Next()
+}
+
+func TestTracing(t *testing.T) {
+	p := &recordProcessor{}
+	s := New()
+	s.Tracing(TracingOptions{Processor: p, MiddlewareSpans: true})
+	s.Use(HandlerFunc(tracedHandler))
+	s.Get("/users/:id", HandlerFunc(func(c *Context) {
+		span := c.StartSpan("load user")
+		span.SetAttribute("user.id", c.Param("id"))
+		span.End()
+		c.Write("user %s", c.Param("id"))
+	}))
+	handler := s.Serve()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/users/42", nil))
+	spans := p.take()
+
+	server := findSpan(spans, "GET /users/:id")
+	if server == nil || server != spans[len(spans)-1] || server.Kind != SpanKindServer {
+		t.Fatalf("Expected the server span to end last but got %d spans", len(spans))
+	}
+	if server.Trace.ParentID.IsValid() || !server.Trace.Sampled() {
+		t.Fatalf("Expected a sampled root span but got %#v", server.Trace)
+	}
+	if spanAttribute(server, "http.route") != "/users/:id" || spanAttribute(server, "http.response.status_code") != 200 ||
+		spanAttribute(server, "url.path") != "/users/42" {
+		t.Fatalf("Expected the http attributes but got %v", server.Attributes)
+	}
+
+	cache := findSpan(spans, "iris.cache")
+	if cache == nil || cache.Trace.ParentID != server.Trace.SpanID || spanAttribute(cache, "iris.cache.hit") != false {
+		t.Fatalf("Expected a missed cache lookup span, child of the server span")
+	}
+	route := findSpan(spans, "iris.route")
+	if route == nil || route.Trace.ParentID != server.Trace.SpanID || spanAttribute(route, "iris.route.found") != true {
+		t.Fatalf("Expected a route lookup span, child of the server span")
+	}
+
+	middleware := findSpan(spans, "github.com/kataras/iris.tracedHandler")
+	if middleware == nil || middleware.Trace.ParentID != server.Trace.SpanID || middleware.Trace.TraceID != server.Trace.TraceID {
+		t.Fatalf("Expected a span of the middleware, child of the server span")
+	}
+	var last *Span
+	for _, span := range spans {
+		if strings.HasPrefix(span.Name, "github.com/kataras/iris.TestTracing") {
+			last = span
+		}
+	}
+	if last == nil || last.Trace.ParentID != middleware.Trace.SpanID {
+		t.Fatalf("Expected the span of the route's handler to be a child of the middleware's span")
+	}
+	custom := findSpan(spans, "load user")
+	if custom == nil || custom.Trace.ParentID != last.Trace.SpanID || spanAttribute(custom, "user.id") != "42" {
+		t.Fatalf("Expected the span of the StartSpan to be a child of the handler's span")
+	}
+
+	// the second request is served from the cache
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+	spans = p.take()
+	if cache = findSpan(spans, "iris.cache"); cache == nil || spanAttribute(cache, "iris.cache.hit") != true {
+		t.Fatalf("Expected a cache hit span")
+	}
+	if findSpan(spans, "iris.route") != nil || findSpan(spans, "GET /users/:id") == nil {
+		t.Fatalf("Expected no route lookup when the route is cached")
+	}
+}
+
+func TestTracingTraceparent(t *testing.T) {
+	p := &recordProcessor{}
+	s := Custom(StationOptions{Cache: false})
+	s.Tracing(TracingOptions{Processor: p})
+	var trace TraceContext
+	s.Get("/", HandlerFunc(func(c *Context) { trace = c.TraceContext() }))
+	s.Get("/fail", HandlerFunc(func(c *Context) { c.EmitError(http.StatusInternalServerError) }))
+	handler := s.Serve()
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(TracestateHeader, "vendor=value")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	spans := p.take()
+	server := findSpan(spans, "GET /")
+	if server == nil || server.Trace != trace {
+		t.Fatalf("Expected the server span to have the trace context of the request")
+	}
+	if trace.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || trace.ParentID.String() != "00f067aa0ba902b7" || trace.State != "vendor=value" {
+		t.Fatalf("Expected the trace of the traceparent to be continued but got %#v", trace)
+	}
+	if route := findSpan(spans, "iris.route"); route == nil || findSpan(spans, "iris.cache") != nil {
+		t.Fatalf("Expected a route lookup span and no cache span without the cache")
+	}
+
+	// the caller has not sampled the trace
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if spans = p.take(); len(spans) != 0 || !trace.IsValid() || trace.Sampled() {
+		t.Fatalf("Expected no spans of a not sampled trace but got %d", len(spans))
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	if server = findSpan(p.take(), "GET /fail"); server == nil || server.Status != SpanStatusError || server.StatusMessage != "500 Internal Server Error" {
+		t.Fatalf("Expected the server span of a 500 to have the error status")
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+	if server = findSpan(p.take(), "GET"); server == nil || spanAttribute(server, "http.response.status_code") != 404 || spanAttribute(server, "http.route") != nil {
+		t.Fatalf("Expected the server span of a not found request to be named after the method only")
+	}
+
+	s.Tracing(TracingOptions{Processor: p, Sampler: func(TraceID) bool { return false }})
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if spans = p.take(); len(spans) != 0 || !trace.IsValid() || trace.Sampled() {
+		t.Fatalf("Expected the Sampler to drop the new trace but got %d spans", len(spans))
+	}
+}
+
+func TestTracingRender(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "user.html"), []byte(`user {{ .ID }}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := &recordProcessor{}
+	s := New()
+	s.Templates(filepath.Join(dir, "*.html"))
+	s.Tracing(TracingOptions{Processor: p})
+	s.Get("/users/:id", HandlerFunc(func(c *Context) { c.RenderFile("user.html", map[string]string{"ID": c.Param("id")}) }))
+	s.Get("/missing", HandlerFunc(func(c *Context) { c.RenderFile("missing.html", nil) }))
+	handler := s.Serve()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+	spans := p.take()
+	render, server := findSpan(spans, "iris.render"), findSpan(spans, "GET /users/:id")
+	if render == nil || server == nil || render.Trace.ParentID != server.Trace.SpanID || spanAttribute(render, "iris.template") != "user.html" {
+		t.Fatalf("Expected a render span, child of the server span")
+	}
+	if render.Status != SpanStatusUnset || render.EndTime.Before(render.StartTime) {
+		t.Fatalf("Expected a successful render span")
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+	if render = findSpan(p.take(), "iris.render"); render == nil || render.Status != SpanStatusError || render.StatusMessage == "" {
+		t.Fatalf("Expected the render span of a missing template to have the error status")
+	}
+}
+
+func TestTracingDisabled(t *testing.T) {
+	s := New()
+	var span *Span
+	s.Get("/", HandlerFunc(func(c *Context) {
+		span = c.StartSpan("noop")
+		span.SetAttribute("key", "value")
+		span.SetStatus(SpanStatusError, "noop")
+		span.End()
+		if c.Span() != nil {
+			t.Fatalf("Expected no current span without the tracing")
+		}
+	}))
+	s.Serve().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if span != nil {
+		t.Fatalf("Expected a nil span without the tracing")
+	}
+}